@@ -0,0 +1,53 @@
+package main
+
+import (
+	"blockchain/internal/blockchain"
+	"blockchain/internal/chainio"
+	"blockchain/internal/snapshot"
+	"blockchain/internal/utils"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// bootstrapChain creates the node's blockchain from a snapshot instead of replaying from genesis.
+// Parameters:
+// - source: A snapshot file, or the API URL of a peer serving /snapshot.
+// - genesis: The configured genesis the snapshot's chain must start with.
+// - checkpoints: Extra trusted checkpoints the snapshot's header chain must match.
+// - logger: The logger instance for reporting progress.
+// Returns:
+// - The bootstrapped blockchain. When bootstrapping from a peer, the blocks after the snapshot are synced from it too.
+func bootstrapChain(source string, genesis *blockchain.Genesis, checkpoints []blockchain.Checkpoint, logger *utils.Logger) (*blockchain.Blockchain, error) {
+	snap, err := snapshot.Fetch(source)
+	if err != nil {
+		return nil, err
+	}
+	bc, err := snap.Bootstrap(genesis, checkpoints)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Bootstrapped from snapshot at height", snap.Height, "with", len(snap.Entries), "state entries")
+
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return bc, nil
+	}
+
+	// Sync only the blocks after the snapshot.
+	url := fmt.Sprintf("%s/export?from=%d", strings.TrimSuffix(source, "/"), snap.Height+1)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("syncing blocks from %s: %s", source, resp.Status)
+	}
+
+	result, err := chainio.Import(bc, resp.Body, snap.Height+1)
+	if err != nil {
+		return nil, fmt.Errorf("syncing blocks from %s: %w", source, err)
+	}
+	logger.Info("Synced", result.Imported, "blocks after the snapshot, chain height is", result.Height)
+	return bc, nil
+}
@@ -1,51 +1,205 @@
 package main
 
 import (
+	"blockchain/internal/anchor"
 	"blockchain/internal/api"
 	"blockchain/internal/blockchain"
+	"blockchain/internal/mempool"
 	"blockchain/internal/p2p"
+	"blockchain/internal/snapshot"
+	"blockchain/internal/storage"
 	"blockchain/internal/utils"
+	"blockchain/pkg/config"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 )
 
+// usage describes the available subcommands.
+const usage = `Usage:
+  blockchain                 Run a node (configured through environment variables)
+  blockchain export [flags]  Export a node's chain to a file
+  blockchain import [flags]  Import a chain file into a node
+  blockchain wallet <cmd>    Manage keys and send signed transactions
+
+Run "blockchain <command> -h" for the flags of a command.
+`
+
 func main() {
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "export":
+			err = runExport(os.Args[2:])
+		case "import":
+			err = runImport(os.Args[2:])
+		case "wallet":
+			err = runWallet(os.Args[2:])
+		default:
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	runNode()
+}
+
+// runNode starts the P2P node and the API server.
+func runNode() {
 	// Create a logger for the application.
 	logger := utils.NewLogger("BlockchainApp: ", log.LstdFlags)
 
-	// Initialize the blockchain.
-	bc := blockchain.GetBlockchain("SHA-256")
+	// Load the configuration from the environment.
+	cfg := config.LoadConfig()
+
+	// Parse any configured checkpoints; they are added to those of the genesis before the stored chain is checked.
+	checkpoints, err := blockchain.ParseCheckpoints(cfg.Checkpoints)
+	if err != nil {
+		logger.Error("Invalid CHECKPOINTS:", err)
+		return
+	}
+
+	// Describe the genesis block; the public chain's genesis unless a UTXO ledger or allocations are configured.
+	genesis, err := genesisFromConfig(cfg)
+	if err != nil {
+		logger.Error("Invalid genesis configuration:", err)
+		return
+	}
+
+	// Coinbases may only pay key-derived addresses.
+	if cfg.Producer != "" && !blockchain.IsKeyAddress(cfg.Producer) {
+		logger.Error("Invalid PRODUCER_ADDRESS: not a key-derived address:", cfg.Producer)
+		return
+	}
+
+	// Open the configured storage backend.
+	store, err := storage.Open(storage.Kind(cfg.StorageBackend), cfg.StoragePath)
+	if err != nil {
+		logger.Error("Failed to open storage:", err)
+		return
+	}
+	defer store.Close()
+	logger.Info("Using", cfg.StorageBackend, "storage backend")
+
+	empty, err := blockchain.IsStoreEmpty(store)
+	if err != nil {
+		logger.Error("Failed to read storage:", err)
+		return
+	}
+
+	// Initialize the blockchain from the store, or from a snapshot if one is configured and the store is empty.
+	var bc *blockchain.Blockchain
+	if cfg.BootstrapSource != "" && empty {
+		if bc, err = bootstrapChain(cfg.BootstrapSource, genesis, checkpoints, logger); err != nil {
+			logger.Error("Failed to bootstrap from", cfg.BootstrapSource, err)
+			return
+		}
+		if err := bc.AttachStore(store); err != nil {
+			logger.Error("Failed to store bootstrapped chain:", err)
+			return
+		}
+	} else {
+		var repairs []string
+		if bc, repairs, err = blockchain.OpenBlockchain(store, genesis, checkpoints); err != nil {
+			logger.Error("Failed to load blockchain from storage:", err)
+			return
+		}
+		for _, repair := range repairs {
+			logger.Warn("Repaired stored chain:", repair)
+		}
+	}
+	logger.Info("Loaded blockchain at height", bc.Len()-1, "in", bc.Ledger(), "ledger mode")
+
+	// Discard old block bodies if pruning is enabled.
+	if cfg.PruneDepth > 0 {
+		bc.PruneDepth = cfg.PruneDepth
+		logger.Info("Pruning enabled: pruned", bc.Prune(), "block bodies, keeping the latest", cfg.PruneDepth)
+	}
+
+	// Use the network-adjusted clock for block timestamps and timestamp validation.
+	clock := blockchain.NewAdjustedClock()
+	bc.Clock = clock
+
+	// Save periodic state snapshots for restarts and bootstrapping peers.
+	snapshots := &snapshot.Store{Dir: cfg.SnapshotDir, Keep: cfg.SnapshotKeep}
+	if cfg.SnapshotInterval > 0 {
+		snapshots.Schedule(bc, cfg.SnapshotInterval, logger)
+	}
+
+	// Periodically re-verify the whole chain in the background.
+	if cfg.ReverifyInterval > 0 {
+		go bc.StartReverification(cfg.ReverifyInterval, nil)
+	}
+
+	// Anchor the chain's tip to the configured transparency log and other chain, periodically if an interval is set.
+	var anchorer *anchor.Anchorer
+	if cfg.AnchorLog != "" || cfg.AnchorNode != "" {
+		anchorer = &anchor.Anchorer{Chain: bc, Path: cfg.AnchorRecords}
+		if cfg.AnchorLog != "" {
+			anchorer.Anchors = append(anchorer.Anchors, &anchor.Log{Path: cfg.AnchorLog})
+		}
+		if cfg.AnchorNode != "" {
+			anchorer.Anchors = append(anchorer.Anchors, &anchor.Remote{Node: cfg.AnchorNode, Token: cfg.AnchorNodeToken})
+		}
+		if cfg.AnchorInterval > 0 {
+			go anchorer.Start(cfg.AnchorInterval, nil, logger)
+		}
+	}
 
 	// Create the P2P node.
-	nodeAddress := getEnv("NODE_ADDRESS", "localhost:3001")
-	node := p2p.NewNode(nodeAddress, bc, logger)
+	node := p2p.NewNode(cfg.NodeAddress, bc, logger)
+	node.Clock = clock
 
 	// Start the P2P node in a separate goroutine.
 	go node.Start()
 
 	// Connect to any initial peers (optional, could be configured via environment or flags).
-	initialPeer := getEnv("INITIAL_PEER", "")
-	if initialPeer != "" {
-		node.ConnectToPeer(initialPeer)
+	if cfg.InitialPeer != "" {
+		node.ConnectToPeer(cfg.InitialPeer)
 	}
 
 	// Register API routes.
-	mux := api.RegisterRoutes(bc, logger)
+	handlers := api.NewHandlers(bc, logger)
+	handlers.Snapshots = snapshots
+	handlers.Mempool = mempool.New(bc)
+	handlers.Mempool.MinFee = uint64(max(cfg.MinTxFee, 0))
+	handlers.Producer = cfg.Producer
+	handlers.Anchorer = anchorer
+	handlers.NotaryToken = cfg.NotaryToken
+	handlers.NotaryRate = cfg.NotaryRate
+	mux := api.RegisterRoutes(handlers)
 
 	// Start the HTTP server for the API.
-	apiAddress := getEnv("API_ADDRESS", "localhost:8080")
-	logger.Info("Starting API server on", apiAddress)
-	if err := http.ListenAndServe(apiAddress, mux); err != nil {
+	logger.Info("Starting API server on", cfg.APIAddress)
+	if err := http.ListenAndServe(cfg.APIAddress, mux); err != nil {
 		logger.Error("Failed to start API server:", err)
 	}
 }
 
-// getEnv retrieves an environment variable or returns a default value if not set.
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// genesisFromConfig returns the genesis configured by LEDGER, GENESIS_ALLOC, BLOCK_REWARD and HALVING_INTERVAL.
+// Without a UTXO ledger, allocations or block rewards it is blockchain.DefaultGenesis, the genesis of the public
+// chain.
+func genesisFromConfig(cfg *config.Config) (*blockchain.Genesis, error) {
+	ledger, err := blockchain.ParseLedgerMode(cfg.Ledger)
+	if err != nil {
+		return nil, err
+	}
+	alloc, err := blockchain.ParseAlloc(cfg.GenesisAlloc)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BlockReward < 0 || cfg.HalvingInterval < 0 {
+		return nil, fmt.Errorf("BLOCK_REWARD and HALVING_INTERVAL may not be negative")
+	}
+	issuance := blockchain.IssuanceSchedule{InitialReward: uint64(cfg.BlockReward), HalvingInterval: cfg.HalvingInterval}
+	if ledger == blockchain.LedgerAccount && len(alloc) == 0 && issuance == (blockchain.IssuanceSchedule{}) {
+		return blockchain.DefaultGenesis, nil
 	}
-	return value
+	return &blockchain.Genesis{Timestamp: blockchain.GenesisTimestamp, Ledger: ledger, Issuance: issuance, Alloc: alloc}, nil
 }
@@ -0,0 +1,807 @@
+package main
+
+import (
+	"blockchain/internal/blockchain"
+	"blockchain/internal/crypto"
+	"blockchain/internal/vm"
+	"blockchain/internal/wallet"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// walletUsage describes the wallet subcommands.
+const walletUsage = `Usage:
+  blockchain wallet new [flags]       Generate a key and add it to the keystore
+  blockchain wallet mnemonic [flags]  Generate a mnemonic seed and derive keys from it
+  blockchain wallet recover [flags]   Restore a mnemonic seed and re-derive its keys
+  blockchain wallet derive [flags]    Derive another key from a seed in the keystore
+  blockchain wallet list [flags]      List the keys in the keystore
+  blockchain wallet import [flags]    Add a raw private key to the keystore
+  blockchain wallet export [flags]    Print the raw private key of a key
+  blockchain wallet sign [flags]      Sign a transaction read from a file or stdin
+  blockchain wallet send [flags]      Build, sign and submit a transfer
+  blockchain wallet deploy [flags]    Deploy a contract from assembly or bytecode
+  blockchain wallet call [flags]      Call a contract with the arguments after the flags
+  blockchain wallet token [flags]     Create, mint, transfer or burn a token
+  blockchain wallet asset [flags]     Mint, transfer, approve or burn a non-fungible asset
+  blockchain wallet multisig [flags]  Create an M-of-N multisig policy and print its address
+  blockchain wallet combine [files]   Combine the signatures of partially-signed transactions
+
+The passphrase is read from WALLET_PASSPHRASE or prompted for.
+Run "blockchain wallet <command> -h" for the flags of a command.
+`
+
+// runWallet dispatches the wallet subcommands.
+func runWallet(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, walletUsage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "new":
+		return runWalletNew(args[1:])
+	case "mnemonic":
+		return runWalletMnemonic(args[1:])
+	case "recover":
+		return runWalletRecover(args[1:])
+	case "derive":
+		return runWalletDerive(args[1:])
+	case "list":
+		return runWalletList(args[1:])
+	case "import":
+		return runWalletImport(args[1:])
+	case "export":
+		return runWalletExport(args[1:])
+	case "sign":
+		return runWalletSign(args[1:])
+	case "send":
+		return runWalletSend(args[1:])
+	case "deploy":
+		return runWalletDeploy(args[1:])
+	case "call":
+		return runWalletCall(args[1:])
+	case "token":
+		return runWalletToken(args[1:])
+	case "asset":
+		return runWalletAsset(args[1:])
+	case "multisig":
+		return runWalletMultisig(args[1:])
+	case "combine":
+		return runWalletCombine(args[1:])
+	}
+	fmt.Fprint(os.Stderr, walletUsage)
+	os.Exit(2)
+	return nil
+}
+
+// keystoreFlag adds the -keystore flag shared by every wallet subcommand.
+func keystoreFlag(flags *flag.FlagSet) *string {
+	return flags.String("keystore", getenvDefault("WALLET_KEYSTORE", "wallet/keystore.json"), "keystore file")
+}
+
+// getenvDefault returns the environment variable key, or defaultValue if it is not set.
+func getenvDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// stdin is shared by every prompt, so that lines piped in for several prompts are not lost to buffering.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassphrase returns WALLET_PASSPHRASE if set, or prompts for the passphrase on stderr and reads it from
+// stdin, without echoing it if stdin is a terminal.
+func readPassphrase(prompt string) (string, error) {
+	if passphrase, ok := os.LookupEnv("WALLET_PASSPHRASE"); ok {
+		return passphrase, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readLine(prompt, "passphrase")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// readNewPassphrase reads the passphrase of a new key like readPassphrase. Unless it comes from
+// WALLET_PASSPHRASE, it is prompted for twice, so that a typo does not lock the key away.
+func readNewPassphrase() (string, error) {
+	passphrase, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if _, ok := os.LookupEnv("WALLET_PASSPHRASE"); ok {
+		return passphrase, nil
+	}
+	confirmation, err := readPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirmation != passphrase {
+		return "", errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// readLine prompts on stderr and reads a line from stdin.
+func readLine(prompt, what string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("reading %s: %w", what, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// unlockKey opens the keystore and decrypts the key with the given name or address.
+func unlockKey(keystore, name string) (crypto.Signer, error) {
+	if name == "" {
+		return nil, errors.New("-key is required")
+	}
+	ks, err := wallet.Open(keystore)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ks.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := readPassphrase("Passphrase for " + key.Name + ": ")
+	if err != nil {
+		return nil, err
+	}
+	return key.Unlock(passphrase)
+}
+
+// runWalletNew generates a key and adds it to the keystore.
+func runWalletNew(args []string) error {
+	flags := flag.NewFlagSet("wallet new", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("name", "", "name of the key; defaults to its address")
+	algorithm := flags.String("algorithm", string(crypto.Ed25519), "signature algorithm: ed25519, secp256k1 or p256")
+	flags.Parse(args)
+
+	ks, err := wallet.Open(*keystore)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		return err
+	}
+	key, err := ks.Generate(*name, crypto.Algorithm(*algorithm), passphrase)
+	if err != nil {
+		return err
+	}
+	if err := ks.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Created %s key %s with address %s\n", key.Algorithm, key.Name, key.Address)
+	return nil
+}
+
+// runWalletMnemonic generates a mnemonic, prints it once for backup, adds it to the keystore and derives
+// the first keys from it.
+func runWalletMnemonic(args []string) error {
+	flags := flag.NewFlagSet("wallet mnemonic", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("name", "", "name of the seed (required)")
+	words := flags.Int("words", 24, "number of words: 12, 15, 18, 21 or 24")
+	algorithm := flags.String("algorithm", string(crypto.Ed25519), "signature algorithm of the derived keys")
+	count := flags.Int("keys", 1, "number of keys to derive")
+	flags.Parse(args)
+
+	if *words%3 != 0 {
+		return fmt.Errorf("-words must be one of 12, 15, 18, 21 or 24, not %d", *words)
+	}
+	mnemonic, err := wallet.NewMnemonic(*words / 3 * 32)
+	if err != nil {
+		return err
+	}
+	if err := addSeed(*keystore, *name, mnemonic, crypto.Algorithm(*algorithm), *count); err != nil {
+		return err
+	}
+	fmt.Printf("\nWrite down this mnemonic and keep it safe; it recovers every key derived from %s:\n\n%s\n", *name, mnemonic)
+	return nil
+}
+
+// runWalletRecover adds a mnemonic read from stdin to the keystore and re-derives its first keys.
+func runWalletRecover(args []string) error {
+	flags := flag.NewFlagSet("wallet recover", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("name", "", "name of the seed (required)")
+	algorithm := flags.String("algorithm", string(crypto.Ed25519), "signature algorithm of the derived keys")
+	count := flags.Int("keys", 1, "number of keys to derive")
+	flags.Parse(args)
+
+	mnemonic, err := readLine("Mnemonic: ", "mnemonic")
+	if err != nil {
+		return err
+	}
+	return addSeed(*keystore, *name, mnemonic, crypto.Algorithm(*algorithm), *count)
+}
+
+// addSeed adds a mnemonic to the keystore under name and derives its first count keys at the default paths,
+// named "<name>-<index>".
+func addSeed(keystore, name, mnemonic string, algorithm crypto.Algorithm, count int) error {
+	if name == "" {
+		return errors.New("-name is required")
+	}
+	ks, err := wallet.Open(keystore)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		return err
+	}
+	if _, err := ks.AddSeed(name, mnemonic, passphrase); err != nil {
+		return err
+	}
+	for index := 0; index < count; index++ {
+		key, err := ks.Derive(name, algorithm, wallet.DefaultPath(uint32(index)), fmt.Sprintf("%s-%d", name, index), passphrase)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Derived %s key %s at %s with address %s\n", key.Algorithm, key.Name, key.Path, key.Address)
+	}
+	return ks.Save()
+}
+
+// runWalletDerive derives a key from a seed in the keystore.
+func runWalletDerive(args []string) error {
+	flags := flag.NewFlagSet("wallet derive", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	seed := flags.String("seed", "", "name of the seed (required)")
+	algorithm := flags.String("algorithm", string(crypto.Ed25519), "signature algorithm of the key")
+	index := flags.Uint("index", 0, "account index; derives the key at the default path for this index")
+	path := flags.String("path", "", "derivation path, e.g. m/44'/1'/0'/0'/0'; overrides -index")
+	name := flags.String("name", "", "name of the key; defaults to <seed>-<index>, or its address with -path")
+	flags.Parse(args)
+
+	if *seed == "" {
+		return errors.New("-seed is required")
+	}
+	if *path == "" {
+		*path = wallet.DefaultPath(uint32(*index))
+		if *name == "" {
+			*name = fmt.Sprintf("%s-%d", *seed, *index)
+		}
+	}
+
+	ks, err := wallet.Open(*keystore)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase("Passphrase for " + *seed + ": ")
+	if err != nil {
+		return err
+	}
+	key, err := ks.Derive(*seed, crypto.Algorithm(*algorithm), *path, *name, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := ks.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Derived %s key %s at %s with address %s\n", key.Algorithm, key.Name, key.Path, key.Address)
+	return nil
+}
+
+// runWalletList prints the keys in the keystore.
+func runWalletList(args []string) error {
+	flags := flag.NewFlagSet("wallet list", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	flags.Parse(args)
+
+	ks, err := wallet.Open(*keystore)
+	if err != nil {
+		return err
+	}
+	for _, key := range ks.Keys {
+		fmt.Printf("%-20s %-10s %s %s\n", key.Name, key.Algorithm, key.Address, key.Path)
+	}
+	return nil
+}
+
+// runWalletImport adds a raw private key to the keystore.
+func runWalletImport(args []string) error {
+	flags := flag.NewFlagSet("wallet import", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("name", "", "name of the key; defaults to its address")
+	algorithm := flags.String("algorithm", string(crypto.Ed25519), "signature algorithm: ed25519, secp256k1 or p256")
+	keyFile := flags.String("in", "", "file holding the hex-encoded private key (required)")
+	flags.Parse(args)
+
+	if *keyFile == "" {
+		return errors.New("-in is required")
+	}
+	encoded, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	privateKey, err := hex.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("%s: private key must be hex encoded: %w", *keyFile, err)
+	}
+
+	ks, err := wallet.Open(*keystore)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readNewPassphrase()
+	if err != nil {
+		return err
+	}
+	key, err := ks.Import(*name, crypto.Algorithm(*algorithm), privateKey, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := ks.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %s key %s with address %s\n", key.Algorithm, key.Name, key.Address)
+	return nil
+}
+
+// runWalletExport prints the hex-encoded raw private key of a key.
+func runWalletExport(args []string) error {
+	flags := flag.NewFlagSet("wallet export", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("key", "", "name or address of the key (required)")
+	flags.Parse(args)
+
+	signer, err := unlockKey(*keystore, *name)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(signer.PrivateKey()))
+	return nil
+}
+
+// runWalletSign signs a JSON transaction and prints it with the signature added.
+func runWalletSign(args []string) error {
+	flags := flag.NewFlagSet("wallet sign", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("key", "", "name or address of the key (required)")
+	in := flags.String("in", "", "file holding the transaction as JSON (required; - for stdin)")
+	flags.Parse(args)
+
+	if *in == "" {
+		return errors.New("-in is required")
+	}
+	tx, err := readTransaction(*in)
+	if err != nil {
+		return err
+	}
+
+	signer, err := unlockKey(*keystore, *name)
+	if err != nil {
+		return err
+	}
+	if err := tx.Sign(signer); err != nil {
+		return err
+	}
+	printMultisigStatus(tx)
+	return json.NewEncoder(os.Stdout).Encode(tx)
+}
+
+// readTransaction reads a JSON transaction from a file, or from stdin if path is "-".
+func readTransaction(path string) (*blockchain.Transaction, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tx blockchain.Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &tx, nil
+}
+
+// printMultisigStatus reports on stderr how many signatures each multisig policy of tx still needs.
+func printMultisigStatus(tx *blockchain.Transaction) {
+	statuses, err := tx.MultisigStatus()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	for _, status := range statuses {
+		if status.Complete {
+			fmt.Fprintf(os.Stderr, "Multisig %s: %d of %d signatures, complete\n", status.Address, len(status.Signed), status.Threshold)
+		} else {
+			fmt.Fprintf(os.Stderr, "Multisig %s: %d of %d signatures, waiting for %s\n", status.Address, len(status.Signed), status.Threshold, strings.Join(status.Missing, ", "))
+		}
+	}
+}
+
+// runWalletSend builds a transfer from a key's address, signs it and submits it to a node's mempool. With
+// -policy the transfer spends from the multisig address of the policy instead; if the key's signature is not
+// enough, the partially-signed transaction is printed for the other signers instead of being submitted.
+func runWalletSend(args []string) error {
+	flags := flag.NewFlagSet("wallet send", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("key", "", "name or address of the sending key (required)")
+	node := flags.String("node", "http://localhost:8080", "API address of the node to submit to")
+	to := flags.String("to", "", "recipient address (required)")
+	amount := flags.Uint64("amount", 0, "amount to send (required)")
+	fee := flags.Uint64("fee", 0, "fee paid to the block producer")
+	lockHeight := flags.Int("lock-height", 0, "height of the first block that may include the transfer")
+	lockTime := flags.String("lock-time", "", "earliest block time that may include the transfer, in RFC 3339 format")
+	ledger := flags.String("ledger", string(blockchain.LedgerAccount), "ledger mode of the chain: account or utxo")
+	policyFile := flags.String("policy", "", "file holding a multisig policy to send from, as written by wallet multisig")
+	flags.Parse(args)
+
+	if *to == "" || *amount == 0 {
+		return errors.New("-to and -amount are required")
+	}
+	mode, err := blockchain.ParseLedgerMode(*ledger)
+	if err != nil {
+		return err
+	}
+	var lockAt time.Time
+	if *lockTime != "" {
+		if lockAt, err = time.Parse(time.RFC3339, *lockTime); err != nil {
+			return fmt.Errorf("-lock-time: %w", err)
+		}
+	}
+	var policy *blockchain.MultisigPolicy
+	if *policyFile != "" {
+		if policy, err = readPolicy(*policyFile); err != nil {
+			return err
+		}
+	}
+	signer, err := unlockKey(*keystore, *name)
+	if err != nil {
+		return err
+	}
+
+	client := &wallet.Client{Node: *node}
+	from := crypto.Address(signer)
+	if policy != nil {
+		from = policy.Address()
+	}
+	var tx *blockchain.Transaction
+	if mode == blockchain.LedgerUTXO {
+		tx, err = client.TransferUTXO(from, *to, *amount, *fee)
+	} else {
+		tx, err = client.Transfer(from, *to, *amount, *fee)
+	}
+	if err != nil {
+		return err
+	}
+	tx.LockHeight = *lockHeight
+	if !lockAt.IsZero() {
+		tx.LockTime = lockAt.UnixMilli()
+	}
+	if policy != nil {
+		tx.Multisig = []blockchain.MultisigPolicy{*policy}
+	}
+	if err := tx.Sign(signer); err != nil {
+		return err
+	}
+	if policy != nil {
+		if statuses, err := tx.MultisigStatus(); err != nil || !statuses[0].Complete {
+			printMultisigStatus(tx)
+			return json.NewEncoder(os.Stdout).Encode(tx)
+		}
+	}
+	id, err := client.Submit(tx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Submitted transaction %s\n", id)
+	return nil
+}
+
+// runWalletDeploy deploys a contract from a key's address. The code is read from a file holding assembly, if
+// its name ends in .asm, or hex-encoded bytecode. The address of the new contract is printed with the
+// transaction's ID.
+func runWalletDeploy(args []string) error {
+	flags := flag.NewFlagSet("wallet deploy", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("key", "", "name or address of the deploying key (required)")
+	node := flags.String("node", "http://localhost:8080", "API address of the node to submit to")
+	codeFile := flags.String("code", "", "file holding the contract as assembly (.asm) or hex-encoded bytecode (required)")
+	amount := flags.Uint64("amount", 0, "amount to fund the contract with")
+	gas := flags.Uint64("gas", 0, "gas limit; defaults to what the deployment uses")
+	fee := flags.Uint64("fee", 0, "fee paid to the block producer; defaults to the gas limit")
+	flags.Parse(args)
+
+	if *codeFile == "" {
+		return errors.New("-code is required")
+	}
+	source, err := os.ReadFile(*codeFile)
+	if err != nil {
+		return err
+	}
+	var code []byte
+	if strings.HasSuffix(*codeFile, ".asm") {
+		code, err = vm.Assemble(string(source))
+	} else if code, err = hex.DecodeString(strings.TrimSpace(string(source))); err == nil {
+		err = vm.Validate(code)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", *codeFile, err)
+	}
+	if *gas == 0 {
+		*gas = uint64(len(code)) * blockchain.DeployGasPerByte
+	}
+
+	signer, err := unlockKey(*keystore, *name)
+	if err != nil {
+		return err
+	}
+	client := &wallet.Client{Node: *node}
+	tx, err := client.Transfer(crypto.Address(signer), "", *amount, max(*fee, *gas))
+	if err != nil {
+		return err
+	}
+	tx.Code = hex.EncodeToString(code)
+	tx.GasLimit = *gas
+	if err := tx.Sign(signer); err != nil {
+		return err
+	}
+	id, err := client.Submit(tx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Submitted transaction %s deploying contract %s\n", id, blockchain.ContractAddress(tx.From, tx.Nonce))
+	return nil
+}
+
+// runWalletCall calls a contract from a key's address. The arguments after the flags are passed to the
+// contract: numbers, 0x-prefixed hex and double-quoted strings as parsed by vm.ParseValue, and anything else
+// as the bytes of the string.
+func runWalletCall(args []string) error {
+	flags := flag.NewFlagSet("wallet call", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("key", "", "name or address of the calling key (required)")
+	node := flags.String("node", "http://localhost:8080", "API address of the node to submit to")
+	to := flags.String("to", "", "address of the contract (required)")
+	amount := flags.Uint64("amount", 0, "amount to pay the contract")
+	gas := flags.Uint64("gas", 100000, "gas limit")
+	fee := flags.Uint64("fee", 0, "fee paid to the block producer; defaults to the gas limit")
+	flags.Parse(args)
+
+	if *to == "" {
+		return errors.New("-to is required")
+	}
+	signer, err := unlockKey(*keystore, *name)
+	if err != nil {
+		return err
+	}
+	client := &wallet.Client{Node: *node}
+	tx, err := client.Transfer(crypto.Address(signer), *to, *amount, max(*fee, *gas))
+	if err != nil {
+		return err
+	}
+	for _, arg := range flags.Args() {
+		value, err := vm.ParseValue(arg)
+		if err != nil {
+			value = []byte(arg)
+		}
+		tx.Args = append(tx.Args, hex.EncodeToString(value))
+	}
+	tx.GasLimit = *gas
+	if err := tx.Sign(signer); err != nil {
+		return err
+	}
+	id, err := client.Submit(tx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Submitted transaction %s; its receipt is at /receipts/%s once it is in a block\n", id, id)
+	return nil
+}
+
+// runWalletToken builds a token operation from a key's address, signs it and submits it to a node's mempool.
+func runWalletToken(args []string) error {
+	flags := flag.NewFlagSet("wallet token", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("key", "", "name or address of the sending key (required)")
+	node := flags.String("node", "http://localhost:8080", "API address of the node to submit to")
+	op := flags.String("op", "", "operation: create, mint, transfer or burn (required)")
+	symbol := flags.String("symbol", "", "symbol of the token (required)")
+	to := flags.String("to", "", "recipient of minted or transferred tokens")
+	amount := flags.Uint64("amount", 0, "amount minted, transferred or burned; for create, the initial supply")
+	tokenName := flags.String("name", "", "create: name of the token")
+	decimals := flags.Int("decimals", 0, "create: number of decimal places")
+	maxSupply := flags.Uint64("max-supply", 0, "create: cap on the supply; 0 for no cap")
+	fee := flags.Uint64("fee", 0, "fee paid to the block producer")
+	flags.Parse(args)
+
+	if *op == "" || *symbol == "" {
+		return errors.New("-op and -symbol are required")
+	}
+	signer, err := unlockKey(*keystore, *name)
+	if err != nil {
+		return err
+	}
+	client := &wallet.Client{Node: *node}
+	tx, err := client.Transfer(crypto.Address(signer), *to, 0, *fee)
+	if err != nil {
+		return err
+	}
+	tx.Token = &blockchain.TokenOperation{Op: *op, Symbol: *symbol, Name: *tokenName, Decimals: *decimals, MaxSupply: *maxSupply, Amount: *amount}
+	if err := tx.Sign(signer); err != nil {
+		return err
+	}
+	id, err := client.Submit(tx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Submitted transaction %s\n", id)
+	return nil
+}
+
+// runWalletAsset builds an asset operation from a key's address, signs it and submits it to a node's mempool.
+func runWalletAsset(args []string) error {
+	flags := flag.NewFlagSet("wallet asset", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	name := flags.String("key", "", "name or address of the sending key (required)")
+	node := flags.String("node", "http://localhost:8080", "API address of the node to submit to")
+	op := flags.String("op", "", "operation: mint, transfer, approve or burn (required)")
+	id := flags.String("id", "", "ID of the asset (required)")
+	to := flags.String("to", "", "new owner for mint or transfer, approved address for approve; empty revokes an approval")
+	metadataFile := flags.String("metadata", "", "mint: file holding the asset's metadata, hashed with SHA-256")
+	metadataHash := flags.String("metadata-hash", "", "mint: hex-encoded SHA-256 hash of the metadata, instead of -metadata")
+	uri := flags.String("uri", "", "mint: where the metadata can be found")
+	fee := flags.Uint64("fee", 0, "fee paid to the block producer")
+	flags.Parse(args)
+
+	if *op == "" || *id == "" {
+		return errors.New("-op and -id are required")
+	}
+	if *metadataFile != "" {
+		metadata, err := os.ReadFile(*metadataFile)
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(metadata)
+		*metadataHash = hex.EncodeToString(hash[:])
+	}
+	signer, err := unlockKey(*keystore, *name)
+	if err != nil {
+		return err
+	}
+	client := &wallet.Client{Node: *node}
+	tx, err := client.Transfer(crypto.Address(signer), *to, 0, *fee)
+	if err != nil {
+		return err
+	}
+	tx.Asset = &blockchain.AssetOperation{Op: *op, ID: *id, MetadataHash: *metadataHash, URI: *uri}
+	if err := tx.Sign(signer); err != nil {
+		return err
+	}
+	txID, err := client.Submit(tx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Submitted transaction %s\n", txID)
+	return nil
+}
+
+// runWalletMultisig creates an M-of-N multisig policy from signer names or addresses, writes it to a file and
+// prints its address.
+func runWalletMultisig(args []string) error {
+	flags := flag.NewFlagSet("wallet multisig", flag.ExitOnError)
+	keystore := keystoreFlag(flags)
+	threshold := flags.Int("threshold", 0, "number of signatures required (required)")
+	signerList := flags.String("signers", "", "comma-separated addresses, or names of keys in the keystore, of the signers (required)")
+	out := flags.String("out", "", "file to write the policy to; printed if empty")
+	flags.Parse(args)
+
+	ks, err := wallet.Open(*keystore)
+	if err != nil {
+		return err
+	}
+	var signers []string
+	for _, signer := range strings.Split(*signerList, ",") {
+		signer = strings.TrimSpace(signer)
+		if key, err := ks.Find(signer); err == nil {
+			signer = key.Address
+		}
+		signers = append(signers, signer)
+	}
+	policy, err := blockchain.NewMultisigPolicy(*threshold, signers)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		fmt.Println(string(encoded))
+	} else if err := os.WriteFile(*out, append(encoded, '\n'), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Multisig address %s (%d of %d)\n", policy.Address(), policy.Threshold, len(policy.Signers))
+	return nil
+}
+
+// readPolicy reads a JSON multisig policy from a file.
+func readPolicy(path string) (*blockchain.MultisigPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy blockchain.MultisigPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &policy, nil
+}
+
+// runWalletCombine combines the signatures of copies of a partially-signed transaction and prints the result,
+// or submits it with -submit once every multisig policy has enough signatures.
+func runWalletCombine(args []string) error {
+	flags := flag.NewFlagSet("wallet combine", flag.ExitOnError)
+	node := flags.String("node", "http://localhost:8080", "API address of the node to submit to")
+	submit := flags.Bool("submit", false, "submit the combined transaction if it is fully signed")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return errors.New("the files of the partially-signed transactions are required")
+	}
+	var txs []*blockchain.Transaction
+	for _, path := range flags.Args() {
+		tx, err := readTransaction(path)
+		if err != nil {
+			return err
+		}
+		txs = append(txs, tx)
+	}
+	combined, err := blockchain.CombineSignatures(txs)
+	if err != nil {
+		return err
+	}
+	printMultisigStatus(combined)
+	if !*submit {
+		return json.NewEncoder(os.Stdout).Encode(combined)
+	}
+
+	statuses, err := combined.MultisigStatus()
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if !status.Complete {
+			return fmt.Errorf("multisig %s needs %d more signatures", status.Address, status.Threshold-len(status.Signed))
+		}
+	}
+	id, err := (&wallet.Client{Node: *node}).Submit(combined)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Submitted transaction %s\n", id)
+	return nil
+}
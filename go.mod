@@ -1,3 +1,13 @@
 module blockchain
 
 go 1.22.6
+
+require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.3.0
+	go.etcd.io/bbolt v1.3.11
+	golang.org/x/crypto v0.31.0
+	golang.org/x/term v0.27.0
+	golang.org/x/text v0.21.0
+)
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,218 @@
+// Package anchor publishes the hash of the chain's tip outside the chain, so that rewriting the chain's history
+// after the fact would contradict the published hashes. Each place the hashes are published is an Anchor; an
+// Anchorer publishes the tip to its anchors on a schedule, keeps a record of every publication and verifies the
+// records against the chain and the anchors.
+package anchor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"blockchain/internal/blockchain"
+	"blockchain/internal/utils"
+)
+
+var (
+	// ErrNotAnchored is returned (possibly wrapped) when an anchor does not hold a record.
+	ErrNotAnchored = errors.New("not anchored")
+
+	// ErrTampered is returned (possibly wrapped) when an anchor's contents have been altered.
+	ErrTampered = errors.New("anchor tampered")
+)
+
+// Record is a block hash published to an anchor.
+type Record struct {
+	Anchor    string `json:"anchor"`     // Name of the anchor that holds the record.
+	Height    int    `json:"height"`     // Height of the block.
+	BlockHash string `json:"block_hash"` // Hash of the block.
+	Time      int64  `json:"time"`       // When the hash was published, in Unix milliseconds.
+	Reference string `json:"reference"`  // Where the anchor holds the hash, in the anchor's terms.
+
+	// Block of another chain that holds the hash, for anchors that are chains.
+	AnchorHeight int    `json:"anchor_height,omitempty"`
+	AnchorBlock  string `json:"anchor_block,omitempty"`
+}
+
+// Anchor is a place outside the chain to which block hashes are published.
+type Anchor interface {
+	// Name identifies the anchor in records.
+	Name() string
+
+	// Publish publishes the hash of the block at height and returns the record of the publication.
+	Publish(height int, blockHash string) (*Record, error)
+
+	// Verify checks that the anchor still holds record, unaltered.
+	// Returns an error wrapping ErrNotAnchored if it does not, or ErrTampered if its contents were altered.
+	Verify(record *Record) error
+}
+
+// Verification is the outcome of verifying a record.
+type Verification struct {
+	Record
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"` // Why the record did not verify.
+}
+
+// Anchorer publishes the tip of a chain to anchors and keeps the records of the publications in a file, one
+// JSON record per line.
+type Anchorer struct {
+	Chain   *blockchain.Blockchain
+	Anchors []Anchor
+	Path    string // File the records are appended to.
+
+	mu   sync.Mutex // Serializes publications and guards last.
+	last string     // Hash of the block last published; empty until known.
+}
+
+// AnchorTip publishes the hash of the chain's tip to every anchor and records the publications. A tip that has
+// already been published is not published again, and no records are returned.
+// Returns the records of the successful publications and an error joining those of the failed ones.
+func (a *Anchorer) AnchorTip() ([]Record, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	height := a.Chain.Len() - 1
+	block := a.Chain.BlockAt(height)
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", height)
+	}
+	if a.last == "" {
+		records, err := a.Records()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 {
+			a.last = records[len(records)-1].BlockHash
+		}
+	}
+	if block.Hash == a.last {
+		return []Record{}, nil
+	}
+
+	records := []Record{}
+	var errs []error
+	for _, anchor := range a.Anchors {
+		record, err := anchor.Publish(height, block.Hash)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", anchor.Name(), err))
+			continue
+		}
+		if err := a.append(record); err != nil {
+			return records, err
+		}
+		records = append(records, *record)
+	}
+	if len(records) > 0 {
+		a.last = block.Hash
+	}
+	return records, errors.Join(errs...)
+}
+
+// append adds record to the records file. The caller must hold a.mu.
+func (a *Anchorer) append(record *Record) error {
+	if err := os.MkdirAll(filepath.Dir(a.Path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(a.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	line, _ := json.Marshal(record)
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Records returns the records of every publication, oldest first.
+func (a *Anchorer) Records() ([]Record, error) {
+	file, err := os.Open(a.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Record{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records := []Record{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records), err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Verify checks every record: the block it names must still be on the chain with the recorded hash, and its
+// anchor must still hold it. Records of anchors that are no longer configured cannot be checked against the
+// anchor and fail.
+func (a *Anchorer) Verify() ([]Verification, error) {
+	records, err := a.Records()
+	if err != nil {
+		return nil, err
+	}
+	anchors := make(map[string]Anchor, len(a.Anchors))
+	for _, anchor := range a.Anchors {
+		anchors[anchor.Name()] = anchor
+	}
+
+	verifications := make([]Verification, len(records))
+	for i, record := range records {
+		verifications[i] = Verification{Record: record}
+		block := a.Chain.BlockAt(record.Height)
+		anchor, ok := anchors[record.Anchor]
+		switch {
+		case block == nil:
+			err = fmt.Errorf("block %d is not on the chain", record.Height)
+		case block.Hash != record.BlockHash:
+			err = fmt.Errorf("block %d on the chain is %s, not the anchored %s", record.Height, block.Hash, record.BlockHash)
+		case !ok:
+			err = fmt.Errorf("anchor %s is not configured", record.Anchor)
+		default:
+			err = anchor.Verify(&record)
+		}
+		if err != nil {
+			verifications[i].Error = err.Error()
+		} else {
+			verifications[i].Valid = true
+		}
+	}
+	return verifications, nil
+}
+
+// Start publishes the chain's tip every interval until stop is closed, logging failures.
+// It is meant to be run in its own goroutine.
+func (a *Anchorer) Start(interval time.Duration, stop <-chan struct{}, logger *utils.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			records, err := a.AnchorTip()
+			if err != nil {
+				logger.Error("Failed to anchor the chain tip:", err)
+			}
+			for _, record := range records {
+				logger.Info("Anchored block", record.Height, "to", record.Anchor)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package anchor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"blockchain/internal/blockchain"
+)
+
+// TestLogAnchor tests that a transparency log verifies its records and detects rewritten entries.
+func TestLogAnchor(t *testing.T) {
+	log := &Log{Path: filepath.Join(t.TempDir(), "anchors", "log.jsonl")}
+	first, err := log.Publish(1, strings.Repeat("a", 64))
+	if err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	second, err := log.Publish(2, strings.Repeat("b", 64))
+	if err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	for _, record := range []*Record{first, second} {
+		if err := log.Verify(record); err != nil {
+			t.Errorf("Expected block %d to verify, but got %v", record.Height, err)
+		}
+	}
+	entries, _ := log.Entries()
+	if len(entries) != 2 || entries[1].Previous != entries[0].Hash {
+		t.Errorf("Expected two chained entries, but got %+v", entries)
+	}
+
+	forged := *first
+	forged.Reference = strings.Repeat("0", 64)
+	if err := log.Verify(&forged); !errors.Is(err, ErrNotAnchored) {
+		t.Errorf("Expected ErrNotAnchored for an unknown entry, but got %v", err)
+	}
+
+	data, _ := os.ReadFile(log.Path)
+	os.WriteFile(log.Path, []byte(strings.Replace(string(data), strings.Repeat("a", 64), strings.Repeat("c", 64), 1)), 0o644)
+	if err := log.Verify(second); !errors.Is(err, ErrTampered) {
+		t.Errorf("Expected ErrTampered for a rewritten entry, but got %v", err)
+	}
+}
+
+// TestAnchorer tests that the chain's tip is anchored once and that records are checked against the chain.
+func TestAnchorer(t *testing.T) {
+	bc := blockchain.GetBlockchain("SHA-256")
+	bc.AddBlock("Test Block 1")
+	dir := t.TempDir()
+	anchorer := &Anchorer{Chain: bc, Anchors: []Anchor{&Log{Path: filepath.Join(dir, "log.jsonl")}}, Path: filepath.Join(dir, "records.jsonl")}
+
+	records, err := anchorer.AnchorTip()
+	if err != nil || len(records) != 1 || records[0].Height != 1 || records[0].BlockHash != bc.LastBlock().Hash {
+		t.Fatalf("Expected block 1 to be anchored, but got %v (%v)", records, err)
+	}
+	if records, err := anchorer.AnchorTip(); err != nil || len(records) != 0 {
+		t.Errorf("Expected an unchanged tip not to be anchored again, but got %v (%v)", records, err)
+	}
+	bc.AddBlock("Test Block 2")
+	if records, err := anchorer.AnchorTip(); err != nil || len(records) != 1 || records[0].Height != 2 {
+		t.Errorf("Expected block 2 to be anchored, but got %v (%v)", records, err)
+	}
+
+	verifications, err := anchorer.Verify()
+	if err != nil || len(verifications) != 2 || !verifications[0].Valid || !verifications[1].Valid {
+		t.Fatalf("Expected both records to verify, but got %+v (%v)", verifications, err)
+	}
+
+	// A chain whose block 1 differs from the anchored one fails verification.
+	anchorer.Chain = blockchain.GetBlockchain("SHA-256")
+	anchorer.Chain.AddBlock("Rewritten Block 1")
+	verifications, _ = anchorer.Verify()
+	if verifications[0].Valid || !strings.Contains(verifications[0].Error, "not the anchored") || verifications[1].Valid {
+		t.Errorf("Expected the rewritten chain to fail verification, but got %+v", verifications)
+	}
+}
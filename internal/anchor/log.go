@@ -0,0 +1,126 @@
+package anchor
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LogEntry is an entry of a transparency log. Each entry commits to the one before it, so that altering,
+// reordering or removing any entry but the last breaks the hashes of the entries after it.
+type LogEntry struct {
+	Index     int    `json:"index"`      // Position of the entry in the log, from zero.
+	Height    int    `json:"height"`     // Height of the anchored block.
+	BlockHash string `json:"block_hash"` // Hash of the anchored block.
+	Time      int64  `json:"time"`       // When the entry was appended, in Unix milliseconds.
+	Previous  string `json:"previous"`   // Hash of the previous entry; empty for the first.
+	Hash      string `json:"hash"`       // Hash of the entry's other fields; see ComputeHash.
+}
+
+// ComputeHash returns the hash of the entry's fields other than Hash, which Hash must equal.
+func (e *LogEntry) ComputeHash() string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%s|%d|%s", e.Index, e.Height, e.BlockHash, e.Time, e.Previous)))
+	return hex.EncodeToString(hash[:])
+}
+
+// Log is an Anchor that appends block hashes to a local append-only transparency log file, one JSON LogEntry
+// per line. Copies of the file kept elsewhere, or its latest entry hash published out of band, make any later
+// rewrite of the log evident.
+type Log struct {
+	Path string
+
+	mu sync.Mutex // Serializes appends.
+}
+
+// Name returns "log:" followed by the log's path.
+func (l *Log) Name() string {
+	return "log:" + l.Path
+}
+
+// Entries reads the log and checks that each entry's hash is correct and commits to the entry before it.
+// Returns an error wrapping ErrTampered if the log has been altered.
+func (l *Log) Entries() ([]LogEntry, error) {
+	file, err := os.Open(l.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []LogEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []LogEntry{}
+	previous := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrTampered, len(entries), err)
+		}
+		if entry.Index != len(entries) || entry.Previous != previous || entry.Hash != entry.ComputeHash() {
+			return nil, fmt.Errorf("%w: entry %d does not match its hash or the entry before it", ErrTampered, len(entries))
+		}
+		entries = append(entries, entry)
+		previous = entry.Hash
+	}
+	return entries, scanner.Err()
+}
+
+// Publish appends an entry for the block to the log, after checking the entries already in it. The record
+// references the entry by its hash.
+func (l *Log) Publish(height int, blockHash string) (*Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.Entries()
+	if err != nil {
+		return nil, err
+	}
+	entry := LogEntry{Index: len(entries), Height: height, BlockHash: blockHash, Time: time.Now().UnixMilli()}
+	if len(entries) > 0 {
+		entry.Previous = entries[len(entries)-1].Hash
+	}
+	entry.Hash = entry.ComputeHash()
+
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	line, _ := json.Marshal(entry)
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+	return &Record{Anchor: l.Name(), Height: height, BlockHash: blockHash, Time: entry.Time, Reference: entry.Hash}, nil
+}
+
+// Verify checks the whole log and that it holds the entry the record references, for the same block.
+func (l *Log) Verify(record *Record) error {
+	entries, err := l.Entries()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Hash != record.Reference {
+			continue
+		}
+		if entry.Height != record.Height || entry.BlockHash != record.BlockHash {
+			return fmt.Errorf("%w: entry %d anchors block %d (%s)", ErrTampered, entry.Index, entry.Height, entry.BlockHash)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: no entry %s in %s", ErrNotAnchored, record.Reference, l.Path)
+}
@@ -0,0 +1,129 @@
+package anchor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"blockchain/internal/blockchain"
+	"blockchain/pkg/merkle"
+)
+
+// Remote is an Anchor that notarizes block hashes on another instance of this blockchain through its API, so
+// that the other chain's blocks and Merkle proofs attest to them. The record references the notarization
+// transaction on the other chain and the height and hash of the block that includes it.
+type Remote struct {
+	Node   string       // Base URL of the other node's API, e.g. http://anchor.example.com:8080.
+	Token  string       // Notary token of the other node, sent as a bearer token when publishing.
+	Client *http.Client // Client for the requests; one with a timeout of RemoteTimeout if nil.
+}
+
+// RemoteTimeout bounds the requests of a Remote without a Client.
+const RemoteTimeout = 30 * time.Second
+
+// defaultClient is the client of a Remote without a Client.
+var defaultClient = &http.Client{Timeout: RemoteTimeout}
+
+// Name returns "chain:" followed by the other node's URL.
+func (r *Remote) Name() string {
+	return "chain:" + r.Node
+}
+
+// client returns the HTTP client to use.
+func (r *Remote) client() *http.Client {
+	if r.Client == nil {
+		return defaultClient
+	}
+	return r.Client
+}
+
+// Publish notarizes the block hash on the other chain. A hash that is already notarized there, e.g. after an
+// earlier publication whose record was lost, is recorded with its existing notarization.
+func (r *Remote) Publish(height int, blockHash string) (*Record, error) {
+	body, _ := json.Marshal(blockchain.Notarization{Hash: blockHash, Name: "anchor", Note: "block " + strconv.Itoa(height)})
+	req, err := http.NewRequest(http.MethodPost, r.Node+"/notary", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.Token)
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		message, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("notarization rejected: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	var notarized blockchain.NotaryRecord
+	if err := json.NewDecoder(resp.Body).Decode(&notarized); err != nil {
+		return nil, err
+	}
+	return &Record{
+		Anchor:       r.Name(),
+		Height:       height,
+		BlockHash:    blockHash,
+		Time:         time.Now().UnixMilli(),
+		Reference:    notarized.TxID,
+		AnchorHeight: notarized.Height,
+		AnchorBlock:  notarized.BlockHash,
+	}, nil
+}
+
+// Verify fetches the notarization of the record's block hash from the other node and checks it: it must be the
+// referenced transaction, notarize exactly that hash, and be proven included in the recorded block, which the
+// other chain must still have at the recorded height. A notarization the other chain has since moved to another
+// block, e.g. after its history was rewritten, does not verify.
+func (r *Remote) Verify(record *Record) error {
+	var notarized blockchain.NotaryRecord
+	status, err := r.get("/notary/verify?hash="+url.QueryEscape(record.BlockHash), &notarized)
+	if status == http.StatusNotFound {
+		return fmt.Errorf("%w: %s is not notarized on %s", ErrNotAnchored, record.BlockHash, r.Node)
+	}
+	if err != nil {
+		return err
+	}
+	tx := blockchain.Transaction{Notarization: &notarized.Notarization}
+	if notarized.TxID != record.Reference || notarized.Hash != record.BlockHash || tx.ID() != notarized.TxID {
+		return fmt.Errorf("%w: %s is notarized by %s, not %s", ErrTampered, record.BlockHash, notarized.TxID, record.Reference)
+	}
+
+	if notarized.Height != record.AnchorHeight || notarized.BlockHash != record.AnchorBlock {
+		return fmt.Errorf("%w: %s is in block %d (%s), not the recorded %d (%s)", ErrTampered, notarized.TxID, notarized.Height, notarized.BlockHash, record.AnchorHeight, record.AnchorBlock)
+	}
+
+	var block blockchain.Block
+	if _, err := r.get("/block?index="+strconv.Itoa(record.AnchorHeight), &block); err != nil {
+		return err
+	}
+	if block.Hash != record.AnchorBlock {
+		return fmt.Errorf("%w: block %d of %s is %s, not the recorded %s", ErrTampered, record.AnchorHeight, r.Node, block.Hash, record.AnchorBlock)
+	}
+	if notarized.Proof == nil || notarized.Proof.TxID != notarized.TxID {
+		return fmt.Errorf("%w: no proof of %s", ErrTampered, notarized.TxID)
+	}
+	if err := merkle.VerifyTxProof(record.AnchorBlock, notarized.Proof); err != nil {
+		return fmt.Errorf("%w: %v", ErrTampered, err)
+	}
+	return nil
+}
+
+// get fetches path from the other node and decodes the JSON response into v. It returns the response status.
+func (r *Remote) get(path string, v any) (int, error) {
+	resp, err := r.client().Get(r.Node + path)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("%s: %s: %s", path, resp.Status, bytes.TrimSpace(message))
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
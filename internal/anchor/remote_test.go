@@ -0,0 +1,59 @@
+package anchor_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blockchain/internal/anchor"
+	"blockchain/internal/api"
+	"blockchain/internal/blockchain"
+	"blockchain/internal/mempool"
+	"blockchain/internal/utils"
+)
+
+// TestRemoteAnchor tests anchoring block hashes on another chain through its API.
+func TestRemoteAnchor(t *testing.T) {
+	other := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{})
+	handlers := api.NewHandlers(other, utils.NewLogger("Test: ", 0))
+	handlers.Mempool = mempool.New(other)
+	handlers.NotaryToken = "secret"
+	server := httptest.NewServer(api.RegisterRoutes(handlers))
+	defer server.Close()
+	remote := &anchor.Remote{Node: server.URL, Token: "secret"}
+
+	hash := strings.Repeat("ab", 32)
+	record, err := remote.Publish(7, hash)
+	if err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if other.Len() != 2 || record.Reference != other.LastBlock().Transactions[0].ID() || record.AnchorHeight != 1 || record.AnchorBlock != other.LastBlock().Hash {
+		t.Errorf("Expected the hash to be notarized on the other chain, but got %+v", record)
+	}
+	if err := remote.Verify(record); err != nil {
+		t.Errorf("Expected the record to verify, but got %v", err)
+	}
+
+	// Publishing again finds the existing notarization.
+	again, err := remote.Publish(7, hash)
+	if err != nil || again.Reference != record.Reference || other.Len() != 2 {
+		t.Errorf("Expected the existing notarization, but got %+v (%v)", again, err)
+	}
+
+	forged := *record
+	forged.BlockHash = strings.Repeat("cd", 32)
+	if err := remote.Verify(&forged); !errors.Is(err, anchor.ErrNotAnchored) {
+		t.Errorf("Expected ErrNotAnchored for a hash that was never published, but got %v", err)
+	}
+	forged = *record
+	forged.Reference = strings.Repeat("0", 64)
+	if err := remote.Verify(&forged); !errors.Is(err, anchor.ErrTampered) {
+		t.Errorf("Expected ErrTampered for another transaction, but got %v", err)
+	}
+	forged = *record
+	forged.AnchorBlock = other.BlockAt(0).Hash
+	if err := remote.Verify(&forged); !errors.Is(err, anchor.ErrTampered) {
+		t.Errorf("Expected ErrTampered for another block of the other chain, but got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GetAccountHandler handles the API request to get the balance, nonce and storage of an account.
+// This is a GET request handler.
+// The address is the last path segment; unused addresses are returned with a zero balance and nonce.
+func (h *Handlers) GetAccountHandler(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if address == "" {
+		http.Error(w, "Address is required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.Blockchain.Account(address)); err != nil {
+		http.Error(w, "Failed to encode account", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode account:", err)
+		return
+	}
+	h.Logger.Info("Account retrieved:", address)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// AnchorsHandler handles the API requests to list the records of the chain tips published to anchors, and to
+// publish the tip now.
+// GET lists the records, oldest first. POST publishes the tip to every anchor, unless it has already been
+// published, and answers with the new records; if some anchors failed, with 502 Bad Gateway and the errors.
+// Nodes without anchors answer with 404 Not Found.
+func (h *Handlers) AnchorsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Anchorer == nil {
+		http.Error(w, "Anchoring is disabled", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		records, err := h.Anchorer.Records()
+		if err != nil {
+			http.Error(w, "Failed to read anchor records", http.StatusInternalServerError)
+			h.Logger.Error("Failed to read anchor records:", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(records); err != nil {
+			http.Error(w, "Failed to encode anchor records", http.StatusInternalServerError)
+			h.Logger.Error("Failed to encode anchor records:", err)
+			return
+		}
+		h.Logger.Info("Anchor records retrieved")
+	case http.MethodPost:
+		records, err := h.Anchorer.AnchorTip()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			h.Logger.Error("Failed to anchor the chain tip:", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records)
+		h.Logger.Info("Chain tip anchored:", len(records), "records")
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// VerifyAnchorsHandler handles the API request to verify every anchor record against the chain and its anchor.
+// This is a GET request handler.
+// It answers with the outcome of each record, oldest first; nodes without anchors answer with 404 Not Found.
+func (h *Handlers) VerifyAnchorsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Anchorer == nil {
+		http.Error(w, "Anchoring is disabled", http.StatusNotFound)
+		return
+	}
+
+	verifications, err := h.Anchorer.Verify()
+	if err != nil {
+		http.Error(w, "Failed to read anchor records", http.StatusInternalServerError)
+		h.Logger.Error("Failed to read anchor records:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(verifications); err != nil {
+		http.Error(w, "Failed to encode anchor verifications", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode anchor verifications:", err)
+		return
+	}
+	h.Logger.Info("Anchor records verified:", len(verifications))
+}
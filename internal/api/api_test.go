@@ -2,14 +2,25 @@ package api
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 
+	"blockchain/internal/anchor"
 	"blockchain/internal/blockchain"
+	"blockchain/internal/blockchain/chaintest"
+	"blockchain/internal/crypto"
+	"blockchain/internal/mempool"
 	"blockchain/internal/utils"
+	"blockchain/internal/vm"
+	"blockchain/pkg/merkle"
 )
 
 // TestAddBlockHandler tests the AddBlockHandler to ensure it correctly adds a block to the blockchain.
@@ -52,6 +63,39 @@ func TestAddBlockHandler(t *testing.T) {
 	}
 }
 
+// TestAddBlockHandlerTooLarge tests that oversized requests, payloads and blocks with too many transactions are
+// rejected with 413.
+func TestAddBlockHandlerTooLarge(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.GetBlockchain("SHA-256")
+	handlers := NewHandlers(bc, logger)
+	handler := http.HandlerFunc(handlers.AddBlockHandler)
+
+	bodies := map[string]map[string]any{
+		"payload":      {"data": strings.Repeat("x", blockchain.MaxPayloadSize+1)},
+		"body":         {"data": strings.Repeat("x", maxRequestBodySize)},
+		"transactions": {"data": "", "transactions": make([]struct{}, blockchain.MaxBlockTransactions+1)},
+	}
+	for name, body := range bodies {
+		bodyBytes, _ := json.Marshal(body)
+		req, err := http.NewRequest("POST", "/addblock", bytes.NewBuffer(bodyBytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", name, status, http.StatusRequestEntityTooLarge)
+		}
+	}
+
+	if len(bc.Blocks) != 1 {
+		t.Errorf("Expected no blocks to be added, but the chain has %d blocks", len(bc.Blocks))
+	}
+}
+
 // TestGetBlockchainHandler tests the GetBlockchainHandler to ensure it returns the correct blockchain data.
 func TestGetBlockchainHandler(t *testing.T) {
 	logger := utils.NewLogger("Test: ", 0)
@@ -132,6 +176,31 @@ func TestGetBlockByIndexHandler(t *testing.T) {
 	}
 }
 
+// TestGetBlockByIndexHandlerPruned tests that pruned blocks are answered with 410 Gone and recent blocks are still served.
+func TestGetBlockByIndexHandlerPruned(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.GetBlockchain("SHA-256")
+	bc.PruneDepth = 1
+	handlers := NewHandlers(bc, logger)
+
+	bc.AddBlock("Test Block 1")
+	bc.AddBlock("Test Block 2")
+
+	for index, want := range map[string]int{"1": http.StatusGone, "2": http.StatusOK} {
+		rr := httptest.NewRecorder()
+		handlers.GetBlockByIndexHandler(rr, httptest.NewRequest("GET", "/block?index="+index, nil))
+		if rr.Code != want {
+			t.Errorf("Block %s: handler returned wrong status code: got %v want %v", index, rr.Code, want)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handlers.ExportHandler(rr, httptest.NewRequest("GET", "/export?from=1", nil))
+	if rr.Code != http.StatusGone {
+		t.Errorf("Export of pruned blocks returned wrong status code: got %v want %v", rr.Code, http.StatusGone)
+	}
+}
+
 // TestGetLastBlockHandler tests the GetLastBlockHandler to ensure it returns the last block in the blockchain.
 func TestGetLastBlockHandler(t *testing.T) {
 	logger := utils.NewLogger("Test: ", 0)
@@ -199,29 +268,712 @@ func TestValidateBlockchainHandler(t *testing.T) {
 	}
 
 	// Check the response body contains the expected validation message.
-	var response map[string]string
+	var response struct {
+		Message  string                    `json:"message"`
+		Valid    bool                      `json:"valid"`
+		Failures []blockchain.BlockFailure `json:"failures"`
+	}
 	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
 		t.Fatalf("Failed to unmarshal response body: %v", err)
 	}
 
-	if response["message"] != "Blockchain is valid" {
-		t.Errorf("Expected validation message 'Blockchain is valid', but got '%s'", response["message"])
+	if response.Message != "Blockchain is valid" {
+		t.Errorf("Expected validation message 'Blockchain is valid', but got '%s'", response.Message)
 	}
 
 	// Tamper with the blockchain to make it invalid.
 	bc.Blocks[1].Data = "Tampered Data"
 
-	// Serve the request again to validate the tampered blockchain.
+	// Incremental validation trusts the blocks it has already verified.
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code for incremental validation: got %v want %v", status, http.StatusOK)
+	}
+
+	// Serve a full validation request to validate the tampered blockchain.
+	req, err = http.NewRequest("GET", "/validate?full=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	rr = httptest.NewRecorder()  // Reset the response recorder
 	handler.ServeHTTP(rr, req)
 
+	// Check the status code reports the conflict.
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+	}
+
 	// Unmarshal the response again to check the updated validation status.
 	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
 		t.Fatalf("Failed to unmarshal response body after tampering: %v", err)
 	}
 
 	// Check that the blockchain is now invalid.
-	if response["message"] != "Blockchain is invalid" {
-		t.Errorf("Expected validation message 'Blockchain is invalid', but got '%s'", response["message"])
+	if response.Message != "Blockchain is invalid" {
+		t.Errorf("Expected validation message 'Blockchain is invalid', but got '%s'", response.Message)
+	}
+
+	// Check that the report names the tampered block.
+	if len(response.Failures) != 1 || response.Failures[0].Height != 1 || response.Failures[0].Reason != blockchain.ReasonHashMismatch {
+		t.Errorf("Expected a single hash mismatch at height 1, but got %+v", response.Failures)
+	}
+}
+
+// TestValidateBlockchainHandlerRange tests that ValidateBlockchainHandler honours the from/to query parameters.
+func TestValidateBlockchainHandlerRange(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.GetBlockchain("SHA-256")
+	handlers := NewHandlers(bc, logger)
+
+	bc.AddBlock("Test Block 1")
+	bc.AddBlock("Test Block 2")
+
+	// Tamper with block 1; a range starting at block 2 must still be valid.
+	bc.Blocks[1].Data = "Tampered Data"
+
+	req, err := http.NewRequest("GET", "/validate?from=2&to=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.ValidateBlockchainHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	// An out-of-range request must be rejected.
+	req, err = http.NewRequest("GET", "/validate?from=0&to=5", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(handlers.ValidateBlockchainHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+// TestGetAccountHandler tests that the accounts endpoint returns balances and nonces after a transfer.
+func TestGetAccountHandler(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+	mux := RegisterRoutes(NewHandlers(bc, logger))
+
+	payment, _ := json.Marshal(chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 40, Nonce: 0}))
+	body := fmt.Sprintf(`{"data": "payment", "transactions": [%s]}`, payment)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/addblock", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/addblock", strings.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Replayed transaction: handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	for address, want := range map[string]blockchain.Account{chaintest.Addr("alice"): {Balance: 60, Nonce: 1}, chaintest.Addr("bob"): {Balance: 40}, chaintest.Addr("nobody"): {}} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/accounts/"+address, nil))
+		var info blockchain.AccountInfo
+		if err := json.Unmarshal(rr.Body.Bytes(), &info); err != nil {
+			t.Fatalf("Failed to unmarshal response body: %v", err)
+		}
+		if info.Address != address || info.Account != want {
+			t.Errorf("Expected %s to be %+v, but got %+v", address, want, info)
+		}
+	}
+}
+
+// TestSubmitTransactionHandler tests submitting UTXO transactions to the mempool and including them in a block.
+func TestSubmitTransactionHandler(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Ledger: blockchain.LedgerUTXO, Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+	handlers := NewHandlers(bc, logger)
+	handlers.Mempool = mempool.New(bc)
+	mux := RegisterRoutes(handlers)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/utxos/"+chaintest.Addr("alice"), nil))
+	var coins []blockchain.UnspentOutput
+	if err := json.Unmarshal(rr.Body.Bytes(), &coins); err != nil || len(coins) != 1 {
+		t.Fatalf("Expected one unspent output for alice, but got %s", rr.Body)
+	}
+
+	for _, test := range []struct {
+		to   string
+		want int
+	}{{"bob", http.StatusAccepted}, {"carol", http.StatusConflict}} {
+		tx := &blockchain.Transaction{Inputs: []blockchain.TxInput{coins[0].TxInput}, Outputs: []blockchain.TxOutput{{Address: chaintest.Addr(test.to), Amount: 100}}}
+		body, _ := json.Marshal(chaintest.Signed(tx, chaintest.Addr("alice")))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("POST", "/transactions", bytes.NewReader(body)))
+		if rr.Code != test.want {
+			t.Errorf("Paying %s: handler returned wrong status code: got %v want %v", test.to, rr.Code, test.want)
+		}
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/transactions", strings.NewReader(`{"outputs": [{"address": "`+chaintest.Addr("bob")+`", "amount": 1}]}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Minting: handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/transactions/pending", nil))
+	var pending []*blockchain.Transaction
+	if err := json.Unmarshal(rr.Body.Bytes(), &pending); err != nil || len(pending) != 1 {
+		t.Fatalf("Expected one pending transaction, but got %s", rr.Body)
+	}
+
+	// Adding a block includes the pending transaction.
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/addblock", strings.NewReader(`{"data": "block"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if handlers.Mempool.Len() != 0 || len(bc.UnspentOutputs(chaintest.Addr("bob"))) != 1 || len(bc.UnspentOutputs(chaintest.Addr("alice"))) != 0 {
+		t.Error("Expected the pending transaction to be included in the block")
+	}
+}
+
+// TestGetAccountProofHandler tests that account proofs verify against the state roots of the tip and of
+// earlier blocks.
+func TestGetAccountProofHandler(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+	bc.AddBlock("payment", chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 40}))
+	mux := RegisterRoutes(NewHandlers(bc, logger))
+
+	for _, test := range []struct {
+		path   string
+		height int
+		exists bool
+	}{
+		{"/proofs/account/" + chaintest.Addr("bob"), 1, true},
+		{"/proofs/account/" + chaintest.Addr("bob") + "?height=0", 0, false},
+		{"/proofs/account/" + chaintest.Addr("alice") + "?height=0", 0, true},
+		{"/proofs/account/nobody", 1, false},
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", test.path, nil))
+		var proof merkle.AccountProof
+		if err := json.Unmarshal(rr.Body.Bytes(), &proof); err != nil {
+			t.Fatalf("%s: failed to unmarshal response body %s: %v", test.path, rr.Body, err)
+		}
+		if proof.Height != test.height || proof.Exists != test.exists {
+			t.Errorf("%s: expected height %d and exists %v, but got %+v", test.path, test.height, test.exists, proof)
+		}
+		if err := merkle.VerifyAccountProof(bc.BlockAt(test.height).StateRoot, &proof); err != nil {
+			t.Errorf("%s: proof does not verify: %v", test.path, err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/proofs/account/"+chaintest.Addr("bob")+"?height=2", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// TestGetTransactionProofHandler tests that transaction proofs verify offline against the block hash.
+func TestGetTransactionProofHandler(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+	var txs []*blockchain.Transaction
+	for nonce := uint64(0); nonce < 5; nonce++ {
+		txs = append(txs, chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 1, Nonce: nonce}))
+	}
+	if err := bc.AddBlock("payments", txs...); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	mux := RegisterRoutes(NewHandlers(bc, logger))
+
+	for index, tx := range txs {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/proofs/tx/"+tx.ID(), nil))
+		var proof merkle.TxProof
+		if err := json.Unmarshal(rr.Body.Bytes(), &proof); err != nil {
+			t.Fatalf("Failed to unmarshal response body %s: %v", rr.Body, err)
+		}
+		if proof.Height != 1 || proof.Index != index || proof.Count != len(txs) {
+			t.Errorf("Expected transaction %d of block 1, but got %+v", index, proof)
+		}
+		if err := merkle.VerifyTxProof(bc.LastBlock().Hash, &proof); err != nil {
+			t.Errorf("Proof of transaction %d does not verify: %v", index, err)
+		}
+		if err := merkle.VerifyTxProof(bc.BlockAt(0).Hash, &proof); err == nil {
+			t.Errorf("Expected the proof of transaction %d to fail against another block", index)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/proofs/tx/unknown", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+// TestCombineSignaturesHandler tests creating a 2-of-2 multisig address, combining the signatures of two
+// copies of a transaction spending from it and submitting the result.
+func TestCombineSignaturesHandler(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	first, _ := crypto.GenerateKey(crypto.Ed25519, nil)
+	second, _ := crypto.GenerateKey(crypto.Secp256k1, nil)
+
+	mux := RegisterRoutes(NewHandlers(blockchain.GetBlockchain("SHA-256"), logger))
+	body := fmt.Sprintf(`{"threshold": 2, "signers": [%q, %q]}`, crypto.Address(first), crypto.Address(second))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/multisig", strings.NewReader(body)))
+	var created struct {
+		Address string                    `json:"address"`
+		Policy  blockchain.MultisigPolicy `json:"policy"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Failed to create a multisig address: %v %s", rr.Code, rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/multisig", strings.NewReader(`{"threshold": 3, "signers": []}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid policy: handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Alloc: map[string]uint64{created.Address: 100}})
+	handlers := NewHandlers(bc, logger)
+	handlers.Mempool = mempool.New(bc)
+	mux = RegisterRoutes(handlers)
+
+	var copies []*blockchain.Transaction
+	for _, signer := range []crypto.Signer{first, second} {
+		tx := &blockchain.Transaction{From: created.Address, To: chaintest.Addr("bob"), Amount: 60, Multisig: []blockchain.MultisigPolicy{created.Policy}}
+		tx.Sign(signer)
+		copies = append(copies, tx)
+	}
+	encoded, _ := json.Marshal(copies[0])
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/transactions", bytes.NewReader(encoded)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Partially-signed transaction: handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	encoded, _ = json.Marshal(copies)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/transactions/combine", bytes.NewReader(encoded)))
+	var combined struct {
+		Transaction *blockchain.Transaction     `json:"transaction"`
+		Multisig    []blockchain.MultisigStatus `json:"multisig"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &combined); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Failed to combine signatures: %v %s", rr.Code, rr.Body)
+	}
+	if len(combined.Multisig) != 1 || !combined.Multisig[0].Complete {
+		t.Errorf("Expected the combined transaction to be complete, but got %+v", combined.Multisig)
+	}
+
+	encoded, _ = json.Marshal(combined.Transaction)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/transactions", bytes.NewReader(encoded)))
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("Combined transaction: handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusAccepted, rr.Body)
+	}
+}
+
+// TestBlockProducerReward tests that blocks added through the API include pending transactions and pay the
+// configured producer the block reward and fees.
+func TestBlockProducerReward(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{
+		Issuance: blockchain.IssuanceSchedule{InitialReward: 50},
+		Alloc:    map[string]uint64{chaintest.Addr("alice"): 100},
+	})
+	handlers := NewHandlers(bc, logger)
+	handlers.Mempool = mempool.New(bc)
+	handlers.Producer = chaintest.Addr("miner")
+	mux := RegisterRoutes(handlers)
+
+	body, _ := json.Marshal(chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 10, Fee: 3}))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/transactions", bytes.NewReader(body)))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusAccepted, rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/addblock", strings.NewReader(`{"data": "block"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if miner, alice := bc.Account(chaintest.Addr("miner")).Balance, bc.Account(chaintest.Addr("alice")).Balance; miner != 53 || alice != 87 {
+		t.Errorf("Expected the miner to earn 53 and alice to keep 87, but got %d and %d", miner, alice)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/issuance", nil))
+	var issuance struct {
+		Height          int    `json:"height"`
+		NextReward      uint64 `json:"next_reward"`
+		ScheduledSupply uint64 `json:"scheduled_supply"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &issuance); err != nil || issuance.Height != 1 || issuance.NextReward != 50 || issuance.ScheduledSupply != 50 {
+		t.Errorf("Unexpected issuance response: %s", rr.Body)
+	}
+
+	// Concurrent requests each add a block with its own coinbase.
+	var wg sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest("POST", "/addblock", strings.NewReader(`{"data": "concurrent"}`)))
+			codes[i] = rr.Code
+		}()
+	}
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Concurrent request %d returned status code %d, want %d", i, code, http.StatusOK)
+		}
+	}
+	if miner := bc.Account(chaintest.Addr("miner")).Balance; miner != 53+8*50 {
+		t.Errorf("Expected the miner to earn %d after the concurrent blocks, but got %d", 53+8*50, miner)
+	}
+}
+
+// TestContractHandlers tests inspecting a contract, calling it without a transaction and reading a receipt.
+func TestContractHandlers(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Alloc: map[string]uint64{chaintest.Addr("alice"): 10000}})
+	code, err := vm.Assemble(`PUSH "value" SLOAD PUSH 1 ADD RETURN`)
+	if err != nil {
+		t.Fatalf("Failed to assemble: %v", err)
+	}
+	deploy := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), Code: hex.EncodeToString(code), GasLimit: 1000, Fee: 1000})
+	if err := bc.AddBlock("deploy", deploy); err != nil {
+		t.Fatalf("Failed to deploy: %v", err)
+	}
+	address := blockchain.ContractAddress(chaintest.Addr("alice"), 0)
+	mux := RegisterRoutes(NewHandlers(bc, logger))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/contracts/"+address, nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), hex.EncodeToString(code)) {
+		t.Errorf("Expected the contract's code, but got %v: %s", rr.Code, rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/contracts/"+chaintest.Addr("alice"), nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an account, but got %v", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/contracts/"+address+"/call", strings.NewReader(`{"caller": "bob"}`)))
+	var call struct {
+		Return  string `json:"return"`
+		GasUsed uint64 `json:"gas_used"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &call); err != nil || call.Return != "01" || call.GasUsed == 0 || call.Error != "" {
+		t.Errorf("Unexpected call response: %s", rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/contracts/"+address+"/call", strings.NewReader(`{"gas_limit": 3}`)))
+	if err := json.Unmarshal(rr.Body.Bytes(), &call); err != nil || call.Error != vm.ErrOutOfGas.Error() {
+		t.Errorf("Expected the call to run out of gas, but got %s", rr.Body)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/receipts/"+deploy.ID(), nil))
+	var receipt blockchain.Receipt
+	if err := json.Unmarshal(rr.Body.Bytes(), &receipt); err != nil || receipt.ContractAddress != address || receipt.Status != blockchain.ReceiptSuccess {
+		t.Errorf("Unexpected receipt: %s", rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/receipts/unknown", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown receipt, but got %v", rr.Code)
+	}
+}
+
+// TestGetLogsHandler tests querying logs and the receipts of plain transactions.
+func TestGetLogsHandler(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Alloc: map[string]uint64{chaintest.Addr("alice"): 10000}})
+	code, _ := vm.Assemble(`PUSH "ping" PUSH "" LOG 1`)
+	bc.AddBlock("deploy", chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), Code: hex.EncodeToString(code), GasLimit: 1000, Fee: 1000}))
+	address := blockchain.ContractAddress(chaintest.Addr("alice"), 0)
+	bc.AddBlock("call", chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: address, GasLimit: 1000, Fee: 1000, Nonce: 1}))
+	handlers := NewHandlers(bc, logger)
+	handlers.Mempool = mempool.New(bc)
+	mux := RegisterRoutes(handlers)
+
+	for query, want := range map[string]int{
+		"":                                1,
+		"?from=2&to=2&address=" + address: 1,
+		"?to=1":                           0,
+		"?topic=" + hex.EncodeToString([]byte("ping")): 1,
+		"?topic=" + hex.EncodeToString([]byte("pong")): 0,
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/logs"+query, nil))
+		var logs []blockchain.LogEntry
+		if err := json.Unmarshal(rr.Body.Bytes(), &logs); err != nil || len(logs) != want {
+			t.Errorf("/logs%s: expected %d logs, but got %v: %s", query, want, rr.Code, rr.Body)
+		}
+	}
+	for _, query := range []string{"?from=x", "?from=2&to=1", "?topic=zz"} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/logs"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("/logs%s: expected 400, but got %v", query, rr.Code)
+		}
+	}
+
+	pending := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 1, Nonce: 2})
+	if err := handlers.Mempool.Add(pending); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/receipts/"+pending.ID(), nil))
+	if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "mempool") {
+		t.Errorf("Expected 404 naming the mempool for a pending transaction, but got %v: %s", rr.Code, rr.Body)
+	}
+	bc.AddBlock("transfer", pending)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/receipts/"+pending.ID(), nil))
+	var receipt blockchain.Receipt
+	if err := json.Unmarshal(rr.Body.Bytes(), &receipt); err != nil || receipt.Status != blockchain.ReceiptSuccess || receipt.BlockHash != bc.LastBlock().Hash {
+		t.Errorf("Unexpected receipt: %s", rr.Body)
+	}
+}
+
+// TestTokenHandlers tests querying a token, its holders and the token list.
+func TestTokenHandlers(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+	create := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), Token: &blockchain.TokenOperation{Op: blockchain.TokenCreate, Symbol: "MTR", Name: "Metering", Amount: 50}})
+	transfer := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Nonce: 1, Token: &blockchain.TokenOperation{Op: blockchain.TokenTransfer, Symbol: "MTR", Amount: 20}})
+	if err := bc.AddBlock("tokens", create, transfer); err != nil {
+		t.Fatalf("Failed to add token transactions: %v", err)
+	}
+	mux := RegisterRoutes(NewHandlers(bc, logger))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/tokens/MTR", nil))
+	var token blockchain.Token
+	if err := json.Unmarshal(rr.Body.Bytes(), &token); err != nil || token.Supply != 50 || token.Issuer != chaintest.Addr("alice") {
+		t.Errorf("Unexpected token: %s", rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/tokens/MTR/holders", nil))
+	var holders []blockchain.TokenHolder
+	if err := json.Unmarshal(rr.Body.Bytes(), &holders); err != nil || len(holders) != 2 || holders[0].Address != chaintest.Addr("alice") || holders[1].Balance != 20 {
+		t.Errorf("Unexpected holders: %s", rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/tokens", nil))
+	if !strings.Contains(rr.Body.String(), `"symbol":"MTR"`) {
+		t.Errorf("Expected the token list to include MTR, but got %s", rr.Body)
+	}
+	for _, path := range []string{"/tokens/XYZ", "/tokens/XYZ/holders"} {
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, but got %v", path, rr.Code)
+		}
+	}
+}
+
+func TestAssetHandlers(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+	hash := strings.Repeat("ab", 32)
+	mint := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), Asset: &blockchain.AssetOperation{Op: blockchain.AssetMint, ID: "deed-1", MetadataHash: hash}})
+	transfer := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Nonce: 1, Asset: &blockchain.AssetOperation{Op: blockchain.AssetTransfer, ID: "deed-1"}})
+	if err := bc.AddBlock("assets", mint, transfer); err != nil {
+		t.Fatalf("Failed to add asset transactions: %v", err)
+	}
+	mux := RegisterRoutes(NewHandlers(bc, logger))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/assets/deed-1", nil))
+	var asset blockchain.Asset
+	if err := json.Unmarshal(rr.Body.Bytes(), &asset); err != nil || asset.Owner != chaintest.Addr("bob") || asset.MetadataHash != hash {
+		t.Errorf("Unexpected asset: %s", rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/assets/deed-1/history", nil))
+	var history []blockchain.AssetEvent
+	if err := json.Unmarshal(rr.Body.Bytes(), &history); err != nil || len(history) != 2 || history[1].Owner != chaintest.Addr("bob") {
+		t.Errorf("Unexpected history: %s", rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/assets?owner="+chaintest.Addr("bob"), nil))
+	if !strings.Contains(rr.Body.String(), `"id":"deed-1"`) {
+		t.Errorf("Expected bob's assets to include deed-1, but got %s", rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/assets", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without an owner, but got %v", rr.Code)
+	}
+	for _, path := range []string{"/assets/deed-2", "/assets/deed-2/history"} {
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, but got %v", path, rr.Code)
+		}
+	}
+}
+
+func TestNotaryHandlers(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{})
+	handlers := NewHandlers(bc, logger)
+	mux := RegisterRoutes(handlers)
+	document := []byte("lease agreement")
+	digest := sha256.Sum256(document)
+	hash := hex.EncodeToString(digest[:])
+	upload := func(path string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("note", "signed")
+		file, _ := form.CreateFormFile("file", "lease.txt")
+		file.Write(document)
+		form.Close()
+		req := httptest.NewRequest("POST", path, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer secret")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	notarize := func(body string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/notary", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Notarizing takes a mempool and the notary token.
+	if rr := upload("/notary"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a notary token configured, but got %v", rr.Code)
+	}
+	handlers.Mempool = mempool.New(bc)
+	handlers.NotaryToken = "secret"
+	if rr := notarize(`{"hash":"`+strings.Repeat("ab", 32)+`"}`, "guess"); rr.Code != http.StatusUnauthorized || bc.Len() != 1 {
+		t.Errorf("Expected 401 for a wrong token, but got %v", rr.Code)
+	}
+
+	rr := upload("/notary")
+	var record blockchain.NotaryRecord
+	if err := json.Unmarshal(rr.Body.Bytes(), &record); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected the upload to be notarized, but got %v: %s", rr.Code, rr.Body)
+	}
+	if record.Hash != hash || record.Name != "lease.txt" || record.Note != "signed" || record.Size != int64(len(document)) || record.Height != 1 {
+		t.Errorf("Unexpected notarization: %+v", record)
+	}
+	if rr = upload("/notary"); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a second notarization, but got %v", rr.Code)
+	}
+
+	rr = upload("/notary/verify")
+	var verified blockchain.NotaryRecord
+	if err := json.Unmarshal(rr.Body.Bytes(), &verified); err != nil || verified.TxID != record.TxID {
+		t.Fatalf("Expected the upload to verify, but got %v: %s", rr.Code, rr.Body)
+	}
+	if err := merkle.VerifyTxProof(bc.Blocks[1].Hash, verified.Proof); err != nil {
+		t.Errorf("Expected a valid proof, but got %v", err)
+	}
+
+	rr = notarize(`{"hash":"`+strings.Repeat("ab", 32)+`","name":"report.pdf"}`, "secret")
+	if rr.Code != http.StatusOK || bc.Blocks[len(bc.Blocks)-1].Transactions[0].Notarization.Name != "report.pdf" {
+		t.Errorf("Expected a precomputed hash to be notarized, but got %v: %s", rr.Code, rr.Body)
+	}
+	for path, want := range map[string]int{
+		"/notary/verify?hash=" + strings.Repeat("ab", 32): http.StatusOK,
+		"/notary/verify?hash=" + strings.Repeat("cd", 32): http.StatusNotFound,
+		"/notary/verify": http.StatusBadRequest,
+	} {
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != want {
+			t.Errorf("%s: expected %v, but got %v", path, want, rr.Code)
+		}
+	}
+	if rr = notarize(`{"hash":"abcd"}`, "secret"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed hash, but got %v", rr.Code)
+	}
+	body, _ := json.Marshal(&blockchain.Transaction{Notarization: &blockchain.Notarization{Hash: strings.Repeat("ef", 32)}})
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/transactions", bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a notarization submitted as a transaction, but got %v", rr.Code)
+	}
+
+	// Concurrent notarizations of one document share a block: one wins and the others conflict.
+	height := bc.Len()
+	var wg sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = notarize(fmt.Sprintf(`{"hash":"%s","note":"copy %d"}`, strings.Repeat("12", 32), i), "secret").Code
+		}()
+	}
+	wg.Wait()
+	won := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			won++
+		case http.StatusConflict:
+		default:
+			t.Errorf("Expected 200 or 409 for a concurrent notarization, but got %v", code)
+		}
+	}
+	if won != 1 || bc.Len() != height+1 {
+		t.Errorf("Expected one notarization in one block, but got %d in %d blocks", won, bc.Len()-height)
+	}
+
+	handlers.NotaryRate = 1
+	if rr = notarize(`{"hash":"`+strings.Repeat("34", 32)+`"}`, "secret"); rr.Code != http.StatusOK {
+		t.Errorf("Expected a notarization within the rate, but got %v", rr.Code)
+	}
+	if rr = notarize(`{"hash":"`+strings.Repeat("56", 32)+`"}`, "secret"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 beyond the rate, but got %v", rr.Code)
+	}
+}
+
+func TestAnchorHandlers(t *testing.T) {
+	logger := utils.NewLogger("Test: ", 0)
+	bc := blockchain.GetBlockchain("SHA-256")
+	bc.AddBlock("Test Block")
+	handlers := NewHandlers(bc, logger)
+	rr := httptest.NewRecorder()
+	RegisterRoutes(handlers).ServeHTTP(rr, httptest.NewRequest("GET", "/anchors", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without anchors, but got %v", rr.Code)
+	}
+
+	dir := t.TempDir()
+	handlers.Anchorer = &anchor.Anchorer{Chain: bc, Anchors: []anchor.Anchor{&anchor.Log{Path: dir + "/log.jsonl"}}, Path: dir + "/records.jsonl"}
+	mux := RegisterRoutes(handlers)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/anchors", nil))
+	var records []anchor.Record
+	if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil || len(records) != 1 || records[0].BlockHash != bc.LastBlock().Hash {
+		t.Errorf("Expected the tip to be anchored, but got %v: %s", rr.Code, rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/anchors/verify", nil))
+	var verifications []anchor.Verification
+	if err := json.Unmarshal(rr.Body.Bytes(), &verifications); err != nil || len(verifications) != 1 || !verifications[0].Valid {
+		t.Errorf("Expected the record to verify, but got %s", rr.Body)
 	}
 }
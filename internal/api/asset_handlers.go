@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"blockchain/internal/blockchain"
+)
+
+// GetAssetsHandler handles the API request to list the assets owned by an address.
+// This is a GET request handler.
+// The owner query parameter is required; requests without it are answered with 400 Bad Request.
+func (h *Handlers) GetAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	owner := r.URL.Query().Get("owner")
+	if owner == "" {
+		http.Error(w, "Missing owner", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.Blockchain.Assets(owner)); err != nil {
+		http.Error(w, "Failed to encode assets", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode assets:", err)
+		return
+	}
+	h.Logger.Info("Assets retrieved:", owner)
+}
+
+// GetAssetHandler handles the API request to get the owner, approval and metadata of an asset.
+// This is a GET request handler.
+// The ID is the last path segment; assets that have not been minted are answered with 404 Not Found.
+func (h *Handlers) GetAssetHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	asset, err := h.Blockchain.Asset(id)
+	if errors.Is(err, blockchain.ErrUnknownAsset) {
+		http.Error(w, "Asset not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(asset); err != nil {
+		http.Error(w, "Failed to encode asset", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode asset:", err)
+		return
+	}
+	h.Logger.Info("Asset retrieved:", id)
+}
+
+// GetAssetHistoryHandler handles the API request to get the ownership history of an asset, oldest first: the
+// mint, transfers, approvals and burn recorded in the blocks.
+// This is a GET request handler.
+// Assets that have not been minted are answered with 404 Not Found.
+func (h *Handlers) GetAssetHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	events, err := h.Blockchain.AssetHistory(id)
+	if errors.Is(err, blockchain.ErrUnknownAsset) {
+		http.Error(w, "Asset not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		http.Error(w, "Failed to encode asset history", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode asset history:", err)
+		return
+	}
+	h.Logger.Info("Asset history retrieved:", id)
+}
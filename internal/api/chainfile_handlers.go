@@ -0,0 +1,136 @@
+package api
+
+import (
+	"blockchain/internal/blockchain"
+	"blockchain/internal/chainio"
+	"blockchain/internal/snapshot"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// importResponse is the JSON body returned by ImportHandler.
+type importResponse struct {
+	Message string `json:"message"`
+	*chainio.ImportResult
+}
+
+// ExportHandler handles the API request to export the blockchain as a portable chain file.
+// This is a GET request handler.
+// It accepts optional "format" (ndjson or binary, default ndjson) and "from" (default 0) query parameters.
+func (h *Handlers) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := chainio.FormatNDJSON
+	if formatStr := query.Get("format"); formatStr != "" {
+		var err error
+		if format, err = chainio.ParseFormat(formatStr); err != nil {
+			http.Error(w, "Invalid format", http.StatusBadRequest)
+			h.Logger.Warn("Invalid export format:", formatStr)
+			return
+		}
+	}
+
+	from, ok := h.heightParam(w, r, "from", 0)
+	if !ok {
+		return
+	}
+	if from < 0 || from > h.Blockchain.Len() {
+		http.Error(w, "Height out of range", http.StatusBadRequest)
+		h.Logger.Warn("Export height out of range:", from)
+		return
+	}
+	if pruned := h.Blockchain.PrunedHeight(); from < pruned {
+		http.Error(w, fmt.Sprintf("Blocks below height %d have been pruned; export from %d or later", pruned, pruned), http.StatusGone)
+		h.Logger.Warn("Export requested pruned height:", from)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	if err := chainio.Export(h.Blockchain, w, format, from); err != nil {
+		// The response has already started, so the client sees a truncated file.
+		h.Logger.Error("Failed to export blockchain:", err)
+		return
+	}
+	h.Logger.Info("Blockchain exported from height", from, "as", format)
+}
+
+// ImportHandler handles the API request to import a chain file into the blockchain.
+// This is a POST request handler.
+// The request body is a chain file in either format. Every block is validated before it is appended.
+// It accepts an optional "from" query parameter to resume an interrupted import at that height.
+func (h *Handlers) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	from, ok := h.heightParam(w, r, "from", 0)
+	if !ok {
+		return
+	}
+
+	result, err := chainio.Import(h.Blockchain, r.Body, from)
+	response := importResponse{Message: "Import completed", ImportResult: result}
+	status := http.StatusOK
+	switch {
+	case err == nil:
+		h.Logger.Info("Imported", result.Imported, "blocks, chain height is now", result.Height)
+	case errors.Is(err, blockchain.ErrInvalidBlock), errors.Is(err, chainio.ErrDiverged):
+		response.Message = err.Error()
+		status = http.StatusConflict
+		h.Logger.Warn("Import rejected:", err)
+	default:
+		response.Message = err.Error()
+		status = http.StatusBadRequest
+		h.Logger.Warn("Import failed:", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.Logger.Error("Failed to encode import result:", err)
+	}
+}
+
+// SnapshotHandler handles the API request to download the latest state snapshot, used by bootstrapping nodes.
+// This is a GET request handler.
+func (h *Handlers) SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Snapshots == nil {
+		http.Error(w, "Snapshots are disabled", http.StatusNotFound)
+		return
+	}
+
+	path, err := h.Snapshots.Latest()
+	if errors.Is(err, snapshot.ErrNoSnapshot) {
+		http.Error(w, "No snapshot available", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to find snapshot", http.StatusInternalServerError)
+		h.Logger.Error("Failed to find snapshot:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	http.ServeFile(w, r, path)
+	h.Logger.Info("Snapshot served:", path)
+}
+
+// heightParam parses an optional integer query parameter, writing a 400 response if it is malformed.
+// Returns the value (or defaultValue if absent) and whether the handler should continue.
+func (h *Handlers) heightParam(w http.ResponseWriter, r *http.Request, name string, defaultValue int) (int, bool) {
+	valueStr := r.URL.Query().Get(name)
+	if valueStr == "" {
+		return defaultValue, true
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		http.Error(w, "Invalid "+name+" height", http.StatusBadRequest)
+		h.Logger.Error("Invalid", name, "height format:", err)
+		return 0, false
+	}
+	return value, true
+}
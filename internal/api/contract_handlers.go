@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"blockchain/internal/blockchain"
+	"blockchain/internal/vm"
+)
+
+// GetContractHandler handles the API request to get the code, balance, nonce and storage of a contract.
+// This is a GET request handler.
+// The address is the last path segment; addresses without a contract are answered with 404 Not Found.
+func (h *Handlers) GetContractHandler(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	contract, err := h.Blockchain.Contract(address)
+	if errors.Is(err, blockchain.ErrNotContract) {
+		http.Error(w, "Contract not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(contract); err != nil {
+		http.Error(w, "Failed to encode contract", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode contract:", err)
+		return
+	}
+	h.Logger.Info("Contract retrieved:", address)
+}
+
+// CallContractHandler handles the API request to run a contract without submitting a transaction.
+// This is a POST request handler.
+// It expects {"caller": address, "args": [hex-encoded arguments], "gas_limit": gas} as the JSON body, all
+// optional; the gas limit defaults to blockchain.MaxGasPerTransaction. It answers with the result of running
+// the contract against the current state, which is left unchanged: the hex-encoded return value, the gas used,
+// the logs and, if execution failed, the error.
+func (h *Handlers) CallContractHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Caller   string   `json:"caller"`
+		Args     []string `json:"args"`
+		GasLimit uint64   `json:"gas_limit"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.Logger.Error("Failed to decode contract call:", err)
+		return
+	}
+	args := make([][]byte, len(request.Args))
+	for i, arg := range request.Args {
+		decoded, err := hex.DecodeString(arg)
+		if err != nil {
+			http.Error(w, "Arguments must be hex-encoded", http.StatusBadRequest)
+			return
+		}
+		args[i] = decoded
+	}
+	if request.GasLimit == 0 {
+		request.GasLimit = blockchain.MaxGasPerTransaction
+	}
+
+	address := r.PathValue("address")
+	result, err := h.Blockchain.CallContract(address, request.Caller, args, request.GasLimit)
+	if errors.Is(err, blockchain.ErrNotContract) {
+		http.Error(w, "Contract not found", http.StatusNotFound)
+		return
+	}
+	response := struct {
+		Return  string   `json:"return"`
+		GasUsed uint64   `json:"gas_used"`
+		Logs    []vm.Log `json:"logs"`
+		Error   string   `json:"error,omitempty"`
+	}{hex.EncodeToString(result.Return), result.GasUsed, result.Logs, ""}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	h.Logger.Info("Contract called:", address)
+}
+
+// GetReceiptHandler handles the API request to get the receipt of a transaction: its status, gas used, logs
+// and the height and hash of the block that included it.
+// This is a GET request handler.
+// The transaction ID is the last path segment; transactions that are not in a block are answered with 404 Not
+// Found, saying whether they are waiting in the mempool.
+func (h *Handlers) GetReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	receipt, ok := h.Blockchain.Receipt(id)
+	if !ok {
+		message := "Receipt not found"
+		if h.Mempool != nil && (h.Mempool.IsHeld(id) || containsTransaction(h.Mempool.Pending(), id)) {
+			message = "Receipt not found: the transaction is waiting in the mempool"
+		}
+		http.Error(w, message, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(receipt); err != nil {
+		http.Error(w, "Failed to encode receipt", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode receipt:", err)
+		return
+	}
+	h.Logger.Info("Receipt retrieved:", id)
+}
+
+// containsTransaction reports whether txs includes the transaction with the given ID.
+func containsTransaction(txs []*blockchain.Transaction, id string) bool {
+	for _, tx := range txs {
+		if tx.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLogsHandler handles the API request to query the logs emitted by contracts.
+// This is a GET request handler.
+// The optional query parameters from and to select a range of block heights, by default the last
+// blockchain.MaxLogRange blocks; address selects the logs of one contract, and topic, hex-encoded, the logs
+// with that topic. Ranges larger than blockchain.MaxLogRange blocks are rejected with 400 Bad Request.
+func (h *Handlers) GetLogsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := blockchain.LogFilter{FromHeight: -1, ToHeight: -1, Address: query.Get("address"), Topic: query.Get("topic")}
+	for name, height := range map[string]*int{"from": &filter.FromHeight, "to": &filter.ToHeight} {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				http.Error(w, "Invalid "+name+" height", http.StatusBadRequest)
+				return
+			}
+			*height = n
+		}
+	}
+
+	logs, err := h.Blockchain.Logs(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(logs); err != nil {
+		http.Error(w, "Failed to encode logs", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode logs:", err)
+		return
+	}
+	h.Logger.Info("Logs retrieved:", len(logs))
+}
@@ -1,17 +1,39 @@
 package api
 
 import (
+	"blockchain/internal/anchor"
 	"blockchain/internal/blockchain"
+	"blockchain/internal/mempool"
+	"blockchain/internal/snapshot"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 	"blockchain/internal/utils"
 )
 
+// maxRequestBodySize bounds request bodies. JSON escaping can make a body larger
+// than the payload it carries, so this is a block's worth rather than a payload's.
+const maxRequestBodySize = blockchain.MaxBlockSize
+
 // Handlers struct holds the blockchain instance and logger to be used by the API handlers.
 type Handlers struct {
 	Blockchain *blockchain.Blockchain
 	Logger     *utils.Logger
+	Snapshots  *snapshot.Store  // Periodic snapshots served to bootstrapping peers; optional.
+	Mempool    *mempool.Mempool // Pending transactions, included in the next block added; optional.
+	Producer   string           // Address paid the block reward and fees of the blocks added; optional.
+	Anchorer   *anchor.Anchorer // Publishes the chain's tip to anchors and verifies the records; optional.
+
+	// Notarizations pay no fee, so /notary takes them only with NotaryToken as a bearer token, and at most
+	// NotaryRate a minute unless it is zero. Without a token or a mempool the node does not notarize.
+	NotaryToken string
+	NotaryRate  int
+
+	produce     sync.Mutex  // Serializes assembling and connecting the blocks added through the API.
+	notaryLimit rateLimiter // Counts the notarizations taken against NotaryRate.
 }
 
 // NewHandlers creates a new Handlers instance.
@@ -29,7 +51,9 @@ func NewHandlers(blockchain *blockchain.Blockchain, logger *utils.Logger) *Handl
 
 // AddBlockHandler handles the API request to add a new block to the blockchain.
 // This is a POST request handler.
-// It expects a JSON body with a "data" field.
+// It expects a JSON body with a "data" field and an optional "transactions" array.
+// The transactions pending in the mempool, if any, are included ahead of the given ones, highest fee first.
+// If a producer is configured, a coinbase transaction pays it the block reward and the fees.
 func (h *Handlers) AddBlockHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
@@ -37,27 +61,80 @@ func (h *Handlers) AddBlockHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Data string `json:"data"`
+		Data         string                    `json:"data"`
+		Transactions []*blockchain.Transaction `json:"transactions"`
 	}
 
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			h.Logger.Warn("Request body exceeds", maxBytesErr.Limit, "bytes")
+			return
+		}
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		h.Logger.Error("Failed to decode request body:", err)
 		return
 	}
 
-	h.Blockchain.AddBlockWithRust(req.Data)
+	for _, tx := range req.Transactions {
+		if tx.Notarization != nil {
+			http.Error(w, "Notarizations are submitted through /notary", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.addBlock(req.Data, req.Transactions); err != nil {
+		if errors.Is(err, blockchain.ErrPayloadTooLarge) || errors.Is(err, blockchain.ErrBlockTooLarge) || errors.Is(err, blockchain.ErrTooManyTransactions) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			h.Logger.Warn("Rejected oversized block:", err)
+			return
+		}
+		if errors.Is(err, blockchain.ErrInvalidBlock) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.Logger.Warn("Rejected invalid block:", err)
+			return
+		}
+		http.Error(w, "Failed to add block", http.StatusInternalServerError)
+		h.Logger.Error("Failed to add block:", err)
+		return
+	}
 	h.Logger.Info("New block added with data:", req.Data)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Block added successfully"})
 }
 
+// addBlock adds a block carrying data and txs, preceded by as many of the transactions pending in the mempool as
+// fit and, if a producer is configured, a coinbase paying it. Blocks are added one at a time, so that concurrent requests do not
+// assemble blocks from the same pending transactions or coinbases for the same height.
+func (h *Handlers) addBlock(data string, txs []*blockchain.Transaction) error {
+	h.produce.Lock()
+	defer h.produce.Unlock()
+	return h.produceBlock(data, txs)
+}
+
+// produceBlock assembles and adds a block like addBlock. The caller must hold h.produce.
+func (h *Handlers) produceBlock(data string, txs []*blockchain.Transaction) error {
+	if h.Mempool != nil {
+		// Take as many pending transactions as fit alongside the given ones and the coinbase.
+		count, size := h.Blockchain.BlockSpace(h.Producer, data, txs)
+		txs = append(h.Mempool.Select(count, size), txs...)
+	}
+	if h.Producer != "" {
+		if coinbase := h.Blockchain.Coinbase(h.Producer, txs); coinbase != nil {
+			txs = append([]*blockchain.Transaction{coinbase}, txs...)
+		}
+	}
+	return h.Blockchain.AddBlockWithRust(data, txs...)
+}
+
 // GetBlockchainHandler handles the API request to get the entire blockchain.
 // This is a GET request handler.
 func (h *Handlers) GetBlockchainHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(h.Blockchain.Blocks); err != nil {
+	if err := json.NewEncoder(w).Encode(h.Blockchain.AllBlocks()); err != nil {
 		http.Error(w, "Failed to encode blockchain data", http.StatusInternalServerError)
 		h.Logger.Error("Failed to encode blockchain data:", err)
 		return
@@ -67,7 +144,7 @@ func (h *Handlers) GetBlockchainHandler(w http.ResponseWriter, r *http.Request)
 
 // GetBlockByIndexHandler handles the API request to get a specific block by its index.
 // This is a GET request handler.
-// It expects an "index" query parameter.
+// It expects an "index" query parameter. Blocks whose body has been pruned are answered with 410 Gone.
 func (h *Handlers) GetBlockByIndexHandler(w http.ResponseWriter, r *http.Request) {
 	indexStr := r.URL.Query().Get("index")
 	if indexStr == "" {
@@ -83,14 +160,20 @@ func (h *Handlers) GetBlockByIndexHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if index < 0 || index >= len(h.Blockchain.Blocks) {
+	block := h.Blockchain.BlockAt(index)
+	if block == nil {
 		http.Error(w, "Index out of range", http.StatusBadRequest)
 		h.Logger.Warn("Index out of range:", index)
 		return
 	}
+	if block.Pruned {
+		http.Error(w, fmt.Sprintf("Block %d has been pruned; this node only keeps its header", index), http.StatusGone)
+		h.Logger.Warn("Requested pruned block:", index)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(h.Blockchain.Blocks[index]); err != nil {
+	if err := json.NewEncoder(w).Encode(block); err != nil {
 		http.Error(w, "Failed to encode block data", http.StatusInternalServerError)
 		h.Logger.Error("Failed to encode block data:", err)
 		return
@@ -101,7 +184,7 @@ func (h *Handlers) GetBlockByIndexHandler(w http.ResponseWriter, r *http.Request
 // GetLastBlockHandler handles the API request to get the last block in the blockchain.
 // This is a GET request handler.
 func (h *Handlers) GetLastBlockHandler(w http.ResponseWriter, r *http.Request) {
-	lastBlock := h.Blockchain.Blocks[len(h.Blockchain.Blocks)-1]
+	lastBlock := h.Blockchain.LastBlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(lastBlock); err != nil {
@@ -112,26 +195,76 @@ func (h *Handlers) GetLastBlockHandler(w http.ResponseWriter, r *http.Request) {
 	h.Logger.Info("Last block data retrieved")
 }
 
+// validationResponse is the JSON body returned by ValidateBlockchainHandler.
+type validationResponse struct {
+	Message string `json:"message"`
+	*blockchain.ValidationReport
+}
+
 // ValidateBlockchainHandler handles the API request to validate the blockchain.
 // This is a GET request handler.
+// By default only blocks added since the last successful validation are checked.
+// It accepts "full=true" to validate the whole chain, or "from" and "to" query parameters to validate a height range.
 func (h *Handlers) ValidateBlockchainHandler(w http.ResponseWriter, r *http.Request) {
-	valid := h.Blockchain.IsChainValid()
+	query := r.URL.Query()
+
+	var report *blockchain.ValidationReport
+	switch {
+	case query.Get("from") != "" || query.Get("to") != "":
+		from, to := 0, h.Blockchain.Len()-1
+		var err error
+		if fromStr := query.Get("from"); fromStr != "" {
+			if from, err = strconv.Atoi(fromStr); err != nil {
+				http.Error(w, "Invalid from height", http.StatusBadRequest)
+				h.Logger.Error("Invalid from height format:", err)
+				return
+			}
+		}
+		if toStr := query.Get("to"); toStr != "" {
+			if to, err = strconv.Atoi(toStr); err != nil {
+				http.Error(w, "Invalid to height", http.StatusBadRequest)
+				h.Logger.Error("Invalid to height format:", err)
+				return
+			}
+		}
 
-	response := map[string]string{
-		"message": "Blockchain is valid",
+		report, err = h.Blockchain.ValidateRange(from, to)
+		if err != nil {
+			http.Error(w, "Height range out of range", http.StatusBadRequest)
+			h.Logger.Warn("Invalid validation range:", err)
+			return
+		}
+	case query.Get("full") == "true":
+		report = h.Blockchain.Validate()
+	default:
+		report = h.Blockchain.ValidateIncremental()
 	}
-	if !valid {
-		response["message"] = "Blockchain is invalid"
-		w.WriteHeader(http.StatusConflict)
-		h.Logger.Warn("Blockchain validation failed")
+
+	response := validationResponse{Message: "Blockchain is valid", ValidationReport: report}
+	status := http.StatusOK
+	if !report.Valid {
+		response.Message = "Blockchain is invalid"
+		status = http.StatusConflict
+		h.Logger.Warn("Blockchain validation failed with", len(report.Failures), "failing checks")
 	} else {
 		h.Logger.Info("Blockchain validation successful")
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Failed to encode validation result", http.StatusInternalServerError)
 		h.Logger.Error("Failed to encode validation result:", err)
 		return
 	}
 }
+
+// ReverificationProgressHandler handles the API request to get the progress of the background full re-verification.
+// This is a GET request handler.
+func (h *Handlers) ReverificationProgressHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.Blockchain.ReverificationProgress()); err != nil {
+		http.Error(w, "Failed to encode re-verification progress", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode re-verification progress:", err)
+		return
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"blockchain/internal/blockchain"
+)
+
+// MultisigAddressHandler handles the API request to create a multisig address.
+// This is a POST request handler.
+// It expects a policy {"threshold": M, "signers": [addresses]} as the JSON body and answers with the address
+// and the canonical policy to attach to transactions spending from it.
+func (h *Handlers) MultisigAddressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request blockchain.MultisigPolicy
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.Logger.Error("Failed to decode multisig policy:", err)
+		return
+	}
+	policy, err := blockchain.NewMultisigPolicy(request.Threshold, request.Signers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Address string                     `json:"address"`
+		Policy  *blockchain.MultisigPolicy `json:"policy"`
+	}{policy.Address(), policy})
+	h.Logger.Info("Multisig address created:", policy.Address())
+}
+
+// CombineSignaturesHandler handles the API request to combine the signatures of copies of a partially-signed
+// transaction.
+// This is a POST request handler.
+// It expects a JSON array of the copies and answers with the combined transaction and the signing status of
+// each of its multisig policies. Combining does not submit the transaction. Copies that differ in anything but
+// their signatures, or carry an invalid signature, are rejected with 400 Bad Request.
+func (h *Handlers) CombineSignaturesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var txs []*blockchain.Transaction
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	if err := json.NewDecoder(r.Body).Decode(&txs); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.Logger.Error("Failed to decode transactions:", err)
+		return
+	}
+	combined, err := blockchain.CombineSignatures(txs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status, err := combined.MultisigStatus()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Transaction *blockchain.Transaction     `json:"transaction"`
+		Multisig    []blockchain.MultisigStatus `json:"multisig"`
+	}{combined, status})
+	h.Logger.Info("Signatures combined for transaction", combined.ID())
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"blockchain/internal/blockchain"
+	"blockchain/internal/mempool"
+)
+
+// maxUploadSize bounds the documents uploaded to be notarized or verified. They are hashed as they stream in
+// and never stored.
+const maxUploadSize = 256 << 20
+
+// readNotarization reads the document digest and metadata of a notary request. A multipart/form-data body may
+// carry the document as the "file" field, which is hashed, or its hash as the "hash" field, along with "name",
+// "content_type" and "note" fields; the file's name and type are used when those are missing. Any other body
+// is decoded as a JSON blockchain.Notarization. The metadata is not validated.
+func readNotarization(w http.ResponseWriter, r *http.Request) (*blockchain.Notarization, error) {
+	var notarization blockchain.Notarization
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+		if err := json.NewDecoder(r.Body).Decode(&notarization); err != nil {
+			return nil, err
+		}
+		return &notarization, nil
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	fileHash := ""
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			hash := sha256.New()
+			if notarization.Size, err = io.Copy(hash, part); err != nil {
+				return nil, err
+			}
+			fileHash = hex.EncodeToString(hash.Sum(nil))
+			if notarization.Name == "" {
+				notarization.Name = part.FileName()
+			}
+			if notarization.ContentType == "" {
+				notarization.ContentType = part.Header.Get("Content-Type")
+			}
+			continue
+		}
+		// Read one byte more than a field may hold, so that Validate rejects overlong fields.
+		value, err := io.ReadAll(io.LimitReader(part, blockchain.MaxNotaryFieldLength+1))
+		if err != nil {
+			return nil, err
+		}
+		switch part.FormName() {
+		case "hash":
+			notarization.Hash = string(value)
+		case "name":
+			notarization.Name = string(value)
+		case "content_type":
+			notarization.ContentType = string(value)
+		case "note":
+			notarization.Note = string(value)
+		}
+	}
+	if fileHash != "" {
+		if notarization.Hash != "" && notarization.Hash != fileHash {
+			return nil, fmt.Errorf("the file hashes to %s, not %s", fileHash, notarization.Hash)
+		}
+		notarization.Hash = fileHash
+	}
+	return &notarization, nil
+}
+
+// writeNotaryError answers a notary request whose body could not be read.
+func (h *Handlers) writeNotaryError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		h.Logger.Warn("Request body exceeds", maxBytesErr.Limit, "bytes")
+		return
+	}
+	http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+	h.Logger.Warn("Failed to read notary request:", err)
+}
+
+// rateLimiter counts events in fixed one-minute windows.
+type rateLimiter struct {
+	mu     sync.Mutex
+	window time.Time // Start of the current window.
+	count  int       // Events allowed in the current window.
+}
+
+// allow reports whether another event at now fits in limit a minute, and counts it if so.
+func (l *rateLimiter) allow(limit int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.window) >= time.Minute {
+		l.window, l.count = now, 0
+	}
+	if l.count >= limit {
+		return false
+	}
+	l.count++
+	return true
+}
+
+// notaryAuthorized reports whether r carries the notary token as a bearer token.
+func (h *Handlers) notaryAuthorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.NotaryToken)) == 1
+}
+
+// NotarizeHandler handles the API request to notarize a document: its SHA-256 hash and metadata are recorded in
+// a transaction, queued in the mempool and included in a block added straight away, after those of any pending
+// transactions paying fees, so that the block's timestamp proves the document existed by then. Concurrent
+// requests share the blocks. The document itself is not stored.
+// This is a POST request handler.
+// The request must carry the node's notary token as a bearer token; requests without it are answered with 401
+// Unauthorized, and those beyond the node's rate with 429 Too Many Requests. The body is a multipart/form-data
+// upload of the document or its hash, or a JSON blockchain.Notarization; see readNotarization. It answers with
+// the blockchain.NotaryRecord of the new block, including its Merkle proof. Documents that have already been
+// notarized, including by a concurrent request, are answered with 409 Conflict and the existing record.
+func (h *Handlers) NotarizeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Mempool == nil || h.NotaryToken == "" {
+		http.Error(w, "This node does not notarize documents", http.StatusNotFound)
+		return
+	}
+	if !h.notaryAuthorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Invalid or missing notary token", http.StatusUnauthorized)
+		h.Logger.Warn("Rejected unauthorized notarization")
+		return
+	}
+	if h.NotaryRate > 0 && !h.notaryLimit.allow(h.NotaryRate, time.Now()) {
+		http.Error(w, "Too many notarizations", http.StatusTooManyRequests)
+		h.Logger.Warn("Rejected notarization over the rate of", h.NotaryRate, "a minute")
+		return
+	}
+
+	notarization, err := readNotarization(w, r)
+	if err != nil {
+		h.writeNotaryError(w, err)
+		return
+	}
+	if err := notarization.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if record, err := h.Blockchain.Notarized(notarization.Hash); err == nil {
+		writeNotaryRecord(w, http.StatusConflict, record)
+		return
+	} else if errors.Is(err, blockchain.ErrPruned) {
+		http.Error(w, "Already notarized: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	// Queue the notarization, or wait for the block of another one of the same document queued already.
+	tx := &blockchain.Transaction{Notarization: notarization}
+	queued := tx
+	if err := h.Mempool.Add(tx); err != nil && !errors.Is(err, mempool.ErrDuplicate) {
+		if queued = pendingNotarization(h.Mempool.Pending(), notarization.Hash); queued == nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.Logger.Warn("Rejected notarization:", err)
+			return
+		}
+	}
+	blockErr := h.includePending(queued)
+
+	// Whether or not the block was added, the chain now tells which notarization of the document, if any, won.
+	record, err := h.Blockchain.Notarized(notarization.Hash)
+	switch {
+	case err == nil && record.TxID == tx.ID():
+		h.Logger.Info("Document notarized:", notarization.Hash)
+		writeNotaryRecord(w, http.StatusOK, record)
+	case err == nil:
+		writeNotaryRecord(w, http.StatusConflict, record)
+	case errors.Is(err, blockchain.ErrPruned):
+		http.Error(w, "Already notarized: "+err.Error(), http.StatusConflict)
+	case errors.Is(blockErr, blockchain.ErrInvalidBlock):
+		http.Error(w, blockErr.Error(), http.StatusBadRequest)
+		h.Logger.Warn("Rejected notarization:", blockErr)
+	case blockErr != nil:
+		http.Error(w, "Failed to add block", http.StatusInternalServerError)
+		h.Logger.Error("Failed to add notarization block:", blockErr)
+	default:
+		http.Error(w, "Failed to prove notarization", http.StatusInternalServerError)
+		h.Logger.Error("Failed to prove notarization:", err)
+	}
+}
+
+// includePending adds blocks with the pending transactions until tx is no longer pending, e.g. because a block
+// included it, possibly that of a concurrent request. Transactions paying higher fees go first, so this may take
+// several blocks; it stops if a block leaves the pending transactions as many as they were.
+func (h *Handlers) includePending(tx *blockchain.Transaction) error {
+	h.produce.Lock()
+	defer h.produce.Unlock()
+
+	for h.Mempool.IsPending(tx.ID()) {
+		pending := h.Mempool.Len()
+		if err := h.produceBlock("", nil); err != nil {
+			return err
+		}
+		if h.Mempool.Len() >= pending {
+			return nil
+		}
+	}
+	return nil
+}
+
+// pendingNotarization returns the pending transaction notarizing the document with the given hash, or nil.
+func pendingNotarization(pending []*blockchain.Transaction, hash string) *blockchain.Transaction {
+	for _, tx := range pending {
+		if tx.Notarization != nil && tx.Notarization.Hash == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
+// writeNotaryRecord answers with record and the given status.
+func writeNotaryRecord(w http.ResponseWriter, status int, record *blockchain.NotaryRecord) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(record)
+}
+
+// VerifyNotarizationHandler handles the API request to verify that a document has been notarized: it answers
+// with the blockchain.NotaryRecord holding the block, timestamp and Merkle proof that recorded its hash.
+// This handles GET requests with a "hash" query parameter, and POST requests whose body carries the document
+// or its hash like a notarization; see readNotarization.
+// Documents that have not been notarized are answered with 404 Not Found, and those recorded in pruned blocks
+// with 410 Gone.
+func (h *Handlers) VerifyNotarizationHandler(w http.ResponseWriter, r *http.Request) {
+	var hash string
+	switch r.Method {
+	case http.MethodGet:
+		hash = r.URL.Query().Get("hash")
+	case http.MethodPost:
+		notarization, err := readNotarization(w, r)
+		if err != nil {
+			h.writeNotaryError(w, err)
+			return
+		}
+		hash = notarization.Hash
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if hash == "" {
+		http.Error(w, "Missing document or hash", http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.Blockchain.Notarized(strings.ToLower(hash))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, blockchain.ErrNotNotarized):
+			status = http.StatusNotFound
+		case errors.Is(err, blockchain.ErrPruned):
+			status = http.StatusGone
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(record); err != nil {
+		http.Error(w, "Failed to encode notarization", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode notarization:", err)
+		return
+	}
+	h.Logger.Info("Notarization verified:", hash)
+}
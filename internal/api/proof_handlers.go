@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"blockchain/internal/blockchain"
+)
+
+// GetAccountProofHandler handles the API request to prove the state of an account against a block's state root.
+// This is a GET request handler.
+// The address is the last path segment; an optional "height" query parameter selects the block, by default the
+// tip. The proof can be verified with merkle.VerifyAccountProof. States whose undo data has been pruned are
+// answered with 410 Gone.
+func (h *Handlers) GetAccountProofHandler(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if address == "" {
+		http.Error(w, "Address is required", http.StatusBadRequest)
+		return
+	}
+
+	height := -1
+	if heightStr := r.URL.Query().Get("height"); heightStr != "" {
+		var err error
+		if height, err = strconv.Atoi(heightStr); err != nil || height < 0 {
+			http.Error(w, "Invalid height", http.StatusBadRequest)
+			h.Logger.Error("Invalid height format:", heightStr)
+			return
+		}
+	}
+
+	proof, err := h.Blockchain.ProveAccount(address, height)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, blockchain.ErrPruned) {
+			status = http.StatusGone
+		}
+		http.Error(w, err.Error(), status)
+		h.Logger.Warn("Failed to prove account:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(proof); err != nil {
+		http.Error(w, "Failed to encode proof", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode account proof:", err)
+		return
+	}
+	h.Logger.Info("Account proof retrieved:", address)
+}
+
+// GetTransactionProofHandler handles the API request to prove that a transaction is included in a block.
+// This is a GET request handler.
+// The transaction ID is the last path segment. The response carries the block header, the transaction's index
+// and its sibling path, and can be verified offline with merkle.VerifyTxProof.
+func (h *Handlers) GetTransactionProofHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Transaction ID is required", http.StatusBadRequest)
+		return
+	}
+
+	proof, err := h.Blockchain.ProveTransaction(id)
+	if err != nil {
+		if errors.Is(err, blockchain.ErrTxNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			h.Logger.Warn("Transaction not found:", id)
+			return
+		}
+		http.Error(w, "Failed to prove transaction", http.StatusInternalServerError)
+		h.Logger.Error("Failed to prove transaction:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(proof); err != nil {
+		http.Error(w, "Failed to encode proof", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode transaction proof:", err)
+		return
+	}
+	h.Logger.Info("Transaction proof retrieved:", id)
+}
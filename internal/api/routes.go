@@ -2,23 +2,17 @@ package api
 
 import (
 	"net/http"
-	"blockchain/internal/blockchain"
-	"blockchain/internal/utils"
 )
 
 // RegisterRoutes sets up the API routes and their corresponding handlers.
 // Parameters:
-// - blockchain: The blockchain instance to be used by the handlers.
-// - logger: The logger instance for logging API activities.
+// - handlers: The handlers, created with NewHandlers and any optional fields set.
 // Returns:
 // - An http.ServeMux that maps the routes to their handlers.
-func RegisterRoutes(blockchain *blockchain.Blockchain, logger *utils.Logger) *http.ServeMux {
+func RegisterRoutes(handlers *Handlers) *http.ServeMux {
 	// Create a new ServeMux to register the routes.
 	mux := http.NewServeMux()
 
-	// Initialize the handlers with the provided blockchain and logger.
-	handlers := NewHandlers(blockchain, logger)
-
 	// Register the route for adding a new block.
 	mux.HandleFunc("/addblock", handlers.AddBlockHandler)
 
@@ -34,6 +28,66 @@ func RegisterRoutes(blockchain *blockchain.Blockchain, logger *utils.Logger) *ht
 	// Register the route for validating the blockchain.
 	mux.HandleFunc("/validate", handlers.ValidateBlockchainHandler)
 
+	// Register the route for the background re-verification progress.
+	mux.HandleFunc("/validate/progress", handlers.ReverificationProgressHandler)
+
+	// Register the routes for exporting and importing chain files.
+	mux.HandleFunc("/export", handlers.ExportHandler)
+	mux.HandleFunc("/import", handlers.ImportHandler)
+
+	// Register the route for downloading the latest state snapshot.
+	mux.HandleFunc("/snapshot", handlers.SnapshotHandler)
+
+	// Register the route for querying an account's balance, nonce and storage.
+	mux.HandleFunc("/accounts/{address}", handlers.GetAccountHandler)
+
+	// Register the routes for submitting transactions and listing the pending and held ones.
+	mux.HandleFunc("/transactions", handlers.SubmitTransactionHandler)
+	mux.HandleFunc("/transactions/pending", handlers.GetPendingTransactionsHandler)
+	mux.HandleFunc("/transactions/held", handlers.GetHeldTransactionsHandler)
+
+	// Register the routes for creating multisig addresses and combining the signatures of their transactions.
+	mux.HandleFunc("/multisig", handlers.MultisigAddressHandler)
+	mux.HandleFunc("/transactions/combine", handlers.CombineSignaturesHandler)
+
+	// Register the routes for inspecting and calling contracts.
+	mux.HandleFunc("/contracts/{address}", handlers.GetContractHandler)
+	mux.HandleFunc("/contracts/{address}/call", handlers.CallContractHandler)
+
+	// Register the routes for the receipts of transactions and the logs emitted by contracts.
+	mux.HandleFunc("/receipts/{id}", handlers.GetReceiptHandler)
+	mux.HandleFunc("/logs", handlers.GetLogsHandler)
+
+	// Register the routes for querying tokens, their supply and their holders.
+	mux.HandleFunc("/tokens", handlers.GetTokensHandler)
+	mux.HandleFunc("/tokens/{symbol}", handlers.GetTokenHandler)
+	mux.HandleFunc("/tokens/{symbol}/holders", handlers.GetTokenHoldersHandler)
+
+	// Register the routes for querying non-fungible assets, their owners and their ownership history.
+	mux.HandleFunc("/assets", handlers.GetAssetsHandler)
+	mux.HandleFunc("/assets/{id}", handlers.GetAssetHandler)
+	mux.HandleFunc("/assets/{id}/history", handlers.GetAssetHistoryHandler)
+
+	// Register the routes for notarizing documents and verifying their notarization.
+	mux.HandleFunc("/notary", handlers.NotarizeHandler)
+	mux.HandleFunc("/notary/verify", handlers.VerifyNotarizationHandler)
+
+	// Register the routes for publishing the chain's tip to anchors and verifying the records.
+	mux.HandleFunc("/anchors", handlers.AnchorsHandler)
+	mux.HandleFunc("/anchors/verify", handlers.VerifyAnchorsHandler)
+
+	// Register the route for querying the block reward schedule.
+	mux.HandleFunc("/issuance", handlers.GetIssuanceHandler)
+
+	// Register the route for querying the unspent outputs of an address.
+	mux.HandleFunc("/utxos/{address}", handlers.GetUnspentOutputsHandler)
+
+	// Register the route for proving an account's state to light clients.
+	mux.HandleFunc("/proofs/account/{address}", handlers.GetAccountProofHandler)
+
+	// Register the route for proving that a transaction is included in a block.
+	mux.HandleFunc("/proofs/tx/{id}", handlers.GetTransactionProofHandler)
+
 	// Return the configured ServeMux.
 	return mux
 }
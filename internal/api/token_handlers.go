@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"blockchain/internal/blockchain"
+)
+
+// GetTokensHandler handles the API request to list the tokens created on the chain.
+// This is a GET request handler.
+func (h *Handlers) GetTokensHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.Blockchain.Tokens()); err != nil {
+		http.Error(w, "Failed to encode tokens", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode tokens:", err)
+		return
+	}
+	h.Logger.Info("Tokens retrieved")
+}
+
+// GetTokenHandler handles the API request to get the metadata and supply of a token.
+// This is a GET request handler.
+// The symbol is the last path segment; unknown tokens are answered with 404 Not Found.
+func (h *Handlers) GetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	symbol := r.PathValue("symbol")
+	token, err := h.Blockchain.Token(symbol)
+	if errors.Is(err, blockchain.ErrUnknownToken) {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		http.Error(w, "Failed to encode token", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode token:", err)
+		return
+	}
+	h.Logger.Info("Token retrieved:", symbol)
+}
+
+// GetTokenHoldersHandler handles the API request to list the holders of a token, largest balance first.
+// This is a GET request handler.
+// Unknown tokens are answered with 404 Not Found.
+func (h *Handlers) GetTokenHoldersHandler(w http.ResponseWriter, r *http.Request) {
+	symbol := r.PathValue("symbol")
+	holders, err := h.Blockchain.TokenHolders(symbol)
+	if errors.Is(err, blockchain.ErrUnknownToken) {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(holders); err != nil {
+		http.Error(w, "Failed to encode token holders", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode token holders:", err)
+		return
+	}
+	h.Logger.Info("Token holders retrieved:", symbol)
+}
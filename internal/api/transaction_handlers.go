@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"blockchain/internal/blockchain"
+	"blockchain/internal/mempool"
+)
+
+// SubmitTransactionHandler handles the API request to submit a transaction to the mempool.
+// This is a POST request handler.
+// It expects a transaction as the JSON body and answers 202 Accepted with the transaction's ID and status once
+// it is pending, or held until its lock height or lock time, 409 Conflict for a double spend and 400 Bad Request for any other invalid transaction, including
+// one paying less than the mempool's minimum fee, locked beyond its lock horizon or too cheap to be held.
+// Notarizations are rejected: they are only taken, with the notary token, through /notary.
+func (h *Handlers) SubmitTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Mempool == nil {
+		http.Error(w, "This node does not accept transactions", http.StatusNotFound)
+		return
+	}
+
+	var tx blockchain.Transaction
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.Logger.Error("Failed to decode transaction:", err)
+		return
+	}
+
+	if tx.Notarization != nil {
+		http.Error(w, "Notarizations are submitted through /notary", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Mempool.Add(&tx); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, blockchain.ErrDoubleSpend) || errors.Is(err, mempool.ErrDuplicate) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		h.Logger.Warn("Rejected transaction:", err)
+		return
+	}
+	status := "pending"
+	if h.Mempool.IsHeld(tx.ID()) {
+		status = "held"
+	}
+	h.Logger.Info("Transaction accepted:", tx.ID(), status)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"id": tx.ID(), "status": status})
+}
+
+// GetPendingTransactionsHandler handles the API request to list the transactions waiting in the mempool.
+// This is a GET request handler.
+func (h *Handlers) GetPendingTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	pending := []*blockchain.Transaction{}
+	if h.Mempool != nil {
+		pending = append(pending, h.Mempool.Pending()...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pending); err != nil {
+		http.Error(w, "Failed to encode transactions", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode pending transactions:", err)
+		return
+	}
+	h.Logger.Info("Pending transactions retrieved")
+}
+
+// GetHeldTransactionsHandler handles the API request to list the transactions held in the mempool until their
+// lock height or lock time.
+// This is a GET request handler.
+func (h *Handlers) GetHeldTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	held := []*blockchain.Transaction{}
+	if h.Mempool != nil {
+		held = append(held, h.Mempool.Held()...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(held); err != nil {
+		http.Error(w, "Failed to encode transactions", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode held transactions:", err)
+		return
+	}
+	h.Logger.Info("Held transactions retrieved")
+}
+
+// GetUnspentOutputsHandler handles the API request to list the unspent outputs paying an address.
+// This is a GET request handler.
+// The address is the last path segment. On a chain in account mode the list is always empty.
+func (h *Handlers) GetUnspentOutputsHandler(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if address == "" {
+		http.Error(w, "Address is required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.Blockchain.UnspentOutputs(address)); err != nil {
+		http.Error(w, "Failed to encode unspent outputs", http.StatusInternalServerError)
+		h.Logger.Error("Failed to encode unspent outputs:", err)
+		return
+	}
+	h.Logger.Info("Unspent outputs retrieved:", address)
+}
+
+// GetIssuanceHandler handles the API request for the chain's issuance schedule.
+// This is a GET request handler.
+// It answers with the schedule, the reward of the next block and the most the blocks so far may have minted.
+func (h *Handlers) GetIssuanceHandler(w http.ResponseWriter, r *http.Request) {
+	schedule := h.Blockchain.Issuance()
+	height := h.Blockchain.Len() - 1
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		blockchain.IssuanceSchedule
+		Height          int    `json:"height"`
+		NextReward      uint64 `json:"next_reward"`
+		ScheduledSupply uint64 `json:"scheduled_supply"`
+	}{schedule, height, schedule.Reward(height + 1), schedule.Supply(height)})
+	h.Logger.Info("Issuance schedule retrieved")
+}
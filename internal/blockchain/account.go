@@ -0,0 +1,189 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"blockchain/pkg/merkle"
+)
+
+// State key prefixes of the account model: "account/<address>" holds the Account as JSON and
+// "storage/<address>/<key>" holds one value of the account's storage.
+const (
+	accountPrefix = merkle.AccountKeyPrefix
+	storagePrefix = "storage/"
+)
+
+// Account is the balance and nonce of an address. Accounts that have never been used have zero values.
+type Account struct {
+	Balance uint64 `json:"balance"` // Funds held by the account.
+	Nonce   uint64 `json:"nonce"`   // Number of transactions sent by the account.
+}
+
+// Account returns the account stored under address, or a zero Account if there is none.
+func (s *State) Account(address string) Account {
+	var account Account
+	if value, ok := s.Get(accountPrefix + address); ok {
+		json.Unmarshal([]byte(value), &account)
+	}
+	return account
+}
+
+// SetAccount stores the account under address. Zero accounts are deleted so that they do not affect the state root.
+func (s *State) SetAccount(address string, account Account) {
+	if account == (Account{}) {
+		s.Delete(accountPrefix + address)
+		return
+	}
+	value, _ := json.Marshal(account)
+	s.Set(accountPrefix+address, string(value))
+}
+
+// AccountStorage returns the value stored under key in the storage of the account at address.
+func (s *State) AccountStorage(address, key string) (string, bool) {
+	return s.Get(storagePrefix + address + "/" + key)
+}
+
+// SetAccountStorage stores value under key in the storage of the account at address; an empty value deletes the key.
+func (s *State) SetAccountStorage(address, key, value string) {
+	if value == "" {
+		s.Delete(storagePrefix + address + "/" + key)
+		return
+	}
+	s.Set(storagePrefix+address+"/"+key, value)
+}
+
+// AccountModule applies the transactions of a block to the accounts.
+// Each transaction must carry the sender's current nonce, and its amount and fee may not exceed the sender's
+// balance. Transactions that carry a token operation are applied by applyTokenTransaction, those that carry
+// an asset operation by applyAssetTransaction, those that deploy or call a contract by
+// applyContractTransaction, and notarizations by applyNotarization.
+func AccountModule(state *State, block *Block, height int) error {
+	for i, tx := range block.Transactions {
+		var err error
+		switch {
+		case tx.Notarization != nil:
+			err = applyNotarization(state, tx, height)
+		case tx.Token != nil:
+			err = applyTokenTransaction(state, tx, height)
+		case tx.Asset != nil:
+			err = applyAssetTransaction(state, tx, height)
+		case tx.isContract() || contractCode(state, tx.To) != nil:
+			err = applyContractTransaction(state, block, tx, height)
+		default:
+			err = applyTransaction(state, tx, height)
+		}
+		if err != nil {
+			return fmt.Errorf("transaction %d (%s): %w", i, tx.ID(), err)
+		}
+	}
+	return nil
+}
+
+// applyTransaction moves the amount of tx from its sender to its recipient.
+func applyTransaction(state *State, tx *Transaction, height int) error {
+	if len(tx.Inputs) > 0 || len(tx.Outputs) > 0 {
+		return fmt.Errorf("%w: inputs and outputs are only allowed in UTXO mode", ErrInvalidTransaction)
+	}
+	if tx.To == "" {
+		return fmt.Errorf("%w: missing recipient", ErrInvalidTransaction)
+	}
+	// Addresses are used in state keys, where "/" separates the address from a storage key.
+	if strings.Contains(tx.From, "/") || strings.Contains(tx.To, "/") {
+		return fmt.Errorf("%w: address contains \"/\"", ErrInvalidTransaction)
+	}
+	if err := checkRecipient(tx.To, height); err != nil {
+		return err
+	}
+	if err := authorize(tx, []string{tx.From}); err != nil {
+		return err
+	}
+
+	if tx.From == "" {
+		if height != 0 && !tx.IsCoinbase() {
+			return fmt.Errorf("%w: only the genesis block and coinbase transactions may allocate funds", ErrInvalidTransaction)
+		}
+		if tx.Fee != 0 {
+			return fmt.Errorf("%w: a transaction without a sender pays no fee", ErrInvalidTransaction)
+		}
+	} else {
+		sender := state.Account(tx.From)
+		if tx.Nonce != sender.Nonce {
+			return fmt.Errorf("%w: got %d, expected %d", ErrBadNonce, tx.Nonce, sender.Nonce)
+		}
+		if tx.Amount > math.MaxUint64-tx.Fee {
+			return fmt.Errorf("%w: amount and fee overflow", ErrInvalidTransaction)
+		}
+		if sender.Balance < tx.Amount+tx.Fee {
+			return fmt.Errorf("%w: %s has %d, needs %d", ErrInsufficientBalance, tx.From, sender.Balance, tx.Amount+tx.Fee)
+		}
+		sender.Balance -= tx.Amount + tx.Fee
+		sender.Nonce++
+		state.SetAccount(tx.From, sender)
+	}
+
+	// Read the recipient after updating the sender, in case they are the same account.
+	recipient := state.Account(tx.To)
+	if recipient.Balance > math.MaxUint64-tx.Amount {
+		return fmt.Errorf("%w: balance of %s would overflow", ErrInvalidTransaction, tx.To)
+	}
+	recipient.Balance += tx.Amount
+	state.SetAccount(tx.To, recipient)
+	return nil
+}
+
+// chargeFee checks that tx carries the sender's nonce and that the sender's balance covers its fee plus spend,
+// then takes the fee and advances the nonce. Transactions other than plain transfers use it before acting.
+func chargeFee(state *State, tx *Transaction, spend uint64) error {
+	sender := state.Account(tx.From)
+	if tx.Nonce != sender.Nonce {
+		return fmt.Errorf("%w: got %d, expected %d", ErrBadNonce, tx.Nonce, sender.Nonce)
+	}
+	if spend > math.MaxUint64-tx.Fee {
+		return fmt.Errorf("%w: amount and fee overflow", ErrInvalidTransaction)
+	}
+	if sender.Balance < spend+tx.Fee {
+		return fmt.Errorf("%w: %s has %d, needs %d", ErrInsufficientBalance, tx.From, sender.Balance, spend+tx.Fee)
+	}
+	sender.Balance -= tx.Fee
+	sender.Nonce++
+	state.SetAccount(tx.From, sender)
+	return nil
+}
+
+// AccountInfo is an account together with its storage and token balances, as returned by Blockchain.Account.
+type AccountInfo struct {
+	Address string            `json:"address"`
+	Account                   // Balance and nonce.
+	Storage map[string]string `json:"storage,omitempty"`
+	Tokens  map[string]uint64 `json:"tokens,omitempty"` // Balances by token symbol.
+}
+
+// Account returns the current state of the account at address.
+func (bc *Blockchain) Account(address string) AccountInfo {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	info := AccountInfo{Address: address, Account: bc.State.Account(address)}
+	prefix := storagePrefix + address + "/"
+	for key, value := range bc.State.entries {
+		if strings.HasPrefix(key, prefix) {
+			if info.Storage == nil {
+				info.Storage = make(map[string]string)
+			}
+			info.Storage[strings.TrimPrefix(key, prefix)] = value
+		}
+		if rest, ok := strings.CutPrefix(key, tokenBalancePrefix); ok {
+			if symbol, holder, _ := strings.Cut(rest, "/"); holder == address {
+				if info.Tokens == nil {
+					info.Tokens = make(map[string]uint64)
+				}
+				info.Tokens[symbol], _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,260 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// assetPrefix is the state key prefix of assets: "asset/<id>" holds the Asset as JSON.
+const assetPrefix = "asset/"
+
+// Asset operations.
+const (
+	AssetMint     = "mint"
+	AssetTransfer = "transfer"
+	AssetBurn     = "burn"
+	AssetApprove  = "approve"
+)
+
+// MaxAssetURILength is the longest metadata URI an asset may have, in bytes.
+const MaxAssetURILength = 256
+
+// assetID is the form of an asset ID: 1 to 64 letters, digits and ".", "_", ":" or "-", e.g. a serial number.
+var assetID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// ErrUnknownAsset is returned (possibly wrapped) for an asset that has not been minted.
+var ErrUnknownAsset = errors.New("unknown asset")
+
+// AssetOperation is the asset part of a transaction; see applyAssetTransaction.
+type AssetOperation struct {
+	Op           string `json:"op"`                      // AssetMint, AssetTransfer, AssetBurn or AssetApprove.
+	ID           string `json:"id"`                      // ID of the asset, unique on the chain.
+	MetadataHash string `json:"metadata_hash,omitempty"` // Mint only: hex-encoded SHA-256 hash of the asset's metadata.
+	URI          string `json:"uri,omitempty"`           // Mint only: where the metadata can be found.
+}
+
+// Asset is a unique, non-fungible asset registered on the chain, e.g. a physical item.
+type Asset struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`              // Current owner; empty once burned.
+	Approved     string `json:"approved,omitempty"` // Address the owner has approved to transfer the asset.
+	MetadataHash string `json:"metadata_hash"`
+	URI          string `json:"uri,omitempty"`
+	Minter       string `json:"minter"`
+	Height       int    `json:"height"`           // Height of the block that minted the asset.
+	Burned       bool   `json:"burned,omitempty"` // Burned assets stay registered so that their ID is not reused.
+}
+
+// AssetEvent is a change of an asset, as recorded by a transaction in a block.
+type AssetEvent struct {
+	Op        string `json:"op"`
+	TxID      string `json:"txid"`
+	Height    int    `json:"height"`
+	BlockHash string `json:"block_hash"`
+	Timestamp int64  `json:"timestamp"`          // Timestamp of the block, in Unix milliseconds.
+	Sender    string `json:"sender"`             // Address that sent the transaction: the owner or an approved address.
+	Owner     string `json:"owner"`              // Owner after the event; empty once burned.
+	Approved  string `json:"approved,omitempty"` // Approve only: the address approved; empty to revoke.
+}
+
+// asset returns the asset with the given ID stored in the state.
+func asset(state *State, id string) (*Asset, bool) {
+	value, ok := state.Get(assetPrefix + id)
+	if !ok {
+		return nil, false
+	}
+	var a Asset
+	if err := json.Unmarshal([]byte(value), &a); err != nil {
+		return nil, false
+	}
+	return &a, true
+}
+
+// setAsset stores an asset in the state.
+func setAsset(state *State, a *Asset) {
+	value, _ := json.Marshal(a)
+	state.Set(assetPrefix+a.ID, string(value))
+}
+
+// validate checks the fields of the operation, without looking at the state.
+func (op *AssetOperation) validate(tx *Transaction) error {
+	if !assetID.MatchString(op.ID) {
+		return fmt.Errorf("%w: asset ID %q, want 1 to 64 letters, digits, \".\", \"_\", \":\" or \"-\"", ErrInvalidTransaction, op.ID)
+	}
+	if op.Op != AssetMint && (op.MetadataHash != "" || op.URI != "") {
+		return fmt.Errorf("%w: metadata is only allowed when minting an asset", ErrInvalidTransaction)
+	}
+	switch op.Op {
+	case AssetMint:
+		if hash, err := hex.DecodeString(op.MetadataHash); err != nil || len(hash) != 32 {
+			return fmt.Errorf("%w: metadata hash must be a hex-encoded SHA-256 hash", ErrInvalidTransaction)
+		}
+		if len(op.URI) > MaxAssetURILength {
+			return fmt.Errorf("%w: metadata URI of %d bytes exceeds %d", ErrInvalidTransaction, len(op.URI), MaxAssetURILength)
+		}
+	case AssetTransfer:
+		if tx.To == "" {
+			return fmt.Errorf("%w: missing recipient", ErrInvalidTransaction)
+		}
+	case AssetBurn:
+		if tx.To != "" {
+			return fmt.Errorf("%w: burning an asset has no recipient", ErrInvalidTransaction)
+		}
+	case AssetApprove:
+	default:
+		return fmt.Errorf("%w: unknown asset operation %q", ErrInvalidTransaction, op.Op)
+	}
+	return nil
+}
+
+// applyAssetTransaction applies a transaction carrying an asset operation. Like a transfer, it needs the
+// sender's signature and nonce and pays its fee, but moves no native funds: its Amount must be zero.
+//
+// Minting registers a new asset ID with its metadata hash and URI, owned by the recipient or, without one, the
+// sender. The owner may approve the recipient to transfer the asset on their behalf, or revoke the approval by
+// approving no one. The owner or the approved address may transfer the asset to the recipient, which clears
+// the approval. Only the owner may burn the asset.
+func applyAssetTransaction(state *State, tx *Transaction, height int) error {
+	op := tx.Asset
+	if len(tx.Inputs) > 0 || len(tx.Outputs) > 0 {
+		return fmt.Errorf("%w: inputs and outputs are only allowed in UTXO mode", ErrInvalidTransaction)
+	}
+	if tx.From == "" {
+		return fmt.Errorf("%w: an asset transaction needs a sender", ErrInvalidTransaction)
+	}
+	if tx.Amount != 0 || tx.isContract() || tx.Token != nil {
+		return fmt.Errorf("%w: an asset transaction has no amount, contract fields or token operation", ErrInvalidTransaction)
+	}
+	if strings.Contains(tx.From, "/") || strings.Contains(tx.To, "/") {
+		return fmt.Errorf("%w: address contains \"/\"", ErrInvalidTransaction)
+	}
+	if err := op.validate(tx); err != nil {
+		return err
+	}
+	if tx.To != "" {
+		if err := checkRecipient(tx.To, height); err != nil {
+			return err
+		}
+	}
+	if err := authorize(tx, []string{tx.From}); err != nil {
+		return err
+	}
+
+	if err := chargeFee(state, tx, 0); err != nil {
+		return err
+	}
+
+	a, exists := asset(state, op.ID)
+	if op.Op == AssetMint {
+		if exists {
+			return fmt.Errorf("%w: asset %s already exists", ErrInvalidTransaction, op.ID)
+		}
+		owner := tx.To
+		if owner == "" {
+			owner = tx.From
+		}
+		setAsset(state, &Asset{ID: op.ID, Owner: owner, MetadataHash: op.MetadataHash, URI: op.URI, Minter: tx.From, Height: height})
+		return nil
+	}
+	if !exists || a.Burned {
+		return fmt.Errorf("%w: %w: %s", ErrInvalidTransaction, ErrUnknownAsset, op.ID)
+	}
+
+	switch op.Op {
+	case AssetTransfer:
+		if tx.From != a.Owner && tx.From != a.Approved {
+			return fmt.Errorf("%w: %s is neither the owner nor approved to transfer asset %s", ErrUnauthorized, tx.From, a.ID)
+		}
+		a.Owner, a.Approved = tx.To, ""
+	case AssetBurn:
+		if tx.From != a.Owner {
+			return fmt.Errorf("%w: only the owner %s may burn asset %s", ErrUnauthorized, a.Owner, a.ID)
+		}
+		a.Owner, a.Approved, a.Burned = "", "", true
+	default: // AssetApprove
+		if tx.From != a.Owner {
+			return fmt.Errorf("%w: only the owner %s may approve transfers of asset %s", ErrUnauthorized, a.Owner, a.ID)
+		}
+		a.Approved = tx.To
+	}
+	setAsset(state, a)
+	return nil
+}
+
+// Asset returns the asset with the given ID, including burned assets.
+// Returns ErrUnknownAsset if it has not been minted.
+func (bc *Blockchain) Asset(id string) (*Asset, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	a, ok := asset(bc.State, id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAsset, id)
+	}
+	return a, nil
+}
+
+// Assets returns the assets owned by owner, by ID.
+func (bc *Blockchain) Assets(owner string) []*Asset {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	assets := []*Asset{}
+	for key := range bc.State.entries {
+		if id, ok := strings.CutPrefix(key, assetPrefix); ok {
+			if a, ok := asset(bc.State, id); ok && a.Owner == owner {
+				assets = append(assets, a)
+			}
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].ID < assets[j].ID })
+	return assets
+}
+
+// AssetHistory returns the events of the asset with the given ID, oldest first, found by searching the blocks.
+// Events in blocks whose bodies have been pruned are missing.
+// Returns ErrUnknownAsset if it has not been minted.
+func (bc *Blockchain) AssetHistory(id string) ([]AssetEvent, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if _, ok := asset(bc.State, id); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAsset, id)
+	}
+	events := []AssetEvent{}
+	owner := ""
+	for height := bc.prunedHeight; height < len(bc.Blocks); height++ {
+		block := bc.Blocks[height]
+		for _, tx := range block.Transactions {
+			if tx.Asset == nil || tx.Asset.ID != id {
+				continue
+			}
+			event := AssetEvent{Op: tx.Asset.Op, TxID: tx.ID(), Height: height, BlockHash: block.Hash, Timestamp: block.Timestamp, Sender: tx.From}
+			switch tx.Asset.Op {
+			case AssetMint:
+				owner = tx.To
+				if owner == "" {
+					owner = tx.From
+				}
+			case AssetTransfer:
+				owner = tx.To
+			case AssetBurn:
+				owner = ""
+			case AssetApprove:
+				event.Approved = tx.To
+				if owner == "" {
+					// The mint was pruned; only the owner may approve.
+					owner = tx.From
+				}
+			}
+			event.Owner = owner
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
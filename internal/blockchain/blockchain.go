@@ -5,29 +5,60 @@ import (
 	"encoding/hex"
 	"fmt" // Import the fmt package for formatting
 	"log"
+	"sync"
 	"time"
 
 	"blockchain/internal/crypto"
+	"blockchain/internal/storage"
 )
 
 // Block represents a single block in the blockchain.
+// The header fields (everything but Data and Transactions) are hashed; the body is committed through
+// DataHash and TxRoot, so a block whose body has been dropped can still be verified.
 type Block struct {
-	Timestamp    int64  // The timestamp when the block was created.
-	Data         string // The actual data stored in the block (e.g., transactions).
-	PreviousHash string // The hash of the previous block in the chain.
-	Hash         string // The hash of the current block.
+	Timestamp    int64          // The time the block was created, in Unix milliseconds.
+	Data         string         // The actual data stored in the block.
+	Transactions []*Transaction `json:",omitempty"` // The transactions applied to the account state.
+	DataHash     string         // The SHA-256 hash of Data.
+	TxRoot       string         // The Merkle root of the transaction IDs.
+	PreviousHash string         // The hash of the previous block in the chain.
+	StateRoot    string         // The root hash of the state after applying this block.
+	LogsBloom    string         `json:",omitempty"` // The hex-encoded Bloom of the block's logs; empty if there are none.
+	Hash         string         // The hash of the current block.
+	Pruned       bool           `json:",omitempty"` // Whether the body has been dropped, leaving only the header.
 }
 
+// GenesisTimestamp is the fixed creation time of the genesis block, so every node derives the same genesis hash.
+const GenesisTimestamp int64 = 1725148800000
+
 // Blockchain represents the entire chain of blocks.
 type Blockchain struct {
-	Blocks []*Block // A slice holding all blocks in the blockchain.
+	Blocks       []*Block      // A slice holding all blocks in the blockchain.
+	Rules        []Rule        // Consensus rules checked during validation.
+	Checkpoints  []Checkpoint  // Trusted height/hash pairs.
+	Clock        Clock         // Source of the current time; the local clock if nil.
+	State        *State        // The state after applying every block.
+	StateModules []StateModule // Modules applied to the state for every connected block.
+	PruneDepth   int           // Number of most recent blocks whose bodies are kept; zero keeps every body.
+
+	mu            sync.RWMutex                 // Guards Blocks and State against concurrent background verification.
+	statusMu      sync.Mutex                   // Guards verified and reverifyState.
+	verified      int                          // Number of leading blocks known to be valid; see ValidateIncremental.
+	reverifyState ReverificationProgress       // Progress of the background full re-verification.
+	listeners     []func(height int, b *Block) // Called after each block is connected; see OnBlock.
+	store         storage.Backend              // Where connected blocks are persisted; nil keeps the chain in memory only.
+	undo          [][]StateChange              // The state changes undoing each block, by height; nil where unknown.
+	prunedHeight  int                          // Height of the first block that still has its body; see Prune.
 }
 
 // NewBlock creates a new block and computes its hash.
+// The block's StateRoot is left empty; blocks added to a chain get theirs from AddBlock.
 func NewBlock(data string, previousHash string) *Block {
 	block := &Block{
-		Timestamp:    time.Now().Unix(),
+		Timestamp:    time.Now().UnixMilli(),
 		Data:         data,
+		DataHash:     calculateDataHash(data),
+		TxRoot:       calculateTxRoot(nil),
 		PreviousHash: previousHash,
 		Hash:         "",
 	}
@@ -35,68 +66,232 @@ func NewBlock(data string, previousHash string) *Block {
 	return block
 }
 
-// calculateHash generates the hash for a block based on its contents.
+// Header returns a copy of the block without its body.
+func (b *Block) Header() *Block {
+	header := *b
+	header.Data = ""
+	header.Transactions = nil
+	header.Pruned = true
+	return &header
+}
+
+// calculateHash generates the hash for a block based on its header.
 func (b *Block) calculateHash() string {
-	// Use fmt.Sprintf to convert the int64 Timestamp to a string of digits.
-	record := fmt.Sprintf("%d", b.Timestamp) + b.Data + b.PreviousHash
-	hash := sha256.Sum256([]byte(record)) // Use SHA-256 to generate the hash.
+	return sha256Hex(b.record())
+}
+
+// calculateDataHash generates the hash committing to a block's data.
+func calculateDataHash(data string) string {
+	return sha256Hex(data)
+}
+
+// sha256Hex returns the hex-encoded SHA-256 hash of s.
+func sha256Hex(s string) string {
+	hash := sha256.Sum256([]byte(s)) // Use SHA-256 to generate the hash.
 	return hex.EncodeToString(hash[:])
 }
 
-// AddBlock creates a new block and adds it to the blockchain.
-func (bc *Blockchain) AddBlock(data string) {
-	previousBlock := bc.Blocks[len(bc.Blocks)-1] // Get the last block in the chain.
-	newBlock := NewBlock(data, previousBlock.Hash)
-	bc.Blocks = append(bc.Blocks, newBlock) // Append the new block to the chain.
-	log.Printf("New block added: %s", newBlock.Hash)
+// record returns the string that is hashed to produce the block hash.
+func (b *Block) record() string {
+	// Use fmt.Sprintf to convert the int64 Timestamp to a string of digits.
+	// The logs bloom is only committed when set, so blocks without logs hash as they did before blooms existed.
+	return fmt.Sprintf("%d", b.Timestamp) + b.DataHash + b.TxRoot + b.PreviousHash + b.StateRoot + b.LogsBloom
+}
+
+// AddBlock creates a new block carrying data and any transactions and adds it to the blockchain.
+// Returns an error if the block would exceed the size limits or a transaction cannot be applied.
+func (bc *Blockchain) AddBlock(data string, txs ...*Transaction) error {
+	block, err := bc.addBlock(data, txs, sha256Hex)
+	if err != nil {
+		return err
+	}
+	log.Printf("New block added: %s", block.Hash)
+	return nil
 }
 
 // AddBlockWithRust creates a new block using Rust's hashing functions and adds it to the blockchain.
-func (bc *Blockchain) AddBlockWithRust(data string) {
-    previousBlock := bc.Blocks[len(bc.Blocks)-1] // Get the last block in the chain.
+// Returns an error if the block would exceed the size limits or a transaction cannot be applied.
+func (bc *Blockchain) AddBlockWithRust(data string, txs ...*Transaction) error {
+	// Hash the same record as calculateHash so that Rust-built blocks pass validation.
+	block, err := bc.addBlock(data, txs, func(record string) string {
+		return hex.EncodeToString(crypto.HashSHA256([]byte(record)))
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("New block added using Rust: %s", block.Hash)
+	return nil
+}
 
-    // Concatenate previous hash and data to match Rust's expected input format.
-    combinedData := previousBlock.Hash + data
-    log.Printf("Data to be hashed by Rust: %s", combinedData)
+// addBlock builds a block on the tip, hashing its header with hash, and connects it.
+func (bc *Blockchain) addBlock(data string, txs []*Transaction, hash func(record string) string) (*Block, error) {
+	// Refuse too many transactions before applying any of them.
+	if err := checkTransactionCount(len(txs)); err != nil {
+		return nil, err
+	}
+	bc.mu.Lock()
+	previousBlock := bc.Blocks[len(bc.Blocks)-1] // Get the last block in the chain.
+	block := &Block{
+		Timestamp:    bc.nextTimestamp(),
+		Data:         data,
+		Transactions: txs,
+		DataHash:     calculateDataHash(data),
+		TxRoot:       calculateTxRoot(txs),
+		PreviousHash: previousBlock.Hash,
+	}
+	err := bc.connect(block, func(stateRoot string) error {
+		block.StateRoot = stateRoot
+		block.LogsBloom = logsBloom(bc.State, block)
+		block.Hash = hash(block.record())
+		return block.checkSize()
+	})
+	height := len(bc.Blocks) - 1
+	bc.mu.Unlock()
 
-    // Calculate the hash using Rust's SHA-256 implementation.
-    hashBytes := crypto.HashSHA256([]byte(combinedData))
-    hash := hex.EncodeToString(hashBytes)
+	if err != nil {
+		return nil, err
+	}
+	bc.notify(height, block)
+	return block, nil
+}
 
-    newBlock := &Block{
-        Timestamp:    time.Now().Unix(),
-        Data:         data,
-        PreviousHash: previousBlock.Hash,
-        Hash:         hash,
-    }
+// AppendBlock validates a block built elsewhere, e.g. read from a chain file or received from a peer,
+// and appends it to the chain.
+// Returns an error wrapping ErrInvalidBlock if the block fails any validation check.
+func (bc *Blockchain) AppendBlock(block *Block) error {
+	bc.mu.Lock()
+	height := len(bc.Blocks)
+	err := bc.appendBlock(block)
+	bc.mu.Unlock()
 
-    bc.Blocks = append(bc.Blocks, newBlock) // Append the new block to the chain.
-    log.Printf("New block added using Rust: %s", newBlock.Hash)
+	if err != nil {
+		return err
+	}
+	log.Printf("Block appended: %s", block.Hash)
+	bc.notify(height, block)
+	return nil
+}
+
+// appendBlock implements AppendBlock; the caller must hold bc.mu.
+func (bc *Blockchain) appendBlock(block *Block) error {
+	height := len(bc.Blocks)
+	if block.Pruned {
+		return fmt.Errorf("%w at height %d: block body is missing", ErrInvalidBlock, height)
+	}
+
+	// The stateless checks look the block up by height, so append it while they run.
+	bc.Blocks = append(bc.Blocks, block)
+	failures := bc.checkBlock(height)
+	bc.Blocks = bc.Blocks[:height]
+	if len(failures) > 0 {
+		return fmt.Errorf("%w at height %d: %s: %s", ErrInvalidBlock, height, failures[0].Reason, failures[0].Detail)
+	}
+
+	return bc.connect(block, func(stateRoot string) error {
+		if block.StateRoot != stateRoot {
+			return fmt.Errorf("%w at height %d: %s: state root %s does not match %s", ErrInvalidBlock, height, ReasonConsensus, block.StateRoot, stateRoot)
+		}
+		if bloom := logsBloom(bc.State, block); block.LogsBloom != bloom {
+			return fmt.Errorf("%w at height %d: %s: logs bloom does not match the logs of the block", ErrInvalidBlock, height, ReasonConsensus)
+		}
+		return nil
+	})
+}
+
+// connect applies a block to the state, writes it to the store and appends it to the chain. check runs once the
+// state has been applied, with the resulting state root; if it or the write fails the state change is undone and
+// the block is not appended.
+// The caller must hold bc.mu.
+func (bc *Blockchain) connect(block *Block, check func(stateRoot string) error) error {
+	undo, err := bc.State.applyBlock(bc.StateModules, block, len(bc.Blocks))
+	if err != nil {
+		return fmt.Errorf("%w at height %d: %s: %w", ErrInvalidBlock, len(bc.Blocks), ReasonConsensus, err)
+	}
+	if err := check(bc.State.Root()); err != nil {
+		bc.State.revert(undo)
+		return err
+	}
+	if err := bc.persistBlock(block, len(bc.Blocks), undo); err != nil {
+		bc.State.revert(undo)
+		return fmt.Errorf("storing block %d: %w", len(bc.Blocks), err)
+	}
+	bc.Blocks = append(bc.Blocks, block) // Append the new block to the chain.
+	bc.undo = append(bc.undo, undo)
+	bc.prune()
+	return nil
+}
+
+// OnBlock registers a function that is called, without any chain lock held, after each block is connected.
+func (bc *Blockchain) OnBlock(listener func(height int, block *Block)) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.listeners = append(bc.listeners, listener)
+}
+
+// notify calls the OnBlock listeners for a newly connected block.
+func (bc *Blockchain) notify(height int, block *Block) {
+	bc.mu.RLock()
+	listeners := bc.listeners
+	bc.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(height, block)
+	}
 }
 
 // IsChainValid verifies the integrity of the blockchain.
+// Use Validate to find out which blocks failed and why.
 func (bc *Blockchain) IsChainValid() bool {
-	for i := 1; i < len(bc.Blocks); i++ {
-		currentBlock := bc.Blocks[i]
-		previousBlock := bc.Blocks[i-1]
-
-		// Recalculate the hash and check if it matches.
-		if currentBlock.Hash != currentBlock.calculateHash() {
-			log.Printf("Invalid block hash at block %d", i)
-			return false
-		}
+	return bc.Validate().Valid
+}
 
-		// Check if the current block's PreviousHash matches the previous block's hash.
-		if currentBlock.PreviousHash != previousBlock.Hash {
-			log.Printf("Invalid previous hash at block %d", i)
-			return false
-		}
+// Len returns the number of blocks in the chain.
+func (bc *Blockchain) Len() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return len(bc.Blocks)
+}
+
+// BlockAt returns the block at the given height, or nil if the height is out of range.
+func (bc *Blockchain) BlockAt(height int) *Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	if height < 0 || height >= len(bc.Blocks) {
+		return nil
 	}
-	return true
+	return bc.Blocks[height]
+}
+
+// LastBlock returns the last block in the chain.
+func (bc *Blockchain) LastBlock() *Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.Blocks[len(bc.Blocks)-1]
 }
 
-// GetBlockchain initializes a new blockchain with a genesis block.
+// AllBlocks returns a copy of the chain's block slice that is safe to use without locking.
+func (bc *Blockchain) AllBlocks() []*Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return append([]*Block(nil), bc.Blocks...)
+}
+
+// NewGenesisBlock returns the genesis block shared by every node, built from DefaultGenesis.
+func NewGenesisBlock() *Block {
+	return DefaultGenesis.Block()
+}
+
+// GetBlockchain initializes a new blockchain with the default genesis block.
 func GetBlockchain(hashMethod string) *Blockchain {
-	genesisBlock := NewBlock("Genesis Block", "0xGENESIS")
-	return &Blockchain{Blocks: []*Block{genesisBlock}}
+	return NewBlockchainFromGenesis(DefaultGenesis)
+}
+
+// newBlockchain returns a blockchain with the default rules, checkpoints and state modules but no blocks.
+func newBlockchain() *Blockchain {
+	return &Blockchain{
+		Rules:        append([]Rule(nil), DefaultRules...),
+		Checkpoints:  append([]Checkpoint(nil), DefaultCheckpoints...),
+		State:        NewState(),
+		StateModules: append([]StateModule(nil), DefaultStateModules...),
+	}
 }
@@ -1,7 +1,19 @@
 package blockchain
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	"blockchain/internal/blockchain/chaintest"
+	"blockchain/internal/crypto"
+	"blockchain/internal/storage"
+	"blockchain/internal/vm"
+	"blockchain/pkg/merkle"
 )
 
 // TestAddBlock tests the AddBlock function to ensure that blocks are added correctly to the blockchain.
@@ -61,3 +73,1330 @@ func TestIsChainValid(t *testing.T) {
 		t.Error("Expected blockchain to be invalid, but it is still considered valid.")
 	}
 }
+
+// TestValidateReport tests that Validate reports every failing block with its reason.
+func TestValidateReport(t *testing.T) {
+	bc := GetBlockchain("SHA-256") // Initialize a new blockchain.
+
+	bc.AddBlock("Test Block 1")
+	bc.AddBlockWithRust("Test Block 2")
+	bc.AddBlock("Test Block 3")
+
+	// Blocks built with either hasher must validate.
+	if report := bc.Validate(); !report.Valid {
+		t.Fatalf("Expected blockchain to be valid, but got failures %+v", report.Failures)
+	}
+
+	// Tamper with block 1's data and break block 3's link.
+	bc.Blocks[1].Data = "Tampered Data"
+	bc.Blocks[3].PreviousHash = "bogus"
+	bc.Blocks[3].Hash = bc.Blocks[3].calculateHash()
+
+	report := bc.Validate()
+	if report.Valid {
+		t.Fatal("Expected blockchain to be invalid, but it is still considered valid.")
+	}
+
+	expected := []BlockFailure{
+		{Height: 1, Reason: ReasonHashMismatch},
+		{Height: 3, Reason: ReasonBrokenLink},
+	}
+	if len(report.Failures) != len(expected) {
+		t.Fatalf("Expected %d failures, but got %+v", len(expected), report.Failures)
+	}
+	for i, want := range expected {
+		if got := report.Failures[i]; got.Height != want.Height || got.Reason != want.Reason {
+			t.Errorf("Failure %d: expected height %d reason %s, but got height %d reason %s", i, want.Height, want.Reason, got.Height, got.Reason)
+		}
+	}
+
+	// A range that excludes the broken blocks is valid.
+	if report, err := bc.ValidateRange(2, 2); err != nil || !report.Valid {
+		t.Errorf("Expected range 2..2 to be valid, got report %+v, err %v", report, err)
+	}
+
+	// A range outside the chain is an error.
+	if _, err := bc.ValidateRange(2, 10); err == nil {
+		t.Error("Expected an error for an out-of-range height range")
+	}
+}
+
+// TestValidateRules tests that rule errors are classified as consensus or signature failures.
+func TestValidateRules(t *testing.T) {
+	bc := GetBlockchain("SHA-256")
+	bc.AddBlock("Test Block 1")
+	bc.AddBlock("Test Block 2")
+
+	bc.Rules = []Rule{
+		func(bc *Blockchain, height int) error {
+			if height == 1 {
+				return fmt.Errorf("block 1 is unsigned: %w", ErrBadSignature)
+			}
+			return nil
+		},
+		func(bc *Blockchain, height int) error {
+			if height == 2 {
+				return errors.New("block 2 breaks the rules")
+			}
+			return nil
+		},
+	}
+
+	report := bc.Validate()
+	if len(report.Failures) != 2 {
+		t.Fatalf("Expected 2 failures, but got %+v", report.Failures)
+	}
+	if report.Failures[0].Reason != ReasonBadSignature {
+		t.Errorf("Expected reason %s, but got %s", ReasonBadSignature, report.Failures[0].Reason)
+	}
+	if report.Failures[1].Reason != ReasonConsensus {
+		t.Errorf("Expected reason %s, but got %s", ReasonConsensus, report.Failures[1].Reason)
+	}
+}
+
+// TestGenesisMatchesCheckpoint tests that the genesis block matches the compiled-in checkpoint.
+func TestGenesisMatchesCheckpoint(t *testing.T) {
+	genesis := NewGenesisBlock()
+	if genesis.Hash != DefaultCheckpoints[0].Hash {
+		t.Errorf("Expected genesis hash %s, but got %s", DefaultCheckpoints[0].Hash, genesis.Hash)
+	}
+}
+
+// TestParseCheckpoints tests parsing of configured checkpoints.
+func TestParseCheckpoints(t *testing.T) {
+	checkpoints, err := ParseCheckpoints("10:abc, 20:def")
+	if err != nil {
+		t.Fatalf("Failed to parse checkpoints: %v", err)
+	}
+	if len(checkpoints) != 2 || checkpoints[1] != (Checkpoint{Height: 20, Hash: "def"}) {
+		t.Errorf("Unexpected checkpoints %+v", checkpoints)
+	}
+
+	for _, invalid := range []string{"10", "x:abc", "-1:abc", "10:"} {
+		if _, err := ParseCheckpoints(invalid); err == nil {
+			t.Errorf("Expected an error for checkpoint list %q", invalid)
+		}
+	}
+}
+
+// TestValidateIncremental tests that incremental validation only checks blocks added since the last run.
+func TestValidateIncremental(t *testing.T) {
+	bc := GetBlockchain("SHA-256")
+	bc.AddBlock("Test Block 1")
+	bc.AddBlock("Test Block 2")
+
+	report := bc.ValidateIncremental()
+	if !report.Valid || report.From != 0 || report.To != 2 {
+		t.Fatalf("Expected a valid report for 0..2, but got %+v", report)
+	}
+
+	// Tampering with an already verified block is not noticed by incremental validation.
+	bc.Blocks[1].Data = "Tampered Data"
+	bc.AddBlock("Test Block 3")
+
+	report = bc.ValidateIncremental()
+	if !report.Valid || report.From != 3 || report.To != 3 {
+		t.Fatalf("Expected a valid report for 3..3, but got %+v", report)
+	}
+
+	// A full re-verification finds it and resets the watermark.
+	report, err := bc.Reverify()
+	if err != nil {
+		t.Fatalf("Reverify failed: %v", err)
+	}
+	if report.Valid || report.Failures[0].Height != 1 {
+		t.Fatalf("Expected a failure at height 1, but got %+v", report)
+	}
+
+	progress := bc.ReverificationProgress()
+	if progress.Running || progress.Checked != 4 || progress.Total != 4 || progress.Runs != 1 {
+		t.Errorf("Unexpected re-verification progress %+v", progress)
+	}
+
+	// Incremental validation now starts again from the failing block.
+	report = bc.ValidateIncremental()
+	if report.Valid || report.From != 1 {
+		t.Errorf("Expected an invalid report starting at height 1, but got %+v", report)
+	}
+}
+
+// TestValidateIncrementalCheckpoint tests that blocks below a matched checkpoint are trusted.
+func TestValidateIncrementalCheckpoint(t *testing.T) {
+	bc := GetBlockchain("SHA-256")
+	bc.AddBlock("Test Block 1")
+	bc.AddBlock("Test Block 2")
+	bc.AddBlock("Test Block 3")
+
+	// Tamper with block 1, then checkpoint block 2.
+	bc.Blocks[1].Data = "Tampered Data"
+	bc.Checkpoints = append(bc.Checkpoints, Checkpoint{Height: 2, Hash: bc.Blocks[2].Hash})
+
+	report := bc.ValidateIncremental()
+	if !report.Valid || report.From != 2 {
+		t.Errorf("Expected a valid report starting at the checkpoint, but got %+v", report)
+	}
+
+	// A block that does not match its checkpoint is a consensus violation.
+	bc.Checkpoints = append(bc.Checkpoints, Checkpoint{Height: 3, Hash: "bogus"})
+	report = bc.Validate()
+	last := report.Failures[len(report.Failures)-1]
+	if last.Height != 3 || last.Reason != ReasonConsensus {
+		t.Errorf("Expected a consensus failure at height 3, but got %+v", report.Failures)
+	}
+}
+
+// fixedClock is a Clock that always returns the same time.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+// TestTimestampRules tests the median-time-past and future drift rules.
+func TestTimestampRules(t *testing.T) {
+	bc := GetBlockchain("SHA-256")
+	for i := 0; i < 5; i++ {
+		bc.AddBlock(fmt.Sprintf("Test Block %d", i))
+	}
+
+	// Blocks added within the same millisecond still satisfy the median-time-past rule.
+	if !bc.IsChainValid() {
+		t.Fatalf("Expected blockchain to be valid, but got failures %+v", bc.Validate().Failures)
+	}
+
+	// A block timestamped before the median time past is rejected.
+	tip := bc.Blocks[len(bc.Blocks)-1]
+	tip.Timestamp = bc.Blocks[1].Timestamp
+	tip.Hash = tip.calculateHash()
+	report := bc.Validate()
+	if report.Valid || report.Failures[0].Reason != ReasonConsensus {
+		t.Errorf("Expected a consensus failure for an old timestamp, but got %+v", report)
+	}
+
+	// A block too far ahead of network time is rejected.
+	tip.Timestamp = time.Now().Add(MaxFutureDrift + time.Minute).UnixMilli()
+	tip.Hash = tip.calculateHash()
+	if bc.IsChainValid() {
+		t.Error("Expected a block from the future to be rejected")
+	}
+
+	// ... unless the network-adjusted clock says it is not in the future.
+	bc.Clock = fixedClock(time.Now().Add(2 * time.Minute))
+	if !bc.IsChainValid() {
+		t.Errorf("Expected the block to be accepted by the adjusted clock, but got %+v", bc.Validate().Failures)
+	}
+}
+
+// TestAdjustedClock tests that the adjusted clock uses the median offset of enough hosts and ignores extreme
+// offsets.
+func TestAdjustedClock(t *testing.T) {
+	clock := NewAdjustedClock()
+	if clock.Offset() != 0 {
+		t.Errorf("Expected no offset without samples, but got %s", clock.Offset())
+	}
+
+	now := time.Now()
+	offsets := []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 40 * time.Second, 10 * time.Hour}
+	for i, offset := range offsets {
+		clock.AddSample(fmt.Sprintf("10.0.0.%d:3000", i), now.Add(offset))
+	}
+	if offset := clock.Offset(); offset < 29*time.Second || offset > 30*time.Second {
+		t.Errorf("Expected the median offset of about 30s, but got %s", offset)
+	}
+
+	// Too few hosts leave the clock alone.
+	clock.RemoveSample("10.0.0.0:3000")
+	if offset := clock.Offset(); offset != 0 {
+		t.Errorf("Expected no offset from %d hosts, but got %s", MinClockSamples-1, offset)
+	}
+
+	// Once the outlier is the median, the offset is ignored.
+	clock.AddSample("10.0.0.5:3000", now.Add(10*time.Hour))
+	clock.AddSample("10.0.0.6:3000", now.Add(10*time.Hour))
+	if offset := clock.Offset(); offset != 0 {
+		t.Errorf("Expected an out-of-bounds median to be ignored, but got %s", offset)
+	}
+}
+
+// TestAdjustedClockSingleHost tests that a single peer, however many connections it opens, does not move the
+// adjusted clock.
+func TestAdjustedClockSingleHost(t *testing.T) {
+	clock := NewAdjustedClock()
+	ahead := time.Now().Add(time.Hour)
+
+	clock.AddSample("10.0.0.1:3000", ahead)
+	if offset := clock.Offset(); offset != 0 {
+		t.Errorf("Expected a single peer not to move the clock, but got %s", offset)
+	}
+	for port := 3001; port < 3001+MinClockSamples; port++ {
+		clock.AddSample(fmt.Sprintf("10.0.0.1:%d", port), ahead)
+	}
+	if offset := clock.Offset(); offset != 0 {
+		t.Errorf("Expected repeated connections from one host not to move the clock, but got %s", offset)
+	}
+
+	// Honest hosts outnumber it, and it keeps its sample while any of its connections remain.
+	for i := 2; i < 2+MinClockSamples; i++ {
+		clock.AddSample(fmt.Sprintf("10.0.0.%d:3000", i), time.Now())
+	}
+	clock.RemoveSample("10.0.0.1:3000")
+	if offset := clock.Offset(); offset > time.Second || offset < -time.Second {
+		t.Errorf("Expected the honest hosts' offset, but got %s", offset)
+	}
+	if n := len(clock.samples); n != 1+MinClockSamples {
+		t.Errorf("Expected a sample per host, but got %d", n)
+	}
+}
+
+// TestSizeLimits tests that oversized payloads and blocks with too many transactions are refused by AddBlock and
+// rejected by validation.
+func TestSizeLimits(t *testing.T) {
+	bc := GetBlockchain("SHA-256")
+
+	oversized := strings.Repeat("x", MaxPayloadSize+1)
+	if err := bc.AddBlock(oversized); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Expected ErrPayloadTooLarge from AddBlock, but got %v", err)
+	}
+	if err := bc.AddBlockWithRust(oversized); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Expected ErrPayloadTooLarge from AddBlockWithRust, but got %v", err)
+	}
+	tooMany := make([]*Transaction, MaxBlockTransactions+1)
+	for i := range tooMany {
+		tooMany[i] = &Transaction{}
+	}
+	if err := bc.AddBlock("Test Block", tooMany...); !errors.Is(err, ErrTooManyTransactions) {
+		t.Errorf("Expected ErrTooManyTransactions from AddBlock, but got %v", err)
+	}
+	if err := (&Block{Transactions: tooMany}).checkSize(); !errors.Is(err, ErrTooManyTransactions) {
+		t.Errorf("Expected ErrTooManyTransactions from the size rule, but got %v", err)
+	}
+	if len(bc.Blocks) != 1 {
+		t.Fatalf("Expected oversized blocks not to be added, but the chain has %d blocks", len(bc.Blocks))
+	}
+
+	// A block that slipped in some other way fails validation.
+	if err := bc.AddBlock("Test Block 1"); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	bc.Blocks[1].Data = oversized
+	bc.Blocks[1].DataHash = calculateDataHash(oversized)
+	bc.Blocks[1].Hash = bc.Blocks[1].calculateHash()
+
+	report := bc.Validate()
+	if report.Valid || report.Failures[0].Reason != ReasonConsensus {
+		t.Errorf("Expected a consensus failure for an oversized block, but got %+v", report)
+	}
+}
+
+// TestBlockSpace tests that a block filled to the space BlockSpace reports, with a coinbase, fits the limits.
+func TestBlockSpace(t *testing.T) {
+	bc := GetBlockchain("SHA-256")
+	producer := chaintest.Addr("producer")
+
+	count, size := bc.BlockSpace(producer, "data", nil)
+	if count != MaxBlockTransactions-1 {
+		t.Errorf("Expected room for %d transactions besides the coinbase, but got %d", MaxBlockTransactions-1, count)
+	}
+
+	// Fill the space with transactions whose encodings are {"code":"..."}, taking 12 bytes besides the code.
+	var txs []*Transaction
+	for size > 12 {
+		code := strings.Repeat("0", min(size-12, 100<<10))
+		txs = append(txs, &Transaction{Code: code})
+		size -= len(code) + 12
+	}
+	hash := strings.Repeat("0", 64)
+	block := &Block{
+		Timestamp:    time.Now().UnixMilli(),
+		Data:         "data",
+		Transactions: append([]*Transaction{bc.Coinbase(producer, txs)}, txs...),
+		DataHash:     hash,
+		TxRoot:       hash,
+		PreviousHash: hash,
+		StateRoot:    hash,
+		LogsBloom:    strings.Repeat("0", 2*BloomSize),
+		Hash:         hash,
+	}
+	if err := block.checkSize(); err != nil {
+		t.Errorf("Expected a block filled to the reported space to fit, but got %v", err)
+	}
+	if block.Size() < MaxBlockSize-128 {
+		t.Errorf("Expected the reported space to fill the block, but it takes %d bytes of %d", block.Size(), MaxBlockSize)
+	}
+}
+
+// TestAppendBlockStateRoot tests that AppendBlock rejects blocks whose state root does not match the state.
+func TestAppendBlockStateRoot(t *testing.T) {
+	source := GetBlockchain("SHA-256")
+	source.AddBlock("Test Block 1")
+	source.AddBlock("Test Block 1") // Repeated data does not change the data index.
+
+	if source.Blocks[1].StateRoot == source.Blocks[0].StateRoot || source.Blocks[2].StateRoot != source.Blocks[1].StateRoot {
+		t.Error("Expected only new data to change the state root")
+	}
+
+	bc := GetBlockchain("SHA-256")
+	forged := *source.Blocks[1]
+	forged.StateRoot = source.Blocks[0].StateRoot
+	forged.Hash = forged.calculateHash()
+	if err := bc.AppendBlock(&forged); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("Expected ErrInvalidBlock for a wrong state root, but got %v", err)
+	}
+	if bc.Len() != 1 || bc.State.Root() != bc.Blocks[0].StateRoot {
+		t.Error("Expected a rejected block to leave the chain and state unchanged")
+	}
+
+	if err := bc.AppendBlock(source.Blocks[1].Header()); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("Expected ErrInvalidBlock for a block without body, but got %v", err)
+	}
+	if err := bc.AppendBlock(source.Blocks[1]); err != nil {
+		t.Errorf("Failed to append a valid block: %v", err)
+	}
+}
+
+// TestPrune tests that pruning keeps headers and recent bodies, and that a pruned chain still validates.
+func TestPrune(t *testing.T) {
+	bc := GetBlockchain("SHA-256")
+	for i := 1; i <= 5; i++ {
+		bc.AddBlock(fmt.Sprintf("Test Block %d", i))
+	}
+	if !bc.FullHistory() {
+		t.Error("Expected a new chain to have full history")
+	}
+
+	bc.PruneDepth = 2
+	if pruned := bc.Prune(); pruned != 4 {
+		t.Errorf("Expected 4 block bodies to be pruned, but got %d", pruned)
+	}
+	bc.AddBlock("Test Block 6")
+
+	if bc.PrunedHeight() != 5 || bc.FullHistory() {
+		t.Errorf("Expected pruned height 5 without full history, but got %d", bc.PrunedHeight())
+	}
+	if _, err := bc.BlockBodyAt(4); !errors.Is(err, ErrPruned) {
+		t.Errorf("Expected ErrPruned for block 4, but got %v", err)
+	}
+	if block, err := bc.BlockBodyAt(5); err != nil || block.Data != "Test Block 5" {
+		t.Errorf("Expected the body of block 5 to be kept, but got %v", err)
+	}
+	if bc.BlockAt(4).Hash == "" || bc.BlockAt(4).Data != "" {
+		t.Error("Expected a pruned block to keep its header but not its data")
+	}
+	if report := bc.Validate(); !report.Valid {
+		t.Errorf("Expected the pruned chain to be valid, but got %+v", report.Failures)
+	}
+}
+
+// failingBackend is a storage backend whose batch writes fail, as if the disk were full.
+type failingBackend struct {
+	*storage.MemoryBackend
+}
+
+func (failingBackend) Write(batch *storage.Batch) error {
+	return errors.New("disk full")
+}
+
+// TestOpenBlockchain tests that a chain connected to a store is loaded back unchanged.
+func TestOpenBlockchain(t *testing.T) {
+	store := storage.NewMemoryBackend()
+	bc, repairs, err := OpenBlockchain(store, DefaultGenesis, nil)
+	if err != nil || len(repairs) != 0 {
+		t.Fatalf("Failed to open an empty store: %v %v", err, repairs)
+	}
+	bc.AddBlock("Test Block 1")
+	bc.AddBlock("Test Block 2")
+
+	loaded, repairs, err := OpenBlockchain(store, DefaultGenesis, nil)
+	if err != nil {
+		t.Fatalf("Failed to load the chain: %v", err)
+	}
+	if len(repairs) != 0 {
+		t.Errorf("Expected no repairs for a consistent store, but got %v", repairs)
+	}
+	if loaded.Len() != 3 || loaded.LastBlock().Hash != bc.LastBlock().Hash || loaded.State.Root() != bc.State.Root() {
+		t.Error("Expected the loaded chain to match the stored one")
+	}
+
+	// Blocks connected to the loaded chain are stored as well.
+	if err := loaded.AddBlock("Test Block 3"); err != nil {
+		t.Fatalf("Failed to add a block to the loaded chain: %v", err)
+	}
+	if reloaded, _, _ := OpenBlockchain(store, DefaultGenesis, nil); reloaded.Len() != 4 {
+		t.Errorf("Expected 4 stored blocks, but got %d", reloaded.Len())
+	}
+}
+
+// TestAddBlockStoreFailure tests that a failed write leaves neither the block nor its state changes behind.
+func TestAddBlockStoreFailure(t *testing.T) {
+	bc := GetBlockchain("SHA-256")
+	root := bc.State.Root()
+	bc.store = failingBackend{storage.NewMemoryBackend()}
+
+	if err := bc.AddBlock("Test Block 1"); err == nil {
+		t.Fatal("Expected AddBlock to fail when the store cannot be written")
+	}
+	if bc.Len() != 1 || bc.State.Root() != root {
+		t.Error("Expected a failed write to leave the chain and state unchanged")
+	}
+}
+
+// TestCheckStoreRepairs tests that the consistency checker repairs a store left diverged by a crash.
+func TestCheckStoreRepairs(t *testing.T) {
+	store := storage.NewMemoryBackend()
+	bc, _, _ := OpenBlockchain(store, DefaultGenesis, nil)
+	for i := 1; i <= 4; i++ {
+		bc.AddBlock(fmt.Sprintf("Test Block %d", i))
+	}
+	want := bc.Blocks[2]
+
+	// Point the tip back at block 2 as if blocks 3 and 4 had been written without it, damage the hash
+	// index and add a stray block after a gap.
+	batch := storage.NewBatch()
+	putTip(batch, want, 2)
+	batch.Delete([]byte(hashKeyPrefix + bc.Blocks[1].Hash))
+	batch.Put([]byte(hashKeyPrefix+"bogus"), []byte("1"))
+	batch.Put(heightKey(blockKeyPrefix, 9), []byte("{}"))
+	store.Write(batch)
+
+	loaded, repairs, err := CheckStore(store, DefaultGenesis, nil)
+	if err != nil {
+		t.Fatalf("Failed to repair the store: %v", err)
+	}
+	if len(repairs) == 0 {
+		t.Error("Expected repairs to be reported")
+	}
+	if loaded.Len() != 3 || loaded.LastBlock().Hash != want.Hash {
+		t.Fatalf("Expected the chain to be cut back to block 2, but it has %d blocks", loaded.Len())
+	}
+	if loaded.State.Root() != want.StateRoot {
+		t.Error("Expected the state to be rolled back to block 2")
+	}
+
+	// A second check finds nothing left to repair.
+	if _, repairs, err := CheckStore(store, DefaultGenesis, nil); err != nil || len(repairs) != 0 {
+		t.Errorf("Expected a consistent store after the repair, but got %v %v", repairs, err)
+	}
+
+	// A damaged state is rebuilt by replaying the blocks.
+	store.Put([]byte(stateKeyPrefix+"bogus"), []byte("value"))
+	if loaded, _, err := CheckStore(store, DefaultGenesis, nil); err != nil || loaded.State.Root() != want.StateRoot {
+		t.Errorf("Expected the state to be rebuilt, but got %v", err)
+	}
+}
+
+// TestOpenBlockchainCheckpoints tests that a stored chain contradicting a configured checkpoint is cut back to
+// the block before it when it is opened.
+func TestOpenBlockchainCheckpoints(t *testing.T) {
+	store := storage.NewMemoryBackend()
+	bc, _, _ := OpenBlockchain(store, DefaultGenesis, nil)
+	for i := 1; i <= 3; i++ {
+		bc.AddBlock(fmt.Sprintf("Test Block %d", i))
+	}
+
+	checkpoints := []Checkpoint{{Height: 2, Hash: strings.Repeat("0", 64)}}
+	loaded, repairs, err := OpenBlockchain(store, DefaultGenesis, checkpoints)
+	if err != nil {
+		t.Fatalf("Failed to open the store: %v", err)
+	}
+	if loaded.Len() != 2 || len(repairs) == 0 {
+		t.Errorf("Expected the chain to be cut back to block 1, but it has %d blocks after %v", loaded.Len(), repairs)
+	}
+}
+
+// TestAccountTransfers tests that transactions move balances and advance nonces, and that invalid ones are rejected.
+func TestAccountTransfers(t *testing.T) {
+	bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+	if report := bc.Validate(); !report.Valid {
+		t.Fatalf("Expected a custom genesis to validate, but got %+v", report.Failures)
+	}
+
+	if err := bc.AddBlock("transfers",
+		chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 30, Nonce: 0}),
+		chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 20, Nonce: 1}),
+	); err != nil {
+		t.Fatalf("Failed to add transfers: %v", err)
+	}
+	if alice := bc.Account(chaintest.Addr("alice")); alice.Balance != 50 || alice.Nonce != 2 {
+		t.Errorf("Expected alice to have balance 50 and nonce 2, but got %+v", alice.Account)
+	}
+	if bob := bc.Account(chaintest.Addr("bob")); bob.Balance != 50 || bob.Nonce != 0 {
+		t.Errorf("Expected bob to have balance 50 and nonce 0, but got %+v", bob.Account)
+	}
+
+	root := bc.State.Root()
+	for _, test := range []struct {
+		tx   *Transaction
+		want error
+	}{
+		{chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 1, Nonce: 1}), ErrBadNonce},
+		{chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 51, Nonce: 2}), ErrInsufficientBalance},
+		{&Transaction{To: chaintest.Addr("bob"), Amount: 1}, ErrInvalidTransaction},
+	} {
+		if err := bc.AddBlock("invalid", test.tx); !errors.Is(err, test.want) || !errors.Is(err, ErrInvalidBlock) {
+			t.Errorf("Expected %v for %+v, but got %v", test.want, test.tx, err)
+		}
+	}
+	if bc.Len() != 2 || bc.State.Root() != root {
+		t.Error("Expected rejected transactions to leave the chain and state unchanged")
+	}
+}
+
+// TestReorganize tests that switching to a longer branch rolls the state back to the fork point.
+func TestReorganize(t *testing.T) {
+	genesis := &Genesis{Timestamp: GenesisTimestamp, Alloc: map[string]uint64{chaintest.Addr("alice"): 100}}
+	store := storage.NewMemoryBackend()
+	bc := NewBlockchainFromGenesis(genesis)
+	bc.AttachStore(store)
+	bc.AddBlock("common")
+	bc.AddBlock("main", chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 60}))
+
+	// Build a longer branch from the common block in which alice pays carol instead.
+	fork := NewBlockchainFromGenesis(genesis)
+	fork.AppendBlock(bc.Blocks[1])
+	fork.AddBlock("fork 1", chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("carol"), Amount: 10}))
+	fork.AddBlock("fork 2")
+	branch := fork.Blocks[2:]
+
+	if err := bc.Reorganize(branch[:1]); !errors.Is(err, ErrForkNotLonger) {
+		t.Errorf("Expected ErrForkNotLonger for a branch of equal length, but got %v", err)
+	}
+
+	// An invalid block in the branch leaves the original chain in place.
+	invalid := *branch[1]
+	invalid.Data = "tampered"
+	if err := bc.Reorganize([]*Block{branch[0], &invalid}); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("Expected ErrInvalidBlock for an invalid branch, but got %v", err)
+	}
+	if bc.Len() != 3 || bc.Account(chaintest.Addr("bob")).Balance != 60 || bc.Account(chaintest.Addr("carol")).Balance != 0 {
+		t.Error("Expected the original chain and state to be restored")
+	}
+
+	if err := bc.Reorganize(branch); err != nil {
+		t.Fatalf("Failed to reorganize: %v", err)
+	}
+	if bc.Len() != 4 || bc.LastBlock().Hash != fork.LastBlock().Hash || bc.State.Root() != fork.State.Root() {
+		t.Fatal("Expected the chain and state to match the branch")
+	}
+	if bc.Account(chaintest.Addr("bob")).Balance != 0 || bc.Account(chaintest.Addr("carol")).Balance != 10 || bc.Account(chaintest.Addr("alice")).Balance != 90 {
+		t.Error("Expected the payment to bob to be rolled back")
+	}
+
+	// The store follows the reorganization.
+	loaded, repairs, err := OpenBlockchain(store, genesis, nil)
+	if err != nil || len(repairs) != 0 {
+		t.Fatalf("Expected a consistent store after reorganizing, but got %v %v", repairs, err)
+	}
+	if loaded.LastBlock().Hash != fork.LastBlock().Hash || loaded.State.Root() != fork.State.Root() {
+		t.Error("Expected the stored chain to match the branch")
+	}
+}
+
+// TestUTXOLedger tests spending outputs in UTXO mode, including double spends and rolling spends back in a
+// reorganization.
+func TestUTXOLedger(t *testing.T) {
+	genesis := &Genesis{Timestamp: GenesisTimestamp, Ledger: LedgerUTXO, Alloc: map[string]uint64{chaintest.Addr("alice"): 100}}
+	bc := NewBlockchainFromGenesis(genesis)
+	if bc.Ledger() != LedgerUTXO {
+		t.Fatalf("Expected the UTXO ledger mode, but got %s", bc.Ledger())
+	}
+	if genesis.Block().Hash == (&Genesis{Timestamp: GenesisTimestamp, Alloc: genesis.Alloc}).Block().Hash {
+		t.Error("Expected the ledger mode to change the genesis hash")
+	}
+	coins := bc.UnspentOutputs(chaintest.Addr("alice"))
+	if len(coins) != 1 || coins[0].Amount != 100 {
+		t.Fatalf("Expected alice to own one output of 100, but got %+v", coins)
+	}
+
+	fork := NewBlockchainFromGenesis(genesis)
+	pay := chaintest.Signed(&Transaction{
+		Inputs:  []TxInput{coins[0].TxInput},
+		Outputs: []TxOutput{{Address: chaintest.Addr("bob"), Amount: 60}, {Address: chaintest.Addr("alice"), Amount: 40}},
+	}, chaintest.Addr("alice"))
+	if err := bc.AddBlock("payment", pay); err != nil {
+		t.Fatalf("Failed to spend an output: %v", err)
+	}
+	if bob := bc.UnspentOutputs(chaintest.Addr("bob")); len(bob) != 1 || bob[0].Amount != 60 || bob[0].TxID != pay.ID() {
+		t.Errorf("Expected bob to own the new output of 60, but got %+v", bob)
+	}
+
+	root := bc.State.Root()
+	for _, test := range []struct {
+		tx   *Transaction
+		want error
+	}{
+		{chaintest.Signed(&Transaction{Inputs: []TxInput{coins[0].TxInput}, Outputs: []TxOutput{{Address: chaintest.Addr("carol"), Amount: 100}}}, chaintest.Addr("alice")), ErrDoubleSpend},
+		{chaintest.Signed(&Transaction{Inputs: []TxInput{{TxID: pay.ID(), Index: 1}, {TxID: pay.ID(), Index: 1}}, Outputs: []TxOutput{{Address: chaintest.Addr("carol"), Amount: 80}}}, chaintest.Addr("alice")), ErrDoubleSpend},
+		{chaintest.Signed(&Transaction{Inputs: []TxInput{{TxID: pay.ID(), Index: 1}}, Outputs: []TxOutput{{Address: chaintest.Addr("carol"), Amount: 41}}}, chaintest.Addr("alice")), ErrInsufficientBalance},
+		{&Transaction{Outputs: []TxOutput{{Address: chaintest.Addr("carol"), Amount: 1}}}, ErrInvalidTransaction},
+		{&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("carol"), Amount: 1}, ErrInvalidTransaction},
+	} {
+		if err := bc.AddBlock("invalid", test.tx); !errors.Is(err, test.want) || !errors.Is(err, ErrInvalidBlock) {
+			t.Errorf("Expected %v for %+v, but got %v", test.want, test.tx, err)
+		}
+	}
+	if bc.Len() != 2 || bc.State.Root() != root {
+		t.Error("Expected rejected transactions to leave the chain and state unchanged")
+	}
+
+	// A longer branch that spends the same output elsewhere replaces the payment to bob.
+	fork.AddBlock("fork 1", chaintest.Signed(&Transaction{Inputs: []TxInput{coins[0].TxInput}, Outputs: []TxOutput{{Address: chaintest.Addr("carol"), Amount: 100}}}, chaintest.Addr("alice")))
+	fork.AddBlock("fork 2")
+	if err := bc.Reorganize(fork.Blocks[1:]); err != nil {
+		t.Fatalf("Failed to reorganize: %v", err)
+	}
+	if len(bc.UnspentOutputs(chaintest.Addr("bob"))) != 0 || len(bc.UnspentOutputs(chaintest.Addr("carol"))) != 1 || bc.State.Root() != fork.State.Root() {
+		t.Error("Expected the payment to bob to be rolled back")
+	}
+}
+
+// TestSignedTransactions tests that spending requires the signature of the key the funds belong to, and that
+// funds can only be sent to key-derived addresses, in both ledger modes.
+func TestSignedTransactions(t *testing.T) {
+	signer, err := crypto.GenerateKey(crypto.Ed25519, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	other, _ := crypto.GenerateKey(crypto.Secp256k1, nil)
+	owner := crypto.Address(signer)
+
+	for _, ledger := range []LedgerMode{LedgerAccount, LedgerUTXO} {
+		bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Ledger: ledger, Alloc: map[string]uint64{owner: 100}})
+		newTx := func() *Transaction {
+			if ledger == LedgerUTXO {
+				return &Transaction{Inputs: []TxInput{bc.UnspentOutputs(owner)[0].TxInput}, Outputs: []TxOutput{{Address: chaintest.Addr("bob"), Amount: 100}}}
+			}
+			return &Transaction{From: owner, To: chaintest.Addr("bob"), Amount: 100}
+		}
+
+		unsigned := newTx()
+		wrongKey := newTx()
+		wrongKey.Sign(other)
+		tampered := newTx()
+		tampered.Sign(signer)
+		tampered.Signatures[0].Signature = tampered.Signatures[0].Signature[2:] + "00"
+		for name, tx := range map[string]*Transaction{"unsigned": unsigned, "wrong key": wrongKey, "tampered": tampered} {
+			if err := bc.AddBlock(name, tx); !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("%s: expected ErrUnauthorized for a %s transaction, but got %v", ledger, name, err)
+			}
+		}
+
+		valid := newTx()
+		if err := valid.Sign(signer); err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		if err := bc.AddBlock("signed", valid); err != nil {
+			t.Errorf("%s: failed to add a signed transaction: %v", ledger, err)
+		}
+
+		// Funds allocated to a name by the genesis block cannot be spent, as no key can sign for them, and
+		// nothing may be sent to a name after the genesis block.
+		named := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Ledger: ledger, Alloc: map[string]uint64{"alice": 100}})
+		spend := &Transaction{From: "alice", To: owner, Amount: 100}
+		toName := &Transaction{From: chaintest.Addr("bob"), To: "carol", Amount: 1}
+		if ledger == LedgerUTXO {
+			spend = &Transaction{Inputs: []TxInput{named.UnspentOutputs("alice")[0].TxInput}, Outputs: []TxOutput{{Address: owner, Amount: 100}}}
+			toName = &Transaction{Inputs: []TxInput{bc.UnspentOutputs(chaintest.Addr("bob"))[0].TxInput}, Outputs: []TxOutput{{Address: "carol", Amount: 100}}}
+		}
+		if err := named.AddBlock("unsigned spend", spend); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%s: expected ErrUnauthorized for spending the funds of a name, but got %v", ledger, err)
+		}
+		if err := bc.AddBlock("payment to a name", chaintest.Signed(toName, chaintest.Addr("bob"))); !errors.Is(err, ErrInvalidTransaction) {
+			t.Errorf("%s: expected ErrInvalidTransaction for paying a name, but got %v", ledger, err)
+		}
+	}
+}
+
+// TestMultisig tests that the funds of a 2-of-3 multisig address can be spent only with two signatures.
+func TestMultisig(t *testing.T) {
+	var signers []crypto.Signer
+	var addresses []string
+	for _, algorithm := range crypto.Algorithms {
+		signer, err := crypto.GenerateKey(algorithm, nil)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		signers = append(signers, signer)
+		addresses = append(addresses, crypto.Address(signer))
+	}
+	policy, err := NewMultisigPolicy(2, addresses)
+	if err != nil {
+		t.Fatalf("Failed to create the policy: %v", err)
+	}
+	owner := policy.Address()
+	if reordered, _ := NewMultisigPolicy(2, []string{addresses[2], addresses[0], addresses[1]}); reordered.Address() != owner {
+		t.Errorf("The address of a policy depends on the order of its signers")
+	}
+	for _, invalid := range []*MultisigPolicy{
+		{Threshold: 0, Signers: policy.Signers},
+		{Threshold: 4, Signers: policy.Signers},
+		{Threshold: 1, Signers: []string{policy.Signers[0], policy.Signers[0]}},
+		{Threshold: 1, Signers: []string{"alice"}},
+	} {
+		if err := invalid.Validate(); !errors.Is(err, ErrInvalidMultisig) {
+			t.Errorf("Expected ErrInvalidMultisig for %+v, but got %v", invalid, err)
+		}
+	}
+
+	for _, ledger := range []LedgerMode{LedgerAccount, LedgerUTXO} {
+		bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Ledger: ledger, Alloc: map[string]uint64{owner: 100}})
+		newTx := func(signedBy ...crypto.Signer) *Transaction {
+			tx := &Transaction{From: owner, To: chaintest.Addr("bob"), Amount: 100, Multisig: []MultisigPolicy{*policy}}
+			if ledger == LedgerUTXO {
+				tx = &Transaction{Inputs: []TxInput{bc.UnspentOutputs(owner)[0].TxInput}, Outputs: []TxOutput{{Address: chaintest.Addr("bob"), Amount: 100}}, Multisig: []MultisigPolicy{*policy}}
+			}
+			for _, signer := range signedBy {
+				tx.Sign(signer)
+			}
+			return tx
+		}
+
+		outsider, _ := crypto.GenerateKey(crypto.Ed25519, nil)
+		withoutPolicy := newTx()
+		withoutPolicy.Multisig = nil
+		withoutPolicy.Sign(signers[0])
+		withoutPolicy.Sign(signers[1])
+		for name, tx := range map[string]*Transaction{
+			"one signature":  newTx(signers[0]),
+			"outsider":       newTx(signers[0], outsider),
+			"signed twice":   newTx(signers[0], signers[0]),
+			"without policy": withoutPolicy,
+		} {
+			if err := bc.AddBlock(name, tx); !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("%s: expected ErrUnauthorized for a transaction with %s, but got %v", ledger, name, err)
+			}
+		}
+
+		// Two signers sign copies of the transaction independently; combined, they authorize it.
+		first, second := newTx(signers[0]), newTx(signers[2])
+		status, err := first.MultisigStatus()
+		if err != nil || len(status) != 1 || status[0].Complete || len(status[0].Signed) != 1 {
+			t.Errorf("%s: unexpected status of a partially-signed transaction: %+v, %v", ledger, status, err)
+		}
+		combined, err := CombineSignatures([]*Transaction{first, second, first})
+		if err != nil {
+			t.Fatalf("%s: failed to combine signatures: %v", ledger, err)
+		}
+		if len(combined.Signatures) != 2 {
+			t.Errorf("%s: expected 2 combined signatures, but got %d", ledger, len(combined.Signatures))
+		}
+		if status, _ := combined.MultisigStatus(); len(status) != 1 || !status[0].Complete {
+			t.Errorf("%s: expected the combined transaction to be complete: %+v", ledger, status)
+		}
+
+		different := newTx(signers[1])
+		different.Multisig = nil
+		if _, err := CombineSignatures([]*Transaction{first, different}); !errors.Is(err, ErrInvalidTransaction) {
+			t.Errorf("%s: expected ErrInvalidTransaction for combining different transactions, but got %v", ledger, err)
+		}
+		if err := bc.AddBlock("combined", combined); err != nil {
+			t.Errorf("%s: failed to add a transaction signed by 2 of 3: %v", ledger, err)
+		}
+	}
+}
+
+// TestFeesAndRewards tests that senders pay fees on top of their transfers, and that a coinbase may pay the
+// producer no more than the block reward plus the fees.
+func TestFeesAndRewards(t *testing.T) {
+	schedule := IssuanceSchedule{InitialReward: 50, HalvingInterval: 2}
+	for height, want := range []uint64{0, 50, 50, 25, 25, 12} {
+		if got := schedule.Reward(height); got != want {
+			t.Errorf("Reward at height %d: got %d, want %d", height, got, want)
+		}
+	}
+	if got := schedule.Supply(5); got != 162 {
+		t.Errorf("Supply at height 5: got %d, want 162", got)
+	}
+
+	for _, ledger := range []LedgerMode{LedgerAccount, LedgerUTXO} {
+		bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Ledger: ledger, Issuance: schedule, Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+		if got := bc.Issuance(); got != schedule {
+			t.Fatalf("%s: the genesis block recorded schedule %+v, want %+v", ledger, got, schedule)
+		}
+		transfer := func(amount, fee uint64) *Transaction {
+			if ledger == LedgerUTXO {
+				input := bc.UnspentOutputs(chaintest.Addr("alice"))[0]
+				return chaintest.Signed(&Transaction{Fee: fee, Inputs: []TxInput{input.TxInput}, Outputs: []TxOutput{{Address: chaintest.Addr("bob"), Amount: amount}, {Address: chaintest.Addr("alice"), Amount: input.Amount - amount - fee}}}, chaintest.Addr("alice"))
+			}
+			return chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: amount, Fee: fee, Nonce: bc.Account(chaintest.Addr("alice")).Nonce})
+		}
+		balance := func(address string) uint64 {
+			if ledger == LedgerUTXO {
+				var total uint64
+				for _, output := range bc.UnspentOutputs(address) {
+					total += output.Amount
+				}
+				return total
+			}
+			return bc.Account(address).Balance
+		}
+
+		overspend := chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 60, Fee: 50})
+		if ledger == LedgerUTXO {
+			overspend = chaintest.Signed(&Transaction{Fee: 50, Inputs: []TxInput{bc.UnspentOutputs(chaintest.Addr("alice"))[0].TxInput}, Outputs: []TxOutput{{Address: chaintest.Addr("bob"), Amount: 60}}}, chaintest.Addr("alice"))
+		}
+		if err := bc.AddBlock("overspend", overspend); !errors.Is(err, ErrInsufficientBalance) {
+			t.Errorf("%s: expected ErrInsufficientBalance for an amount and fee above the balance, but got %v", ledger, err)
+		}
+
+		tx := transfer(40, 5)
+		coinbase := bc.Coinbase(chaintest.Addr("miner"), []*Transaction{tx})
+		greedy := bc.Coinbase(chaintest.Addr("miner"), []*Transaction{tx, {Fee: 1}})
+		wrongHeight := bc.Coinbase(chaintest.Addr("miner"), []*Transaction{tx})
+		wrongHeight.Coinbase++
+		for name, txs := range map[string][]*Transaction{
+			"overpaying coinbase":   {greedy, tx},
+			"coinbase at height 2":  {wrongHeight, tx},
+			"coinbase not first":    {tx, coinbase},
+			"coinbase without fees": {bc.Coinbase(chaintest.Addr("miner"), []*Transaction{tx})},
+		} {
+			if err := bc.AddBlock(name, txs...); !errors.Is(err, ErrInvalidTransaction) {
+				t.Errorf("%s: expected ErrInvalidTransaction for a block with an %s, but got %v", ledger, name, err)
+			}
+		}
+		if err := bc.CheckTransactions([]*Transaction{coinbase}); !errors.Is(err, ErrInvalidTransaction) {
+			t.Errorf("%s: expected a coinbase to be rejected outside a block, but got %v", ledger, err)
+		}
+
+		if err := bc.AddBlock("rewarded", coinbase, tx); err != nil {
+			t.Fatalf("%s: failed to add a block with a coinbase: %v", ledger, err)
+		}
+		if balance(chaintest.Addr("miner")) != 55 || balance(chaintest.Addr("alice")) != 55 || balance(chaintest.Addr("bob")) != 40 {
+			t.Errorf("%s: balances after the block: miner %d, alice %d, bob %d; want 55, 55, 40", ledger, balance(chaintest.Addr("miner")), balance(chaintest.Addr("alice")), balance(chaintest.Addr("bob")))
+		}
+
+		// Without a coinbase, the fees are burned.
+		if err := bc.AddBlock("unrewarded", transfer(10, 5)); err != nil {
+			t.Fatalf("%s: failed to add a block without a coinbase: %v", ledger, err)
+		}
+		if balance(chaintest.Addr("alice")) != 40 {
+			t.Errorf("%s: alice has %d after paying 10 and a fee of 5, want 40", ledger, balance(chaintest.Addr("alice")))
+		}
+	}
+}
+
+// TestLockedTransactions tests that blocks may not include transactions before their lock height or lock time.
+func TestLockedTransactions(t *testing.T) {
+	now := time.Now()
+	bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+	bc.Clock = fixedClock(now)
+
+	byHeight := chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 10, LockHeight: 2})
+	if bc.Unlocked(byHeight) {
+		t.Errorf("Expected a transaction locked until height 2 to be locked at height 1")
+	}
+	if err := bc.AddBlock("early", byHeight); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked for a transaction included below its lock height, but got %v", err)
+	}
+	if err := bc.CheckTransactions([]*Transaction{byHeight}); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected CheckTransactions to reject a locked transaction, but got %v", err)
+	}
+	if err := bc.AddBlock("empty"); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	if err := bc.AddBlock("on time", byHeight); err != nil {
+		t.Errorf("Failed to add a transaction at its lock height: %v", err)
+	}
+
+	byTime := chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 10, Nonce: 1, LockTime: now.Add(time.Hour).UnixMilli()})
+	if err := bc.AddBlock("early", byTime); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked for a transaction included before its lock time, but got %v", err)
+	}
+	bc.Clock = fixedClock(now.Add(time.Hour))
+	if !bc.Unlocked(byTime) {
+		t.Errorf("Expected the transaction to be unlocked at its lock time")
+	}
+	if err := bc.AddBlock("on time", byTime); err != nil {
+		t.Errorf("Failed to add a transaction at its lock time: %v", err)
+	}
+}
+
+// TestContracts tests deploying and calling a contract, including failed calls and read-only calls.
+func TestContracts(t *testing.T) {
+	bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Alloc: map[string]uint64{chaintest.Addr("alice"): 10000}})
+	code, err := vm.Assemble(`
+		DUP 1 PUSH "inc" EQ PUSH @inc JUMPI
+		DUP 1 PUSH "get" EQ PUSH @get JUMPI
+		PUSH "unknown function" REVERT
+	inc:
+		PUSH "count" PUSH "count" SLOAD PUSH 1 ADD
+		DUP 1 PUSH "incremented" SWAP 1 LOG 1
+		SSTORE STOP
+	get:
+		PUSH "count" SLOAD RETURN
+	`)
+	if err != nil {
+		t.Fatalf("Failed to assemble: %v", err)
+	}
+
+	deploy := chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), Code: fmt.Sprintf("%x", code), GasLimit: 5000, Fee: 5000, Amount: 7})
+	if err := bc.AddBlock("cheap deploy", chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), Code: deploy.Code, GasLimit: 10, Fee: 10})); !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("Expected a deployment above its gas limit to be rejected, but got %v", err)
+	}
+	if err := bc.AddBlock("deploy", deploy); err != nil {
+		t.Fatalf("Failed to deploy: %v", err)
+	}
+	receipt, ok := bc.Receipt(deploy.ID())
+	address := ContractAddress(chaintest.Addr("alice"), 0)
+	if !ok || receipt.Status != ReceiptSuccess || receipt.ContractAddress != address || receipt.GasUsed != uint64(len(code))*DeployGasPerByte {
+		t.Fatalf("Expected a successful deployment at %s, but got %+v", address, receipt)
+	}
+
+	call := func(function string, nonce uint64) *Transaction {
+		return chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: address, Args: []string{fmt.Sprintf("%x", function)}, GasLimit: 1000, Fee: 1000, Amount: 3, Nonce: nonce})
+	}
+	inc, fail := call("inc", 1), call("set", 2)
+	if err := bc.AddBlock("calls", inc, fail); err != nil {
+		t.Fatalf("Failed to call: %v", err)
+	}
+	receipt, _ = bc.Receipt(inc.ID())
+	if receipt.Status != ReceiptSuccess || len(receipt.Logs) != 1 || receipt.Logs[0].Data != "01" {
+		t.Errorf("Expected a successful call with a log, but got %+v", receipt)
+	}
+	receipt, _ = bc.Receipt(fail.ID())
+	if receipt.Status != ReceiptFailed || !strings.Contains(receipt.Error, "unknown function") || len(receipt.Logs) != 0 {
+		t.Errorf("Expected a reverted call, but got %+v", receipt)
+	}
+
+	// The failed call paid its fee and used its nonce, but its amount stayed with alice.
+	if got := bc.Account(chaintest.Addr("alice")); got.Balance != 10000-5000-7-1000-3-1000 || got.Nonce != 3 {
+		t.Errorf("Expected alice to have 2990 and nonce 3, but got %+v", got)
+	}
+	contract, err := bc.Contract(address)
+	if err != nil || contract.Balance != 10 || contract.Storage[fmt.Sprintf("%x", "count")] != "01" {
+		t.Errorf("Expected the contract to hold 10 and a count of 1, but got %+v (%v)", contract, err)
+	}
+	if _, err := bc.Contract(chaintest.Addr("alice")); !errors.Is(err, ErrNotContract) {
+		t.Errorf("Expected ErrNotContract for an account, but got %v", err)
+	}
+
+	root, journal := bc.State.Root(), len(bc.State.journal)
+	result, err := bc.CallContract(address, chaintest.Addr("bob"), [][]byte{[]byte("inc")}, 1000)
+	if err != nil || len(result.Logs) != 1 {
+		t.Errorf("Expected a read-only call to succeed, but got %v", err)
+	}
+	if bc.State.Root() != root || len(bc.State.journal) != journal {
+		t.Error("Expected a read-only call to leave the chain's state untouched")
+	}
+	result, err = bc.CallContract(address, chaintest.Addr("bob"), [][]byte{[]byte("get")}, 1000)
+	if err != nil || string(result.Return) != "\x01" {
+		t.Errorf("Expected the read-only call not to change the count, but got %x (%v)", result.Return, err)
+	}
+
+	if err := bc.AddBlock("underpaid", chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: address, GasLimit: 1000, Fee: 10, Nonce: 3})); !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("Expected a fee below the gas limit to be rejected, but got %v", err)
+	}
+	if err := bc.AddBlock("not a contract", chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Args: []string{"00"}, GasLimit: 1000, Fee: 1000, Nonce: 3})); !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("Expected a call to an account to be rejected, but got %v", err)
+	}
+}
+
+// TestContractPayout tests that a contract can only pay key, multisig or contract addresses.
+func TestContractPayout(t *testing.T) {
+	bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Alloc: map[string]uint64{chaintest.Addr("alice"): 10000}})
+	code, _ := vm.Assemble(`CALLVALUE TRANSFER`) // Pays what it is paid to its argument.
+	if err := bc.AddBlock("deploy", chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), Code: fmt.Sprintf("%x", code), GasLimit: 1000, Fee: 1000})); err != nil {
+		t.Fatalf("Failed to deploy: %v", err)
+	}
+	address := ContractAddress(chaintest.Addr("alice"), 0)
+
+	pay := func(to string, nonce uint64) *Transaction {
+		return chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: address, Args: []string{fmt.Sprintf("%x", to)}, GasLimit: 1000, Fee: 1000, Amount: 5, Nonce: nonce})
+	}
+	valid, invalid := pay(chaintest.Addr("bob"), 1), pay("\xff/bob", 2)
+	if err := bc.AddBlock("payouts", valid, invalid); err != nil {
+		t.Fatalf("Failed to call: %v", err)
+	}
+	if receipt, _ := bc.Receipt(valid.ID()); receipt.Status != ReceiptSuccess || bc.Account(chaintest.Addr("bob")).Balance != 5 {
+		t.Errorf("Expected bob to be paid 5, but got %+v", receipt)
+	}
+	if receipt, _ := bc.Receipt(invalid.ID()); receipt.Status != ReceiptFailed || !strings.Contains(receipt.Error, "invalid recipient") {
+		t.Errorf("Expected a payout to a malformed address to fail, but got %+v", receipt)
+	}
+	for _, entry := range bc.State.Entries() {
+		if !utf8.ValidString(entry.Key) {
+			t.Errorf("Expected valid UTF-8 state keys, but got %q", entry.Key)
+		}
+	}
+}
+
+// TestReceiptsAndLogs tests the receipts of plain and contract transactions, the logs bloom of block headers
+// and log queries.
+func TestReceiptsAndLogs(t *testing.T) {
+	genesis := &Genesis{Timestamp: GenesisTimestamp, Alloc: map[string]uint64{chaintest.Addr("alice"): 10000}}
+	bc := NewBlockchainFromGenesis(genesis)
+	code, _ := vm.Assemble(`PUSH "event" SWAP 1 PUSH "data" LOG 2`) // Logs its argument as the second topic.
+	deploy := chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), Code: fmt.Sprintf("%x", code), GasLimit: 1000, Fee: 1000})
+	transfer := chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 10, Nonce: 1})
+	if err := bc.AddBlock("deploy", deploy, transfer); err != nil {
+		t.Fatalf("Failed to deploy: %v", err)
+	}
+	address := ContractAddress(chaintest.Addr("alice"), 0)
+	emit := func(topic string, nonce uint64) *Transaction {
+		return chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: address, Args: []string{fmt.Sprintf("%x", topic)}, GasLimit: 1000, Fee: 1000, Nonce: nonce})
+	}
+	x, y := emit("x", 2), emit("y", 3)
+	bc.AddBlock("x", x)
+	bc.AddBlock("empty")
+	bc.AddBlock("y", y)
+
+	receipt, ok := bc.Receipt(transfer.ID())
+	if !ok || receipt.Status != ReceiptSuccess || receipt.Height != 1 || receipt.BlockHash != bc.Blocks[1].Hash {
+		t.Errorf("Expected a successful receipt in block 1 for the transfer, but got %+v", receipt)
+	}
+	receipt, ok = bc.Receipt(x.ID())
+	if !ok || receipt.BlockHash != bc.Blocks[2].Hash || receipt.GasUsed == 0 || len(receipt.Logs) != 1 {
+		t.Errorf("Expected a receipt with a log in block 2 for the call, but got %+v", receipt)
+	}
+	if _, ok := bc.Receipt("unknown"); ok {
+		t.Errorf("Expected no receipt for an unknown transaction")
+	}
+
+	if bc.Blocks[1].LogsBloom != "" || bc.Blocks[3].LogsBloom != "" {
+		t.Errorf("Expected blocks without logs to have no logs bloom")
+	}
+	bloom, err := ParseBloom(bc.Blocks[2].LogsBloom)
+	if err != nil || !bloom.Test([]byte(address)) || !bloom.Test([]byte("x")) || bloom.Test([]byte("y")) {
+		t.Errorf("Expected the bloom of block 2 to hold its address and topic x only (%v)", err)
+	}
+
+	for _, test := range []struct {
+		filter LogFilter
+		want   []string // Second topics of the logs returned.
+	}{
+		{LogFilter{FromHeight: -1, ToHeight: -1}, []string{"x", "y"}},
+		{LogFilter{FromHeight: 3, ToHeight: -1}, []string{"y"}},
+		{LogFilter{FromHeight: 0, ToHeight: 2, Address: address}, []string{"x"}},
+		{LogFilter{FromHeight: -1, ToHeight: -1, Topic: fmt.Sprintf("%x", "y")}, []string{"y"}},
+		{LogFilter{FromHeight: -1, ToHeight: -1, Address: chaintest.Addr("bob")}, nil},
+	} {
+		logs, err := bc.Logs(test.filter)
+		var got []string
+		for _, log := range logs {
+			topic, _ := hex.DecodeString(log.Topics[1])
+			got = append(got, string(topic))
+		}
+		if err != nil || strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("Logs(%+v): got %v (%v), want %v", test.filter, got, err, test.want)
+		}
+	}
+	logs, _ := bc.Logs(LogFilter{FromHeight: 2, ToHeight: 2})
+	if len(logs) != 1 || logs[0].TxID != x.ID() || logs[0].Height != 2 || logs[0].BlockHash != bc.Blocks[2].Hash {
+		t.Errorf("Expected the log to name its transaction and block, but got %+v", logs)
+	}
+	for _, filter := range []LogFilter{{FromHeight: 3, ToHeight: 2}, {Topic: "zz"}} {
+		if _, err := bc.Logs(filter); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Expected ErrInvalidFilter for %+v, but got %v", filter, err)
+		}
+	}
+
+	// A block whose header commits to the wrong bloom is rejected.
+	copied := NewBlockchainFromGenesis(genesis)
+	copied.AppendBlock(bc.Blocks[1])
+	tampered := *bc.Blocks[2]
+	tampered.LogsBloom = bc.Blocks[1].LogsBloom
+	tampered.Hash = tampered.calculateHash()
+	if err := copied.AppendBlock(&tampered); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("Expected ErrInvalidBlock for a block with the wrong logs bloom, but got %v", err)
+	}
+	if err := copied.AppendBlock(bc.Blocks[2]); err != nil {
+		t.Errorf("Failed to append a block with logs: %v", err)
+	}
+}
+
+// TestTokens tests creating, minting, transferring and burning a token, and the supply and holders it tracks.
+func TestTokens(t *testing.T) {
+	bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Alloc: map[string]uint64{chaintest.Addr("alice"): 100, chaintest.Addr("bob"): 100}})
+	nonces := map[string]uint64{}
+	op := func(from, to string, token TokenOperation) *Transaction {
+		tx := chaintest.Signed(&Transaction{From: from, To: to, Nonce: nonces[from], Fee: 1, Token: &token})
+		nonces[from]++
+		return tx
+	}
+	create := TokenOperation{Op: TokenCreate, Symbol: "MTR", Name: "Metering", Decimals: 2, MaxSupply: 1000, Amount: 500}
+	if err := bc.AddBlock("create", op(chaintest.Addr("alice"), "", create)); err != nil {
+		t.Fatalf("Failed to create a token: %v", err)
+	}
+	if err := bc.AddBlock("moves",
+		op(chaintest.Addr("alice"), chaintest.Addr("bob"), TokenOperation{Op: TokenMint, Symbol: "MTR", Amount: 300}),
+		op(chaintest.Addr("alice"), chaintest.Addr("carol"), TokenOperation{Op: TokenTransfer, Symbol: "MTR", Amount: 200}),
+		op(chaintest.Addr("bob"), "", TokenOperation{Op: TokenBurn, Symbol: "MTR", Amount: 100}),
+	); err != nil {
+		t.Fatalf("Failed to move tokens: %v", err)
+	}
+
+	token, err := bc.Token("MTR")
+	if err != nil || token.Supply != 700 || token.Issuer != chaintest.Addr("alice") || token.Name != "Metering" || token.Height != 1 {
+		t.Errorf("Expected MTR issued by alice with a supply of 700, but got %+v (%v)", token, err)
+	}
+	holders, _ := bc.TokenHolders("MTR")
+	if len(holders) != 3 || holders[0] != (TokenHolder{chaintest.Addr("alice"), 300}) || holders[1].Balance != 200 || holders[2].Balance != 200 {
+		t.Errorf("Unexpected holders: %v", holders)
+	}
+	if got := bc.Account(chaintest.Addr("carol")).Tokens["MTR"]; got != 200 {
+		t.Errorf("Expected carol's account to show 200 MTR, but got %d", got)
+	}
+	if got := bc.Account(chaintest.Addr("alice")).Balance; got != 97 {
+		t.Errorf("Expected alice to pay 3 in fees, leaving 97, but got %d", got)
+	}
+	if tokens := bc.Tokens(); len(tokens) != 1 || tokens[0].Symbol != "MTR" {
+		t.Errorf("Expected one token, but got %v", tokens)
+	}
+	if _, err := bc.Token("XYZ"); !errors.Is(err, ErrUnknownToken) {
+		t.Errorf("Expected ErrUnknownToken, but got %v", err)
+	}
+
+	for name, test := range map[string]struct {
+		tx   *Transaction
+		want error
+	}{
+		"mint by a holder":      {&Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("bob"), Nonce: 1, Token: &TokenOperation{Op: TokenMint, Symbol: "MTR", Amount: 1}}, ErrUnauthorized},
+		"mint beyond the cap":   {&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Nonce: 3, Token: &TokenOperation{Op: TokenMint, Symbol: "MTR", Amount: 301}}, ErrInvalidTransaction},
+		"transfer beyond funds": {&Transaction{From: chaintest.Addr("carol"), To: chaintest.Addr("bob"), Token: &TokenOperation{Op: TokenTransfer, Symbol: "MTR", Amount: 201}}, ErrInsufficientBalance},
+		"create twice":          {&Transaction{From: chaintest.Addr("bob"), Nonce: 1, Token: &create}, ErrInvalidTransaction},
+		"unknown token":         {&Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("alice"), Nonce: 1, Token: &TokenOperation{Op: TokenTransfer, Symbol: "XYZ", Amount: 1}}, ErrUnknownToken},
+		"bad symbol":            {&Transaction{From: chaintest.Addr("bob"), Nonce: 1, Token: &TokenOperation{Op: TokenCreate, Symbol: "mtr", Name: "x"}}, ErrInvalidTransaction},
+		"native amount":         {&Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("alice"), Amount: 1, Nonce: 1, Token: &TokenOperation{Op: TokenTransfer, Symbol: "MTR", Amount: 1}}, ErrInvalidTransaction},
+	} {
+		if err := bc.AddBlock(name, chaintest.Signed(test.tx)); !errors.Is(err, test.want) {
+			t.Errorf("%s: expected %v, but got %v", name, test.want, err)
+		}
+	}
+}
+
+func TestAssets(t *testing.T) {
+	bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Alloc: map[string]uint64{chaintest.Addr("alice"): 100, chaintest.Addr("bob"): 100, chaintest.Addr("carol"): 100}})
+	nonces := map[string]uint64{}
+	op := func(from, to string, asset AssetOperation) *Transaction {
+		tx := chaintest.Signed(&Transaction{From: from, To: to, Nonce: nonces[from], Fee: 1, Asset: &asset})
+		nonces[from]++
+		return tx
+	}
+	hash := sha256Hex("deed metadata")
+	if err := bc.AddBlock("mint",
+		op(chaintest.Addr("alice"), "", AssetOperation{Op: AssetMint, ID: "deed-1", MetadataHash: hash, URI: "ipfs://deed-1"}),
+		op(chaintest.Addr("alice"), chaintest.Addr("bob"), AssetOperation{Op: AssetMint, ID: "deed-2", MetadataHash: hash}),
+	); err != nil {
+		t.Fatalf("Failed to mint assets: %v", err)
+	}
+	if err := bc.AddBlock("delegated transfer",
+		op(chaintest.Addr("alice"), chaintest.Addr("carol"), AssetOperation{Op: AssetApprove, ID: "deed-1"}),
+		op(chaintest.Addr("carol"), chaintest.Addr("dave"), AssetOperation{Op: AssetTransfer, ID: "deed-1"}),
+	); err != nil {
+		t.Fatalf("Failed to transfer an asset on the owner's behalf: %v", err)
+	}
+	if err := bc.AddBlock("burn", op(chaintest.Addr("bob"), "", AssetOperation{Op: AssetBurn, ID: "deed-2"})); err != nil {
+		t.Fatalf("Failed to burn an asset: %v", err)
+	}
+
+	asset, err := bc.Asset("deed-1")
+	if err != nil || asset.Owner != chaintest.Addr("dave") || asset.Approved != "" || asset.Minter != chaintest.Addr("alice") || asset.MetadataHash != hash || asset.URI != "ipfs://deed-1" {
+		t.Errorf("Expected deed-1 owned by dave without an approval, but got %+v (%v)", asset, err)
+	}
+	if asset, _ := bc.Asset("deed-2"); !asset.Burned || asset.Owner != "" {
+		t.Errorf("Expected deed-2 to be burned, but got %+v", asset)
+	}
+	if assets := bc.Assets(chaintest.Addr("dave")); len(assets) != 1 || assets[0].ID != "deed-1" {
+		t.Errorf("Expected dave to own deed-1, but got %v", assets)
+	}
+	if assets := bc.Assets(chaintest.Addr("bob")); len(assets) != 0 {
+		t.Errorf("Expected bob to own nothing, but got %v", assets)
+	}
+	history, err := bc.AssetHistory("deed-1")
+	if err != nil || len(history) != 3 {
+		t.Fatalf("Expected 3 events of deed-1, but got %v (%v)", history, err)
+	}
+	for i, want := range []AssetEvent{
+		{Op: AssetMint, Height: 1, Sender: chaintest.Addr("alice"), Owner: chaintest.Addr("alice")},
+		{Op: AssetApprove, Height: 2, Sender: chaintest.Addr("alice"), Owner: chaintest.Addr("alice"), Approved: chaintest.Addr("carol")},
+		{Op: AssetTransfer, Height: 2, Sender: chaintest.Addr("carol"), Owner: chaintest.Addr("dave")},
+	} {
+		got := history[i]
+		if got.Op != want.Op || got.Height != want.Height || got.Sender != want.Sender || got.Owner != want.Owner || got.Approved != want.Approved || got.BlockHash != bc.Blocks[want.Height].Hash {
+			t.Errorf("Event %d: expected %+v, but got %+v", i, want, got)
+		}
+	}
+	if _, err := bc.AssetHistory("deed-3"); !errors.Is(err, ErrUnknownAsset) {
+		t.Errorf("Expected ErrUnknownAsset, but got %v", err)
+	}
+
+	for name, test := range map[string]struct {
+		tx   *Transaction
+		want error
+	}{
+		"approval cleared":  {&Transaction{From: chaintest.Addr("carol"), To: chaintest.Addr("carol"), Nonce: 1, Asset: &AssetOperation{Op: AssetTransfer, ID: "deed-1"}}, ErrUnauthorized},
+		"approve by other":  {&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("alice"), Nonce: 3, Asset: &AssetOperation{Op: AssetApprove, ID: "deed-1"}}, ErrUnauthorized},
+		"mint burned ID":    {&Transaction{From: chaintest.Addr("alice"), Nonce: 3, Asset: &AssetOperation{Op: AssetMint, ID: "deed-2", MetadataHash: hash}}, ErrInvalidTransaction},
+		"transfer burned":   {&Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("alice"), Nonce: 1, Asset: &AssetOperation{Op: AssetTransfer, ID: "deed-2"}}, ErrUnknownAsset},
+		"bad metadata hash": {&Transaction{From: chaintest.Addr("alice"), Nonce: 3, Asset: &AssetOperation{Op: AssetMint, ID: "deed-3", MetadataHash: "abc"}}, ErrInvalidTransaction},
+		"bad ID":            {&Transaction{From: chaintest.Addr("alice"), Nonce: 3, Asset: &AssetOperation{Op: AssetMint, ID: "deed/3", MetadataHash: hash}}, ErrInvalidTransaction},
+	} {
+		if err := bc.AddBlock(name, chaintest.Signed(test.tx)); !errors.Is(err, test.want) {
+			t.Errorf("%s: expected %v, but got %v", name, test.want, err)
+		}
+	}
+}
+
+func TestNotarization(t *testing.T) {
+	for _, ledger := range []LedgerMode{LedgerAccount, LedgerUTXO} {
+		bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Ledger: ledger})
+		hash := sha256Hex("contract.pdf")
+		notarization := &Notarization{Hash: hash, Name: "contract.pdf", ContentType: "application/pdf", Size: 12, Note: "signed copy"}
+		if err := bc.AddBlock("", &Transaction{Notarization: &Notarization{Hash: sha256Hex("other")}}, &Transaction{Notarization: notarization}); err != nil {
+			t.Fatalf("%s: failed to notarize documents: %v", ledger, err)
+		}
+
+		record, err := bc.Notarized(hash)
+		if err != nil {
+			t.Fatalf("%s: expected the document to be notarized, but got %v", ledger, err)
+		}
+		if record.Notarization != *notarization || record.Height != 1 || record.BlockHash != bc.Blocks[1].Hash || record.Timestamp != bc.Blocks[1].Timestamp {
+			t.Errorf("%s: unexpected record %+v", ledger, record)
+		}
+		if err := merkle.VerifyTxProof(bc.Blocks[1].Hash, record.Proof); err != nil || record.Proof.TxID != record.TxID {
+			t.Errorf("%s: expected a valid proof of %s, but got %v", ledger, record.TxID, err)
+		}
+		if _, err := bc.Notarized(sha256Hex("unknown")); !errors.Is(err, ErrNotNotarized) {
+			t.Errorf("%s: expected ErrNotNotarized, but got %v", ledger, err)
+		}
+
+		for name, tx := range map[string]*Transaction{
+			"notarized twice": {Notarization: &Notarization{Hash: hash, Note: "again"}},
+			"upper-case hash": {Notarization: &Notarization{Hash: strings.ToUpper(sha256Hex("new"))}},
+			"short hash":      {Notarization: &Notarization{Hash: "abcd"}},
+			"long note":       {Notarization: &Notarization{Hash: sha256Hex("new"), Note: strings.Repeat("x", MaxNotaryFieldLength+1)}},
+			"with a fee":      {Fee: 1, Notarization: &Notarization{Hash: sha256Hex("new")}},
+		} {
+			if err := bc.AddBlock(name, tx); !errors.Is(err, ErrInvalidTransaction) {
+				t.Errorf("%s: %s: expected ErrInvalidTransaction, but got %v", ledger, name, err)
+			}
+		}
+	}
+}
+
+// TestPendingState tests that a pending state applies each transaction on top of the ones before it, undoes only
+// the transactions that fail, leaves the chain untouched and goes stale once a block is connected.
+func TestPendingState(t *testing.T) {
+	bc := NewBlockchainFromGenesis(&Genesis{Timestamp: GenesisTimestamp, Alloc: map[string]uint64{chaintest.Addr("alice"): 100}})
+	pending := bc.NewPendingState()
+
+	first := chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 60})
+	overdraft := chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 60, Nonce: 1})
+	second := chaintest.Signed(&Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 40, Nonce: 1})
+	if err := pending.Apply(first); err != nil {
+		t.Fatalf("Failed to apply a transaction: %v", err)
+	}
+	if err := pending.Apply(overdraft); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("Expected ErrInsufficientBalance after the first transaction, but got %v", err)
+	}
+	if err := pending.Apply(second); err != nil {
+		t.Fatalf("Failed to apply a transaction after a failed one: %v", err)
+	}
+	if account := bc.Account(chaintest.Addr("alice")); account.Balance != 100 || account.Nonce != 0 {
+		t.Errorf("Expected the chain's state to be untouched, but alice has %+v", account.Account)
+	}
+
+	if err := bc.AddBlock("block", first); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	if err := pending.Apply(chaintest.Signed(&Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("alice"), Amount: 1})); !errors.Is(err, ErrTipChanged) {
+		t.Errorf("Expected ErrTipChanged once a block is connected, but got %v", err)
+	}
+}
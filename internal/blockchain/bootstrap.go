@@ -0,0 +1,61 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSnapshotMismatch is returned when snapshot state does not match the state root in its header.
+var ErrSnapshotMismatch = errors.New("snapshot state does not match header")
+
+// StateSnapshot returns the headers of every block and the state entries at the current tip, captured atomically.
+func (bc *Blockchain) StateSnapshot() (headers []*Block, entries []StateEntry) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	headers = make([]*Block, len(bc.Blocks))
+	for i, block := range bc.Blocks {
+		headers[i] = block.Header()
+	}
+	return headers, bc.State.Entries()
+}
+
+// NewBlockchainFromSnapshot creates a blockchain from the headers of every block up to a snapshot height and
+// the state at that height, without replaying any block bodies.
+// The header chain is validated from genesis, including the checkpoints, and the state must match the
+// StateRoot of the last header. Bodies of later blocks can then be appended with AppendBlock.
+// Parameters:
+// - genesis: The genesis the chain must start with; it selects the checkpoints like NewBlockchainFromGenesis.
+// - headers: The headers of blocks 0..height; any bodies are dropped.
+// - entries: The state after applying block height.
+// - checkpoints: Extra trusted checkpoints in addition to those of the genesis.
+// Returns:
+// - The bootstrapped blockchain, or an error if the headers or state cannot be trusted.
+func NewBlockchainFromSnapshot(genesis *Genesis, headers []*Block, entries []StateEntry, checkpoints []Checkpoint) (*Blockchain, error) {
+	if len(headers) == 0 {
+		return nil, errors.New("snapshot has no headers")
+	}
+
+	bc := newBlockchain()
+	bc.Checkpoints = append(genesisCheckpoints(genesis.Block()), checkpoints...)
+	for _, header := range headers {
+		bc.Blocks = append(bc.Blocks, header.Header())
+	}
+
+	report, _ := bc.validateRange(0, len(bc.Blocks)-1)
+	if !report.Valid {
+		failure := report.Failures[0]
+		return nil, fmt.Errorf("%w at height %d: %s: %s", ErrInvalidBlock, failure.Height, failure.Reason, failure.Detail)
+	}
+
+	bc.State = NewStateFromEntries(entries)
+	tip := bc.Blocks[len(bc.Blocks)-1]
+	if root := bc.State.Root(); root != tip.StateRoot {
+		return nil, fmt.Errorf("%w: state root %s, header %s", ErrSnapshotMismatch, root, tip.StateRoot)
+	}
+
+	bc.verified = len(bc.Blocks)
+	bc.prunedHeight = len(bc.Blocks) // Only the headers of the snapshot blocks are known.
+	bc.undo = make([][]StateChange, len(bc.Blocks))
+	return bc, nil
+}
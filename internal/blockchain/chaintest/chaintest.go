@@ -0,0 +1,67 @@
+// Package chaintest provides the test accounts and chain fixtures shared by the tests of the blockchain packages.
+// It does not import package blockchain, so that package's own tests can use it too.
+package chaintest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"blockchain/internal/crypto"
+)
+
+var (
+	keysMu sync.Mutex
+	keys   = map[string]crypto.Signer{} // Keys of the test accounts by address; see Addr.
+)
+
+// Addr returns the address of the test account with the given name, whose Ed25519 key is derived from it.
+func Addr(name string) string {
+	seed := sha256.Sum256([]byte(name))
+	signer, err := crypto.NewSigner(crypto.Ed25519, seed[:])
+	if err != nil {
+		panic(err)
+	}
+	address := crypto.Address(signer)
+	keysMu.Lock()
+	keys[address] = signer
+	keysMu.Unlock()
+	return address
+}
+
+// Transaction is a transaction the test accounts can sign, such as a *blockchain.Transaction.
+type Transaction interface {
+	Sign(signer crypto.Signer) error
+}
+
+// Signed signs tx with the keys of the test accounts at owners, or of its sender, the account in its From field,
+// if none are given, and returns it.
+func Signed[T Transaction](tx T, owners ...string) T {
+	if len(owners) == 0 {
+		owners = []string{reflect.Indirect(reflect.ValueOf(tx)).FieldByName("From").String()}
+	}
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	for _, owner := range owners {
+		tx.Sign(keys[owner])
+	}
+	return tx
+}
+
+// Chain is a chain blocks can be added to, such as a *blockchain.Blockchain.
+type Chain[T any] interface {
+	AddBlock(data string, txs ...T) error
+}
+
+// AddBlocks adds the given number of blocks, "Test Block 1" onwards, to bc and returns it.
+func AddBlocks[T any, C Chain[T]](t testing.TB, bc C, blocks int) C {
+	t.Helper()
+	for i := 1; i <= blocks; i++ {
+		if err := bc.AddBlock(fmt.Sprintf("Test Block %d", i)); err != nil {
+			t.Fatalf("Failed to add block: %v", err)
+		}
+	}
+	return bc
+}
@@ -0,0 +1,101 @@
+package blockchain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Checkpoint is a trusted height/hash pair. Blocks at or below the highest
+// checkpoint matched by the chain are not rehashed by incremental validation.
+type Checkpoint struct {
+	Height int    `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// DefaultCheckpoints are the checkpoints compiled into every node.
+var DefaultCheckpoints = []Checkpoint{
+	{Height: 0, Hash: "9f5c9124450ceea26d4c5c552f377ab9c5123aa83d0ff0313d46cb809d282171"},
+}
+
+// ParseCheckpoints parses a comma-separated list of "height:hash" pairs.
+// Parameters:
+// - s: The checkpoint list, e.g. "100:ab12...,200:cd34...". An empty string yields no checkpoints.
+// Returns:
+// - The parsed checkpoints, or an error describing the first malformed entry.
+func ParseCheckpoints(s string) ([]Checkpoint, error) {
+	var checkpoints []Checkpoint
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		heightStr, hash, ok := strings.Cut(entry, ":")
+		if !ok || hash == "" {
+			return nil, fmt.Errorf("invalid checkpoint %q: expected height:hash", entry)
+		}
+		height, err := strconv.Atoi(heightStr)
+		if err != nil || height < 0 {
+			return nil, fmt.Errorf("invalid checkpoint height %q", heightStr)
+		}
+		checkpoints = append(checkpoints, Checkpoint{Height: height, Hash: hash})
+	}
+	return checkpoints, nil
+}
+
+// checkpointAt returns the checkpoint at the given height, if any.
+func (bc *Blockchain) checkpointAt(height int) (Checkpoint, bool) {
+	for _, checkpoint := range bc.Checkpoints {
+		if checkpoint.Height == height {
+			return checkpoint, true
+		}
+	}
+	return Checkpoint{}, false
+}
+
+// trustedHeight returns the height of the highest checkpoint matched by the chain, or -1 if there is none.
+// The caller must hold bc.mu.
+func (bc *Blockchain) trustedHeight() int {
+	trusted := -1
+	for _, checkpoint := range bc.Checkpoints {
+		if checkpoint.Height < len(bc.Blocks) && checkpoint.Height > trusted && bc.Blocks[checkpoint.Height].Hash == checkpoint.Hash {
+			trusted = checkpoint.Height
+		}
+	}
+	return trusted
+}
+
+// ValidateIncremental verifies only the blocks added since the last successful validation.
+// Validation starts at the cached watermark or the highest matched checkpoint, whichever is higher;
+// the tip is always re-checked so the report is never empty.
+func (bc *Blockchain) ValidateIncremental() *ValidationReport {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	tip := len(bc.Blocks) - 1
+	bc.statusMu.Lock()
+	from := bc.verified
+	bc.statusMu.Unlock()
+
+	// Blocks below a matched checkpoint are trusted; the checkpoint block itself is still checked.
+	if trusted := bc.trustedHeight(); trusted > from {
+		from = trusted
+	}
+	from = min(from, tip)
+
+	report, _ := bc.validateRange(from, tip)
+	bc.updateWatermark(report)
+	return report
+}
+
+// updateWatermark records the outcome of a validation that covered every untrusted block up to report.To.
+func (bc *Blockchain) updateWatermark(report *ValidationReport) {
+	bc.statusMu.Lock()
+	defer bc.statusMu.Unlock()
+
+	if report.Valid {
+		bc.verified = report.To + 1
+	} else {
+		bc.verified = report.Failures[0].Height
+	}
+}
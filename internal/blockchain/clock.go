@@ -0,0 +1,115 @@
+package blockchain
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxClockOffset bounds how far the network-adjusted clock may drift from the local clock.
+	MaxClockOffset = 70 * time.Minute
+
+	// MinClockSamples is the number of hosts that must report their time before the clock is adjusted.
+	MinClockSamples = 5
+
+	// MaxClockSamples is the most hosts whose time is kept; later hosts are ignored until some disconnect.
+	MaxClockSamples = 200
+)
+
+// Clock provides the current time used for block timestamps and timestamp validation.
+type Clock interface {
+	Now() time.Time
+}
+
+// AdjustedClock is the local clock corrected by the median offset reported by peers.
+// Each host contributes a single sample however many connections it opens, and the clock is only adjusted once
+// MinClockSamples hosts have reported, so no single peer sets it; moving it still takes lying about the time from
+// half of the sampled hosts. Medians beyond MaxClockOffset are ignored.
+type AdjustedClock struct {
+	mu      sync.Mutex
+	samples map[string]*clockSample // Samples keyed by the peer's host, without its port.
+}
+
+// clockSample is the clock offset reported by a host.
+type clockSample struct {
+	offset time.Duration   // Offset of the host's clock from ours, as last reported.
+	peers  map[string]bool // Addresses of the host's peers that reported it.
+}
+
+// NewAdjustedClock creates an AdjustedClock without any peer samples.
+func NewAdjustedClock() *AdjustedClock {
+	return &AdjustedClock{samples: make(map[string]*clockSample)}
+}
+
+// sampleHost returns the host of a peer address, or the address itself if it has no port.
+func sampleHost(peer string) string {
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		return host
+	}
+	return peer
+}
+
+// AddSample records the time reported by a peer during its handshake. A host already sampled through another
+// connection has its sample replaced; a new host is ignored once MaxClockSamples hosts are sampled.
+// Parameters:
+// - peer: The address of the peer, whose host contributes a single sample.
+// - peerTime: The time reported by the peer.
+func (c *AdjustedClock) AddSample(peer string, peerTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	host := sampleHost(peer)
+	sample, ok := c.samples[host]
+	if !ok {
+		if len(c.samples) >= MaxClockSamples {
+			return
+		}
+		sample = &clockSample{peers: make(map[string]bool)}
+		c.samples[host] = sample
+	}
+	sample.offset = time.Until(peerTime)
+	sample.peers[peer] = true
+}
+
+// RemoveSample forgets the sample of a disconnected peer once its host has no other connection sampled.
+func (c *AdjustedClock) RemoveSample(peer string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	host := sampleHost(peer)
+	if sample, ok := c.samples[host]; ok {
+		delete(sample.peers, peer)
+		if len(sample.peers) == 0 {
+			delete(c.samples, host)
+		}
+	}
+}
+
+// Offset returns the median peer offset, or zero if fewer than MinClockSamples hosts are sampled or the median
+// exceeds MaxClockOffset.
+func (c *AdjustedClock) Offset() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.samples) < MinClockSamples {
+		return 0
+	}
+	offsets := make([]time.Duration, 0, len(c.samples))
+	for _, sample := range c.samples {
+		offsets = append(offsets, sample.offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	median := offsets[len(offsets)/2]
+	if median > MaxClockOffset || median < -MaxClockOffset {
+		return 0
+	}
+	return median
+}
+
+// Now returns the local time adjusted by the median peer offset.
+func (c *AdjustedClock) Now() time.Time {
+	return time.Now().Add(c.Offset())
+}
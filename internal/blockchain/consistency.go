@@ -0,0 +1,256 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"blockchain/internal/storage"
+)
+
+// ErrUnrepairable is returned by CheckStore when a stored chain is too damaged to be repaired.
+var ErrUnrepairable = errors.New("stored chain cannot be repaired")
+
+// CheckStore loads the chain kept in store and checks that its blocks, hash index, state, undo data and tip
+// pointer agree, repairing any divergence in one atomic batch:
+//   - blocks after a gap, beyond the tip pointer or that fail validation, including against the checkpoints of
+//     genesis and the extra trusted checkpoints, are removed, cutting the chain back;
+//   - the state is rolled back with the undo data of removed blocks, or rebuilt by replaying every block
+//     if it still does not match the state root of the last block;
+//   - hash index entries and undo data are made to match the remaining blocks;
+//   - the tip pointer is reset to the last remaining block.
+//
+// Returns:
+// - The loaded blockchain, without a store attached.
+// - A description of each repair made, empty if the store was consistent.
+// - An error wrapping ErrUnrepairable if the genesis block is missing, differs from the one built from genesis,
+// or the state cannot be rebuilt.
+func CheckStore(store storage.Backend, genesis *Genesis, checkpoints []Checkpoint) (*Blockchain, []string, error) {
+	var repairs []string
+	repair := func(format string, args ...interface{}) {
+		repairs = append(repairs, fmt.Sprintf(format, args...))
+	}
+	batch := storage.NewBatch()
+
+	// Read the tip pointer; -1 if it is missing or damaged.
+	tip := tipRecord{Height: -1}
+	if record, err := store.Get([]byte(tipKey)); err == nil {
+		if json.Unmarshal(record, &tip) != nil {
+			tip.Height = -1
+		}
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, err
+	}
+	if tip.Height < 0 {
+		repair("tip pointer missing or damaged")
+	}
+
+	// Load the blocks in height order, up to the first gap or undecodable record and at most up to the tip.
+	bc := newBlockchain()
+	bc.Checkpoints = append(genesisCheckpoints(genesis.Block()), checkpoints...)
+	stray := 0
+	err := store.Iterate([]byte(blockKeyPrefix), func(key, value []byte) error {
+		height, err := strconv.ParseInt(string(key[len(blockKeyPrefix):]), 16, 64)
+		var block Block
+		if err != nil || int(height) != len(bc.Blocks) || (tip.Height >= 0 && int(height) > tip.Height) || json.Unmarshal(value, &block) != nil {
+			batch.Delete(key)
+			stray++
+			return nil
+		}
+		bc.Blocks = append(bc.Blocks, &block)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(bc.Blocks) == 0 {
+		return nil, nil, fmt.Errorf("%w: genesis block missing", ErrUnrepairable)
+	}
+	if stray > 0 {
+		repair("removed %d blocks after height %d that are missing, damaged or beyond the tip", stray, len(bc.Blocks)-1)
+	}
+	storedTip := tip.Height
+	if storedTip < 0 || storedTip >= len(bc.Blocks) {
+		storedTip = len(bc.Blocks) - 1
+		if tip.Height >= len(bc.Blocks) {
+			repair("tip pointer at height %d but blocks end at height %d", tip.Height, storedTip)
+		}
+	}
+
+	// Cut the chain at the first block that fails validation.
+	report, _ := bc.validateRange(0, len(bc.Blocks)-1)
+	if !report.Valid {
+		failure := report.Failures[0]
+		if failure.Height == 0 {
+			return nil, nil, fmt.Errorf("%w: genesis block is invalid: %s", ErrUnrepairable, failure.Reason)
+		}
+		for height := failure.Height; height < len(bc.Blocks); height++ {
+			batch.Delete(heightKey(blockKeyPrefix, height))
+		}
+		repair("removed blocks from height %d, which fails validation: %s: %s", failure.Height, failure.Reason, failure.Detail)
+		bc.Blocks = bc.Blocks[:failure.Height]
+	}
+	last := bc.Blocks[len(bc.Blocks)-1]
+
+	if err := checkStoredState(store, bc, storedTip, batch, repair); err != nil {
+		return nil, nil, err
+	}
+	if err := checkHashIndex(store, bc, batch, repair); err != nil {
+		return nil, nil, err
+	}
+
+	// Drop undo data of blocks that are no longer part of the chain.
+	dropped := 0
+	err = store.Iterate([]byte(undoKeyPrefix), func(key, value []byte) error {
+		height, err := strconv.ParseInt(string(key[len(undoKeyPrefix):]), 16, 64)
+		if err != nil || int(height) >= len(bc.Blocks) {
+			batch.Delete(key)
+			dropped++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if dropped > 0 {
+		repair("removed undo data of %d blocks beyond the tip", dropped)
+	}
+
+	if tip.Height != len(bc.Blocks)-1 || tip.Hash != last.Hash {
+		if err := putTip(batch, last, len(bc.Blocks)-1); err != nil {
+			return nil, nil, err
+		}
+		repair("reset tip pointer to block %d %s", len(bc.Blocks)-1, last.Hash)
+	}
+
+	if batch.Len() > 0 {
+		if err := store.Write(batch); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for bc.prunedHeight < len(bc.Blocks) && bc.Blocks[bc.prunedHeight].Pruned {
+		bc.prunedHeight++
+	}
+
+	// Keep the undo data in memory for reorganizations.
+	bc.undo = make([][]StateChange, len(bc.Blocks))
+	err = store.Iterate([]byte(undoKeyPrefix), func(key, value []byte) error {
+		height, _ := strconv.ParseInt(string(key[len(undoKeyPrefix):]), 16, 64)
+		return json.Unmarshal(value, &bc.undo[height])
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	bc.verified = len(bc.Blocks)
+	return bc, repairs, nil
+}
+
+// checkStoredState loads the stored state into bc and makes it match the state root of bc's last block.
+// If blocks were removed above the last block, their undo data is applied first; if the state still does not
+// match, it is rebuilt by replaying every block. Any change is added to batch.
+func checkStoredState(store storage.Backend, bc *Blockchain, storedTip int, batch *storage.Batch, repair func(string, ...interface{})) error {
+	var entries []StateEntry
+	err := store.Iterate([]byte(stateKeyPrefix), func(key, value []byte) error {
+		entries = append(entries, StateEntry{Key: string(key[len(stateKeyPrefix):]), Value: string(value)})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	state := NewStateFromEntries(entries)
+	storedRoot := state.Root()
+	last := len(bc.Blocks) - 1
+
+	// Undo the blocks that were removed, newest first, while the state still matches the chain they belonged to.
+	if storedTip > last {
+		for height := storedTip; height > last; height-- {
+			record, err := store.Get(heightKey(undoKeyPrefix, height))
+			var undo []StateChange
+			if err != nil || json.Unmarshal(record, &undo) != nil {
+				break
+			}
+			state.revert(undo)
+		}
+	}
+
+	if state.Root() != bc.Blocks[last].StateRoot {
+		replayed, err := replayState(bc, batch)
+		if err != nil {
+			return err
+		}
+		state = replayed
+		repair("rebuilt the state by replaying %d blocks", len(bc.Blocks))
+	} else if storedTip > last {
+		repair("rolled the state back from height %d to %d", storedTip, last)
+	}
+
+	if state.Root() != storedRoot {
+		// Rewrite the whole state; the deletes are applied before the puts.
+		for _, entry := range entries {
+			batch.Delete([]byte(stateKeyPrefix + entry.Key))
+		}
+		for _, entry := range state.Entries() {
+			batch.Put([]byte(stateKeyPrefix+entry.Key), []byte(entry.Value))
+		}
+	}
+	bc.State = state
+	return nil
+}
+
+// replayState rebuilds the state by applying every block of bc from genesis, adding fresh undo data to batch.
+// Returns an error wrapping ErrUnrepairable if a block body has been pruned or the result does not match.
+func replayState(bc *Blockchain, batch *storage.Batch) (*State, error) {
+	state := NewState()
+	for height, block := range bc.Blocks {
+		if block.Pruned {
+			return nil, fmt.Errorf("%w: state does not match and block %d has been pruned; bootstrap from a snapshot instead", ErrUnrepairable, height)
+		}
+		undo, err := state.applyBlock(bc.StateModules, block, height)
+		if err != nil {
+			return nil, fmt.Errorf("%w: replaying block %d: %v", ErrUnrepairable, height, err)
+		}
+		if err := putUndo(batch, undo, height); err != nil {
+			return nil, err
+		}
+	}
+	if root := state.Root(); root != bc.Blocks[len(bc.Blocks)-1].StateRoot {
+		return nil, fmt.Errorf("%w: replayed state root %s does not match the last block", ErrUnrepairable, root)
+	}
+	return state, nil
+}
+
+// checkHashIndex makes the hash index map exactly the hashes of bc's blocks to their heights, adding any
+// change to batch.
+func checkHashIndex(store storage.Backend, bc *Blockchain, batch *storage.Batch, repair func(string, ...interface{})) error {
+	fixed := 0
+	err := store.Iterate([]byte(hashKeyPrefix), func(key, value []byte) error {
+		height, err := strconv.Atoi(string(value))
+		hash := string(key[len(hashKeyPrefix):])
+		if err != nil || height < 0 || height >= len(bc.Blocks) || bc.Blocks[height].Hash != hash {
+			batch.Delete(key)
+			fixed++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for height, block := range bc.Blocks {
+		value, err := store.Get([]byte(hashKeyPrefix + block.Hash))
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		if want := []byte(strconv.Itoa(height)); !bytes.Equal(value, want) {
+			batch.Put([]byte(hashKeyPrefix+block.Hash), want)
+			fixed++
+		}
+	}
+	if fixed > 0 {
+		repair("fixed %d hash index entries", fixed)
+	}
+	return nil
+}
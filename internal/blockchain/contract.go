@@ -0,0 +1,273 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"blockchain/internal/crypto"
+	"blockchain/internal/vm"
+)
+
+// State key prefixes of contracts: "code/<address>" holds the hex-encoded code of the contract at address and
+// "receipt/<txid>" holds the Receipt of a contract transaction as JSON. A contract's storage is its account
+// storage, with hex-encoded keys and values.
+const (
+	codePrefix    = "code/"
+	receiptPrefix = "receipt/"
+)
+
+const (
+	// MaxGasPerTransaction is the largest gas limit of a transaction.
+	MaxGasPerTransaction = 10_000_000
+
+	// MaxGasPerBlock is the most gas the transactions of a block may reserve with their gas limits.
+	MaxGasPerBlock = 50_000_000
+
+	// DeployGasPerByte is the gas a deployment uses per byte of code.
+	DeployGasPerByte = 20
+)
+
+// contractAlgorithm separates contract addresses from the addresses of public keys in crypto.DeriveAddress.
+const contractAlgorithm crypto.Algorithm = "contract"
+
+// ContractInfo is a contract's account together with its code, as returned by Blockchain.Contract.
+type ContractInfo struct {
+	AccountInfo
+	Code string `json:"code"` // Hex-encoded bytecode.
+}
+
+// ContractAddress returns the address of the contract deployed by the transaction of sender with nonce. It has
+// the form of a key-derived address, but no key can sign for it, so a contract's funds move only by its code.
+func ContractAddress(sender string, nonce uint64) string {
+	return crypto.DeriveAddress(contractAlgorithm, []byte(sender+"/"+strconv.FormatUint(nonce, 10)))
+}
+
+// isContract reports whether tx sets any of the contract fields.
+func (tx *Transaction) isContract() bool {
+	return tx.Code != "" || len(tx.Args) > 0 || tx.GasLimit != 0
+}
+
+// contractCode returns the code of the contract at address, or nil if there is none.
+func contractCode(state *State, address string) []byte {
+	value, ok := state.Get(codePrefix + address)
+	if !ok {
+		return nil
+	}
+	code, _ := hex.DecodeString(value)
+	return code
+}
+
+// checkGas rejects a block whose transactions' gas limits add up to more than MaxGasPerBlock.
+func checkGas(block *Block) error {
+	var gas uint64
+	for _, tx := range block.Transactions {
+		if tx.GasLimit > MaxGasPerTransaction {
+			return fmt.Errorf("transaction %s: %w: gas limit %d exceeds %d", tx.ID(), ErrInvalidTransaction, tx.GasLimit, MaxGasPerTransaction)
+		}
+		gas += tx.GasLimit
+	}
+	if gas > MaxGasPerBlock {
+		return fmt.Errorf("%w: gas limits of %d exceed the block's %d", ErrInvalidTransaction, gas, MaxGasPerBlock)
+	}
+	return nil
+}
+
+// applyContractTransaction applies a transaction that deploys a contract, if it has Code and no recipient, or
+// calls one, if its recipient is a contract.
+//
+// A deployment stores the code under the address given by ContractAddress and uses DeployGasPerByte gas per
+// byte. A call runs the contract's code with the transaction's arguments and its gas limit. Either way the
+// amount moves to the contract. The fee must cover the gas limit, a unit of gas costing a unit of currency, and
+// is paid in full even if less gas is used. A call that fails, e.g. by reverting or running out of gas, is
+// still included: the sender pays the fee and uses up the nonce, but the amount and every change made by the
+// contract are undone. Either way a Receipt is stored in the state.
+func applyContractTransaction(state *State, block *Block, tx *Transaction, height int) error {
+	if len(tx.Inputs) > 0 || len(tx.Outputs) > 0 {
+		return fmt.Errorf("%w: inputs and outputs are only allowed in UTXO mode", ErrInvalidTransaction)
+	}
+	if tx.From == "" {
+		return fmt.Errorf("%w: a contract transaction needs a sender", ErrInvalidTransaction)
+	}
+	if tx.Token != nil || tx.Asset != nil {
+		return fmt.Errorf("%w: a contract transaction has no token or asset operation", ErrInvalidTransaction)
+	}
+	if strings.Contains(tx.From, "/") || strings.Contains(tx.To, "/") {
+		return fmt.Errorf("%w: address contains \"/\"", ErrInvalidTransaction)
+	}
+	if tx.GasLimit > MaxGasPerTransaction {
+		return fmt.Errorf("%w: gas limit %d exceeds %d", ErrInvalidTransaction, tx.GasLimit, MaxGasPerTransaction)
+	}
+	if tx.Fee < tx.GasLimit {
+		return fmt.Errorf("%w: fee %d does not cover the gas limit %d", ErrInvalidTransaction, tx.Fee, tx.GasLimit)
+	}
+
+	deploy := tx.To == ""
+	var code []byte
+	var args [][]byte
+	if deploy {
+		if len(tx.Args) > 0 {
+			return fmt.Errorf("%w: a deployment takes no arguments", ErrInvalidTransaction)
+		}
+		var err error
+		if code, err = hex.DecodeString(tx.Code); err != nil {
+			return fmt.Errorf("%w: malformed code: %v", ErrInvalidTransaction, err)
+		}
+		if err := vm.Validate(code); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+		}
+		if gas := uint64(len(code)) * DeployGasPerByte; gas > tx.GasLimit {
+			return fmt.Errorf("%w: deploying %d bytes uses %d gas, more than the limit of %d", ErrInvalidTransaction, len(code), gas, tx.GasLimit)
+		}
+	} else {
+		if tx.Code != "" {
+			return fmt.Errorf("%w: code is only allowed in deployments, which have no recipient", ErrInvalidTransaction)
+		}
+		if code = contractCode(state, tx.To); code == nil {
+			return fmt.Errorf("%w: %s is not a contract", ErrInvalidTransaction, tx.To)
+		}
+		for i, arg := range tx.Args {
+			decoded, err := hex.DecodeString(arg)
+			if err != nil {
+				return fmt.Errorf("%w: malformed argument %d: %v", ErrInvalidTransaction, i, err)
+			}
+			args = append(args, decoded)
+		}
+	}
+	if err := authorize(tx, []string{tx.From}); err != nil {
+		return err
+	}
+
+	if err := chargeFee(state, tx, tx.Amount); err != nil {
+		return err
+	}
+
+	receipt := Receipt{TxID: tx.ID(), Height: height, Status: ReceiptSuccess}
+	if deploy {
+		receipt.ContractAddress = ContractAddress(tx.From, tx.Nonce)
+		receipt.GasUsed = uint64(len(code)) * DeployGasPerByte
+		state.Set(codePrefix+receipt.ContractAddress, tx.Code)
+		if err := transfer(state, tx.From, receipt.ContractAddress, tx.Amount); err != nil {
+			return err
+		}
+	} else {
+		mark := state.snapshot()
+		ctx := &vm.Context{
+			Address:   tx.To,
+			Caller:    tx.From,
+			Value:     tx.Amount,
+			Height:    height,
+			Timestamp: block.Timestamp,
+			Args:      args,
+			Gas:       tx.GasLimit,
+		}
+		err := transfer(state, tx.From, tx.To, tx.Amount)
+		if err == nil {
+			var result *vm.Result
+			result, err = vm.Execute(code, ctx, &contractHost{state: state, address: tx.To})
+			receipt.GasUsed = result.GasUsed
+			if err == nil {
+				receipt.Return = hex.EncodeToString(result.Return)
+				receipt.Logs = result.Logs
+			}
+		}
+		if err != nil {
+			state.revertTo(mark)
+			receipt.Status = ReceiptFailed
+			receipt.Error = err.Error()
+		}
+	}
+
+	encoded, _ := json.Marshal(receipt)
+	state.Set(receiptPrefix+receipt.TxID, string(encoded))
+	return nil
+}
+
+// transfer moves amount from one account to another. Contracts name the recipient with arbitrary bytes, so it must
+// be a key, multisig or contract address before it is used in a state key.
+func transfer(state *State, from, to string, amount uint64) error {
+	if !IsKeyAddress(to) {
+		return fmt.Errorf("%w: invalid recipient %q", ErrInvalidTransaction, to)
+	}
+	sender := state.Account(from)
+	if sender.Balance < amount {
+		return fmt.Errorf("%w: %s has %d, needs %d", ErrInsufficientBalance, from, sender.Balance, amount)
+	}
+	sender.Balance -= amount
+	state.SetAccount(from, sender)
+
+	recipient := state.Account(to)
+	if recipient.Balance > math.MaxUint64-amount {
+		return fmt.Errorf("%w: balance of %s would overflow", ErrInvalidTransaction, to)
+	}
+	recipient.Balance += amount
+	state.SetAccount(to, recipient)
+	return nil
+}
+
+// contractHost gives the contract at address access to the state.
+type contractHost struct {
+	state   *State
+	address string
+}
+
+func (h *contractHost) Load(key []byte) []byte {
+	value, _ := h.state.AccountStorage(h.address, hex.EncodeToString(key))
+	decoded, _ := hex.DecodeString(value)
+	return decoded
+}
+
+func (h *contractHost) Store(key, value []byte) {
+	h.state.SetAccountStorage(h.address, hex.EncodeToString(key), hex.EncodeToString(value))
+}
+
+func (h *contractHost) Balance(address string) uint64 {
+	return h.state.Account(address).Balance
+}
+
+func (h *contractHost) Transfer(to string, amount uint64) error {
+	return transfer(h.state, h.address, to, amount)
+}
+
+// ErrNotContract is returned when an address holds no contract.
+var ErrNotContract = errors.New("not a contract")
+
+// Contract returns the account and code of the contract at address.
+// Returns ErrNotContract if there is no contract at address.
+func (bc *Blockchain) Contract(address string) (ContractInfo, error) {
+	info := bc.Account(address)
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	code, ok := bc.State.Get(codePrefix + address)
+	if !ok {
+		return ContractInfo{}, ErrNotContract
+	}
+	return ContractInfo{AccountInfo: info, Code: code}, nil
+}
+
+// CallContract runs the contract at address as if caller called it with args in the next block, and returns
+// the result without changing the state. It lets clients read a contract and estimate the gas a call needs.
+// The call runs on an overlay of the state, under the chain's read lock, so its changes never reach the chain.
+// Returns ErrNotContract if there is no contract at address.
+func (bc *Blockchain) CallContract(address, caller string, args [][]byte, gas uint64) (*vm.Result, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	code := contractCode(bc.State, address)
+	if code == nil {
+		return nil, ErrNotContract
+	}
+	ctx := &vm.Context{
+		Address:   address,
+		Caller:    caller,
+		Height:    len(bc.Blocks),
+		Timestamp: bc.nextTimestamp(),
+		Args:      args,
+		Gas:       min(gas, MaxGasPerTransaction),
+	}
+	return vm.Execute(code, ctx, &contractHost{state: newOverlay(bc.State), address: address})
+}
@@ -0,0 +1,176 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// issuanceKey is the state key recording the issuance schedule of a chain with block rewards.
+const issuanceKey = "meta/issuance"
+
+// issuanceMarker separates the issuance schedule, as JSON, from the rest of the genesis block's data. Like the
+// ledger mode, the schedule is committed to the genesis hash, so that chains with different schedules never
+// share a genesis block.
+const issuanceMarker = " issuance "
+
+// IssuanceSchedule sets the reward minted by the coinbase transaction of each block. It is fixed by the genesis
+// block. The zero schedule mints nothing, so block producers earn only the fees.
+type IssuanceSchedule struct {
+	InitialReward   uint64 `json:"initial_reward"`   // Reward of the blocks at height 1 and up.
+	HalvingInterval int    `json:"halving_interval"` // The reward halves every this many blocks; zero never halves it.
+}
+
+// Reward returns the reward of the block at height. The genesis block has no reward.
+func (s IssuanceSchedule) Reward(height int) uint64 {
+	if height <= 0 {
+		return 0
+	}
+	if s.HalvingInterval <= 0 {
+		return s.InitialReward
+	}
+	halvings := (height - 1) / s.HalvingInterval
+	if halvings >= 64 {
+		return 0
+	}
+	return s.InitialReward >> halvings
+}
+
+// Supply returns the most the blocks up to and including height may mint: the sum of their rewards.
+func (s IssuanceSchedule) Supply(height int) uint64 {
+	var supply uint64
+	for start := 1; start <= height; {
+		reward := s.Reward(start)
+		if reward == 0 {
+			break
+		}
+		end := height
+		if s.HalvingInterval > 0 {
+			end = min(height, start+s.HalvingInterval-1)
+		}
+		blocks := uint64(end - start + 1)
+		if reward > (math.MaxUint64-supply)/blocks {
+			return math.MaxUint64
+		}
+		supply += reward * blocks
+		start = end + 1
+	}
+	return supply
+}
+
+// genesisData returns the data of a genesis block with the schedule appended.
+func (s IssuanceSchedule) genesisData(data string) string {
+	if s == (IssuanceSchedule{}) {
+		return data
+	}
+	encoded, _ := json.Marshal(s)
+	return data + issuanceMarker + string(encoded)
+}
+
+// parseGenesisData splits the data of a genesis block into its base data and issuance schedule.
+func parseGenesisData(data string) (string, IssuanceSchedule, error) {
+	var schedule IssuanceSchedule
+	base, encoded, found := strings.Cut(data, issuanceMarker)
+	if !found {
+		return data, schedule, nil
+	}
+	if err := json.Unmarshal([]byte(encoded), &schedule); err != nil {
+		return "", schedule, fmt.Errorf("%w: malformed issuance schedule: %v", ErrInvalidTransaction, err)
+	}
+	return base, schedule, nil
+}
+
+// issuance returns the issuance schedule recorded in the state.
+func issuance(state *State) IssuanceSchedule {
+	var schedule IssuanceSchedule
+	if value, ok := state.Get(issuanceKey); ok {
+		json.Unmarshal([]byte(value), &schedule)
+	}
+	return schedule
+}
+
+// IsCoinbase reports whether tx is a coinbase transaction, which pays the block reward and fees to the block's
+// producer.
+func (tx *Transaction) IsCoinbase() bool {
+	return tx.Coinbase != 0
+}
+
+// value returns the amount a transaction pays: its amount in account mode or the sum of its outputs in UTXO
+// mode.
+func (tx *Transaction) value() (uint64, error) {
+	value := tx.Amount
+	for _, output := range tx.Outputs {
+		if value > math.MaxUint64-output.Amount {
+			return 0, fmt.Errorf("%w: output amounts overflow", ErrInvalidTransaction)
+		}
+		value += output.Amount
+	}
+	return value, nil
+}
+
+// checkCoinbase checks the coinbase transaction of the block at height, if it has one. The coinbase must be the
+// first transaction, name the block's height and pay no more than the block's reward plus the fees of the
+// other transactions.
+func checkCoinbase(state *State, block *Block, height int) error {
+	var fees uint64
+	for i, tx := range block.Transactions {
+		if tx.IsCoinbase() && i != 0 {
+			return fmt.Errorf("transaction %d (%s): %w: only the first transaction may be a coinbase", i, tx.ID(), ErrInvalidTransaction)
+		}
+		if fees > math.MaxUint64-tx.Fee {
+			return fmt.Errorf("%w: fees overflow", ErrInvalidTransaction)
+		}
+		fees += tx.Fee
+	}
+	if len(block.Transactions) == 0 || !block.Transactions[0].IsCoinbase() {
+		return nil
+	}
+
+	coinbase := block.Transactions[0]
+	if coinbase.Coinbase != height {
+		return fmt.Errorf("%w: coinbase names height %d in the block at height %d", ErrInvalidTransaction, coinbase.Coinbase, height)
+	}
+	if coinbase.From != "" || len(coinbase.Inputs) > 0 || coinbase.Fee != 0 {
+		return fmt.Errorf("%w: a coinbase has no sender, inputs or fee", ErrInvalidTransaction)
+	}
+	value, err := coinbase.value()
+	if err != nil {
+		return err
+	}
+	reward := issuance(state).Reward(height)
+	if value > reward && value-reward > fees {
+		return fmt.Errorf("%w: coinbase pays %d, more than the reward of %d plus fees of %d", ErrInvalidTransaction, value, reward, fees)
+	}
+	return nil
+}
+
+// Issuance returns the chain's issuance schedule.
+func (bc *Blockchain) Issuance() IssuanceSchedule {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return issuance(bc.State)
+}
+
+// Coinbase returns the coinbase transaction paying producer the reward of the next block plus the fees of txs,
+// which are the other transactions of the block. Returns nil if there is nothing to pay.
+func (bc *Blockchain) Coinbase(producer string, txs []*Transaction) *Transaction {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	height := len(bc.Blocks)
+	value := issuance(bc.State).Reward(height)
+	for _, tx := range txs {
+		if value > math.MaxUint64-tx.Fee {
+			return nil
+		}
+		value += tx.Fee
+	}
+	if value == 0 {
+		return nil
+	}
+	if ledgerMode(bc.State) == LedgerUTXO {
+		return &Transaction{Coinbase: height, Outputs: []TxOutput{{Address: producer, Amount: value}}}
+	}
+	return &Transaction{Coinbase: height, To: producer, Amount: value}
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import "sort"
+
+// Genesis describes the genesis block of a chain.
+type Genesis struct {
+	Timestamp int64             // Creation time of the genesis block, in Unix milliseconds.
+	Ledger    LedgerMode        // How transactions are applied; LedgerAccount if empty.
+	Issuance  IssuanceSchedule  // Block rewards; none if zero.
+	Alloc     map[string]uint64 // Initial balances by address, allocated by the genesis block's transactions.
+}
+
+// DefaultGenesis is the genesis of the public chain; its block hash is pinned by DefaultCheckpoints.
+var DefaultGenesis = &Genesis{Timestamp: GenesisTimestamp}
+
+// Block builds the genesis block, with one allocation transaction per address in address order.
+func (g *Genesis) Block() *Block {
+	addresses := make([]string, 0, len(g.Alloc))
+	for address := range g.Alloc {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	data := "Genesis Block"
+	var txs []*Transaction
+	for _, address := range addresses {
+		if g.Ledger == LedgerUTXO {
+			txs = append(txs, &Transaction{Outputs: []TxOutput{{Address: address, Amount: g.Alloc[address]}}})
+		} else {
+			txs = append(txs, &Transaction{To: address, Amount: g.Alloc[address]})
+		}
+	}
+	if g.Ledger == LedgerUTXO {
+		data = utxoGenesisData
+	}
+	data = g.Issuance.genesisData(data)
+
+	block := &Block{
+		Timestamp:    g.Timestamp,
+		Data:         data,
+		Transactions: txs,
+		DataHash:     calculateDataHash(data),
+		TxRoot:       calculateTxRoot(txs),
+		PreviousHash: "0xGENESIS",
+	}
+	state := NewState()
+	state.applyBlock(DefaultStateModules, block, 0)
+	block.StateRoot = state.Root()
+	block.Hash = block.calculateHash()
+	return block
+}
+
+// NewBlockchainFromGenesis initializes a new blockchain with the genesis block described by g.
+func NewBlockchainFromGenesis(g *Genesis) *Blockchain {
+	bc := newBlockchain()
+	genesis := g.Block()
+	bc.Checkpoints = genesisCheckpoints(genesis)
+	bc.connectGenesis(genesis)
+	return bc
+}
+
+// genesisCheckpoints returns the checkpoints of a chain starting with the given genesis block.
+// A chain with a custom genesis, e.g. a private network or a test, is checkpointed at its own genesis block
+// instead of the default checkpoints.
+func genesisCheckpoints(genesis *Block) []Checkpoint {
+	if len(DefaultCheckpoints) > 0 && DefaultCheckpoints[0].Hash == genesis.Hash {
+		return append([]Checkpoint(nil), DefaultCheckpoints...)
+	}
+	return []Checkpoint{{Height: 0, Hash: genesis.Hash}}
+}
+
+// connectGenesis applies the genesis block to the empty state and makes it the first block.
+func (bc *Blockchain) connectGenesis(genesis *Block) {
+	undo, _ := bc.State.applyBlock(bc.StateModules, genesis, 0)
+	bc.Blocks = []*Block{genesis}
+	bc.undo = [][]StateChange{undo}
+}
@@ -0,0 +1,127 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LedgerMode selects how transactions are applied to the state. It is fixed by the genesis block.
+type LedgerMode string
+
+const (
+	LedgerAccount LedgerMode = "account" // Transactions transfer balances between accounts; see AccountModule.
+	LedgerUTXO    LedgerMode = "utxo"    // Transactions spend and create unspent outputs; see UTXOModule.
+)
+
+// ledgerKey is the state key recording a non-default ledger mode.
+const ledgerKey = "meta/ledger"
+
+// utxoGenesisData is the data of the genesis block of a UTXO chain. It commits the ledger mode to the genesis
+// hash, so that chains in different modes never share a genesis block.
+const utxoGenesisData = "Genesis Block (UTXO ledger)"
+
+// ParseLedgerMode converts a ledger mode name to a LedgerMode; the empty string selects LedgerAccount.
+func ParseLedgerMode(name string) (LedgerMode, error) {
+	switch LedgerMode(name) {
+	case "", LedgerAccount:
+		return LedgerAccount, nil
+	case LedgerUTXO:
+		return LedgerUTXO, nil
+	}
+	return "", fmt.Errorf("unknown ledger mode %q", name)
+}
+
+// ParseAlloc parses genesis allocations in the form "address:amount,address:amount".
+// An empty string yields no allocations.
+func ParseAlloc(s string) (map[string]uint64, error) {
+	alloc := make(map[string]uint64)
+	if strings.TrimSpace(s) == "" {
+		return alloc, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		address, amountStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || address == "" {
+			return nil, fmt.Errorf("invalid allocation %q: expected address:amount", pair)
+		}
+		if !IsKeyAddress(address) {
+			return nil, fmt.Errorf("invalid allocation %q: %s is not a key-derived address", pair, address)
+		}
+		amount, err := strconv.ParseUint(amountStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount in allocation %q: %w", pair, err)
+		}
+		alloc[address] = amount
+	}
+	return alloc, nil
+}
+
+// LedgerModule applies a block's transactions in the chain's ledger mode. The genesis block selects the mode
+// and the issuance schedule.
+func LedgerModule(state *State, block *Block, height int) error {
+	if height == 0 {
+		data, schedule, err := parseGenesisData(block.Data)
+		if err != nil {
+			return err
+		}
+		if data == utxoGenesisData {
+			state.Set(ledgerKey, string(LedgerUTXO))
+		}
+		if schedule != (IssuanceSchedule{}) {
+			value, _ := json.Marshal(schedule)
+			state.Set(issuanceKey, string(value))
+		}
+	}
+	if err := checkCoinbase(state, block, height); err != nil {
+		return err
+	}
+	if err := checkLocks(block, height); err != nil {
+		return err
+	}
+	if err := checkGas(block); err != nil {
+		return err
+	}
+	if ledgerMode(state) == LedgerUTXO {
+		return UTXOModule(state, block, height)
+	}
+	return AccountModule(state, block, height)
+}
+
+// ledgerMode returns the ledger mode recorded in the state.
+func ledgerMode(state *State) LedgerMode {
+	if mode, ok := state.Get(ledgerKey); ok {
+		return LedgerMode(mode)
+	}
+	return LedgerAccount
+}
+
+// Ledger returns the chain's ledger mode.
+func (bc *Blockchain) Ledger() LedgerMode {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return ledgerMode(bc.State)
+}
+
+// CheckTransactions reports whether txs could be included, in order, in the next block, without changing the
+// chain. It is used to validate transactions before they are admitted to a mempool, so coinbase transactions,
+// which only a block's producer may add, are rejected.
+func (bc *Blockchain) CheckTransactions(txs []*Transaction) error {
+	for i, tx := range txs {
+		if tx.IsCoinbase() {
+			return fmt.Errorf("transaction %d (%s): %w: a coinbase cannot be submitted", i, tx.ID(), ErrInvalidTransaction)
+		}
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	// Apply the transactions as a block would and undo them straight away.
+	block := &Block{Timestamp: bc.nextTimestamp(), Transactions: txs}
+	undo, err := bc.State.applyBlock(bc.StateModules, block, len(bc.Blocks))
+	if err != nil {
+		return err
+	}
+	bc.State.revert(undo)
+	return nil
+}
@@ -0,0 +1,112 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// MaxPayloadSize is the maximum size in bytes of a block's Data.
+	MaxPayloadSize = 64 << 10
+
+	// MaxBlockSize is the maximum size in bytes of a JSON-encoded block.
+	MaxBlockSize = 1 << 20
+
+	// MaxBlockTransactions is the maximum number of transactions in a block, including its coinbase.
+	MaxBlockTransactions = 2000
+)
+
+var (
+	// ErrPayloadTooLarge is returned when block data exceeds MaxPayloadSize.
+	ErrPayloadTooLarge = errors.New("payload too large")
+
+	// ErrBlockTooLarge is returned when an encoded block exceeds MaxBlockSize.
+	ErrBlockTooLarge = errors.New("block too large")
+
+	// ErrTooManyTransactions is returned when a block holds more than MaxBlockTransactions transactions.
+	ErrTooManyTransactions = errors.New("too many transactions")
+)
+
+// Size returns the size in bytes of the block's JSON encoding, the form used by the API and the P2P layer.
+func (b *Block) Size() int {
+	encoded, err := json.Marshal(b)
+	if err != nil {
+		return 0
+	}
+	return len(encoded)
+}
+
+// Size returns the size in bytes of the transaction's JSON encoding, as part of a block.
+func (tx *Transaction) Size() int {
+	encoded, err := json.Marshal(tx)
+	if err != nil {
+		return 0
+	}
+	return len(encoded)
+}
+
+// checkTransactionCount returns an error if a block with n transactions breaks the transaction count limit.
+func checkTransactionCount(n int) error {
+	if n > MaxBlockTransactions {
+		return fmt.Errorf("%w: %d exceeds %d", ErrTooManyTransactions, n, MaxBlockTransactions)
+	}
+	return nil
+}
+
+// checkSize returns an error if the block breaks the transaction count, payload or block size limits.
+func (b *Block) checkSize() error {
+	if err := checkTransactionCount(len(b.Transactions)); err != nil {
+		return err
+	}
+	if len(b.Data) > MaxPayloadSize {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrPayloadTooLarge, len(b.Data), MaxPayloadSize)
+	}
+	if size := b.Size(); size > MaxBlockSize {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrBlockTooLarge, size, MaxBlockSize)
+	}
+	return nil
+}
+
+// SizeRule rejects blocks that exceed MaxBlockTransactions, MaxPayloadSize or MaxBlockSize.
+func SizeRule(bc *Blockchain, height int) error {
+	return bc.Blocks[height].checkSize()
+}
+
+// BlockSpace returns how many more transactions, and how many bytes of their JSON encodings, fit in the next block
+// if it carries data and txs, leaving room for a coinbase paying producer, unless producer is empty, however much
+// the added transactions pay. Each added transaction takes its encoded size plus one byte separating it from the
+// others.
+func (bc *Blockchain) BlockSpace(producer, data string, txs []*Transaction) (count, size int) {
+	if producer != "" {
+		bc.mu.RLock()
+		coinbase := &Transaction{Coinbase: len(bc.Blocks), To: producer, Amount: math.MaxUint64}
+		if ledgerMode(bc.State) == LedgerUTXO {
+			coinbase = &Transaction{Coinbase: len(bc.Blocks), Outputs: []TxOutput{{Address: producer, Amount: math.MaxUint64}}}
+		}
+		bc.mu.RUnlock()
+		txs = append([]*Transaction{coinbase}, txs...)
+	}
+
+	// Size the block with the longest header it could have.
+	hash := strings.Repeat("0", 2*sha256.Size)
+	block := &Block{
+		Timestamp:    math.MaxInt64,
+		Data:         data,
+		Transactions: txs,
+		DataHash:     hash,
+		TxRoot:       hash,
+		PreviousHash: hash,
+		StateRoot:    hash,
+		LogsBloom:    strings.Repeat("0", 2*BloomSize),
+		Hash:         hash,
+	}
+	used := block.Size()
+	if len(txs) == 0 {
+		used += len(`,"Transactions":[]`)
+	}
+	return MaxBlockTransactions - len(txs), MaxBlockSize - used
+}
@@ -0,0 +1,44 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLocked is returned (possibly wrapped) when a transaction is included in a block before its lock height or
+// lock time.
+var ErrLocked = errors.New("transaction locked")
+
+// Unlocked reports whether tx may be included in a block at height with the given timestamp, in Unix
+// milliseconds: the height must be at least its LockHeight and the timestamp at least its LockTime.
+func (tx *Transaction) Unlocked(height int, timestamp int64) bool {
+	return height >= tx.LockHeight && timestamp >= tx.LockTime
+}
+
+// checkLocks rejects a block that includes a transaction before its lock height or lock time. Lock times are
+// compared with the block's timestamp, which FutureDriftRule keeps from running ahead of network time.
+func checkLocks(block *Block, height int) error {
+	for i, tx := range block.Transactions {
+		if !tx.Unlocked(height, block.Timestamp) {
+			return fmt.Errorf("transaction %d (%s): %w: locked until height %d and time %d, included at height %d and time %d",
+				i, tx.ID(), ErrLocked, tx.LockHeight, tx.LockTime, height, block.Timestamp)
+		}
+	}
+	return nil
+}
+
+// Unlocked reports whether tx may be included in the next block appended to the chain now.
+func (bc *Blockchain) Unlocked(tx *Transaction) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return tx.Unlocked(len(bc.Blocks), bc.nextTimestamp())
+}
+
+// LockedBeyond reports whether tx is locked until more than blocks blocks after the next one, or more than d
+// after the next block's timestamp.
+func (bc *Blockchain) LockedBeyond(tx *Transaction, blocks int, d time.Duration) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return tx.LockHeight > len(bc.Blocks)+blocks || tx.LockTime > bc.nextTimestamp()+d.Milliseconds()
+}
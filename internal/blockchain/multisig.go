@@ -0,0 +1,169 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"blockchain/internal/crypto"
+)
+
+// MaxMultisigSigners is the largest number of signers of a multisig policy.
+const MaxMultisigSigners = 16
+
+// multisigAlgorithm separates multisig addresses from the addresses of public keys in crypto.DeriveAddress.
+const multisigAlgorithm crypto.Algorithm = "multisig"
+
+// ErrInvalidMultisig is returned (possibly wrapped) for a malformed multisig policy.
+var ErrInvalidMultisig = errors.New("invalid multisig policy")
+
+// MultisigPolicy is an M-of-N policy: funds of its address can be spent only by a transaction that carries the
+// policy and valid signatures of at least Threshold of its Signers.
+//
+// A partially-signed transaction is an ordinary transaction carrying the policy and the signatures collected
+// so far. Signatures cover the SigningHash, which leaves out the signatures, so signers can sign copies of the
+// transaction independently and CombineSignatures merges them.
+type MultisigPolicy struct {
+	Threshold int      `json:"threshold"` // M: the number of signatures required.
+	Signers   []string `json:"signers"`   // N: the key-derived addresses allowed to sign, in ascending order.
+}
+
+// NewMultisigPolicy returns the policy requiring threshold signatures of the given signer addresses, which are
+// sorted into canonical order.
+func NewMultisigPolicy(threshold int, signers []string) (*MultisigPolicy, error) {
+	policy := &MultisigPolicy{Threshold: threshold, Signers: append([]string(nil), signers...)}
+	sort.Strings(policy.Signers)
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate checks that the policy has between 1 and MaxMultisigSigners distinct key-derived signers in ascending
+// order and a threshold between 1 and the number of signers. The order makes the encoding, and so the
+// address, of a policy unique.
+func (p *MultisigPolicy) Validate() error {
+	if len(p.Signers) == 0 || len(p.Signers) > MaxMultisigSigners {
+		return fmt.Errorf("%w: %d signers, want 1 to %d", ErrInvalidMultisig, len(p.Signers), MaxMultisigSigners)
+	}
+	if p.Threshold < 1 || p.Threshold > len(p.Signers) {
+		return fmt.Errorf("%w: threshold %d, want 1 to %d", ErrInvalidMultisig, p.Threshold, len(p.Signers))
+	}
+	for i, signer := range p.Signers {
+		if !IsKeyAddress(signer) {
+			return fmt.Errorf("%w: signer %q is not a key-derived address", ErrInvalidMultisig, signer)
+		}
+		if i > 0 && p.Signers[i-1] >= signer {
+			return fmt.Errorf("%w: signers are not distinct and in ascending order", ErrInvalidMultisig)
+		}
+	}
+	return nil
+}
+
+// Address returns the multisig address of the policy, which has the form of a key-derived address.
+func (p *MultisigPolicy) Address() string {
+	var encoded bytes.Buffer
+	encoded.WriteString(strconv.Itoa(p.Threshold))
+	for _, signer := range p.Signers {
+		encoded.WriteByte(',')
+		encoded.WriteString(signer)
+	}
+	return crypto.DeriveAddress(multisigAlgorithm, encoded.Bytes())
+}
+
+// signed returns the number of the policy's signers among signers.
+func (p *MultisigPolicy) signed(signers map[string]bool) int {
+	count := 0
+	for _, signer := range p.Signers {
+		if signers[signer] {
+			count++
+		}
+	}
+	return count
+}
+
+// multisigPolicies validates the multisig policies of the transaction and returns them by address.
+func (tx *Transaction) multisigPolicies() (map[string]*MultisigPolicy, error) {
+	policies := make(map[string]*MultisigPolicy, len(tx.Multisig))
+	for i := range tx.Multisig {
+		policy := &tx.Multisig[i]
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: multisig policy %d: %v", ErrInvalidTransaction, i, err)
+		}
+		address := policy.Address()
+		if policies[address] != nil {
+			return nil, fmt.Errorf("%w: multisig policy of %s attached twice", ErrInvalidTransaction, address)
+		}
+		policies[address] = policy
+	}
+	return policies, nil
+}
+
+// MultisigStatus reports how far a multisig address of a transaction is from being authorized.
+type MultisigStatus struct {
+	Address   string   `json:"address"`
+	Threshold int      `json:"threshold"`
+	Signed    []string `json:"signed"`   // Signers of the policy that have signed.
+	Missing   []string `json:"missing"`  // Signers of the policy that have not signed.
+	Complete  bool     `json:"complete"` // Whether at least Threshold signers have signed.
+}
+
+// MultisigStatus verifies the signatures of the transaction and returns the status of each of its multisig
+// policies.
+func (tx *Transaction) MultisigStatus() ([]MultisigStatus, error) {
+	signers, err := tx.Signers()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.multisigPolicies(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MultisigStatus, 0, len(tx.Multisig))
+	for _, policy := range tx.Multisig {
+		status := MultisigStatus{Address: policy.Address(), Threshold: policy.Threshold, Signed: []string{}, Missing: []string{}}
+		for _, signer := range policy.Signers {
+			if signers[signer] {
+				status.Signed = append(status.Signed, signer)
+			} else {
+				status.Missing = append(status.Missing, signer)
+			}
+		}
+		status.Complete = len(status.Signed) >= policy.Threshold
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CombineSignatures merges copies of a partially-signed transaction into one transaction holding every
+// distinct valid signature, in the order first seen.
+// Returns an error wrapping ErrInvalidTransaction if the copies differ in anything but their signatures, or
+// ErrUnauthorized if a signature is invalid.
+func CombineSignatures(txs []*Transaction) (*Transaction, error) {
+	if len(txs) == 0 {
+		return nil, fmt.Errorf("%w: no transactions to combine", ErrInvalidTransaction)
+	}
+	combined := *txs[0]
+	combined.Signatures = nil
+	hash := combined.SigningHash()
+
+	seen := make(map[string]bool)
+	for i, tx := range txs {
+		if !bytes.Equal(tx.SigningHash(), hash) {
+			return nil, fmt.Errorf("%w: transaction %d is not a copy of the first", ErrInvalidTransaction, i)
+		}
+		for j := range tx.Signatures {
+			address, err := tx.Signatures[j].verify(hash)
+			if err != nil {
+				return nil, fmt.Errorf("%w: transaction %d: signature %d: %v", ErrUnauthorized, i, j, err)
+			}
+			if !seen[address] {
+				seen[address] = true
+				combined.Signatures = append(combined.Signatures, tx.Signatures[j])
+			}
+		}
+	}
+	return &combined, nil
+}
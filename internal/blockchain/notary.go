@@ -0,0 +1,120 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"blockchain/pkg/merkle"
+)
+
+// notaryPrefix is the state key prefix of the notary index: "notary/<document hash>" holds the notaryEntry of
+// the transaction that notarized the document.
+const notaryPrefix = "notary/"
+
+// MaxNotaryFieldLength is the longest name, content type or note a notarization may have, in bytes.
+const MaxNotaryFieldLength = 256
+
+// ErrNotNotarized is returned (possibly wrapped) for a document hash that has not been notarized.
+var ErrNotNotarized = errors.New("not notarized")
+
+// Notarization is the notary part of a transaction: the digest of a document, and optionally what it is,
+// recorded to prove that the document existed when the block was made. The document itself is never stored.
+type Notarization struct {
+	Hash        string `json:"hash"`                   // Lower-case hex-encoded SHA-256 hash of the document.
+	Name        string `json:"name,omitempty"`         // File name of the document.
+	ContentType string `json:"content_type,omitempty"` // MIME type of the document.
+	Size        int64  `json:"size,omitempty"`         // Size of the document in bytes; zero if unknown.
+	Note        string `json:"note,omitempty"`         // Free-form description.
+}
+
+// NotaryRecord is a notarization together with the block that recorded it and the proof of its inclusion,
+// which a client can check with merkle.VerifyTxProof knowing only the block's hash.
+type NotaryRecord struct {
+	Notarization
+	TxID      string          `json:"txid"`
+	Height    int             `json:"height"`
+	BlockHash string          `json:"block_hash"`
+	Timestamp int64           `json:"timestamp"` // Timestamp of the block, in Unix milliseconds.
+	Proof     *merkle.TxProof `json:"proof"`
+}
+
+// notaryEntry locates the transaction that notarized a document.
+type notaryEntry struct {
+	TxID   string `json:"txid"`
+	Height int    `json:"height"`
+}
+
+// Validate checks the fields of the notarization.
+func (n *Notarization) Validate() error {
+	if hash, err := hex.DecodeString(n.Hash); err != nil || len(hash) != 32 || strings.ToLower(n.Hash) != n.Hash {
+		return fmt.Errorf("%w: document hash must be a lower-case hex-encoded SHA-256 hash", ErrInvalidTransaction)
+	}
+	for name, value := range map[string]string{"name": n.Name, "content type": n.ContentType, "note": n.Note} {
+		if len(value) > MaxNotaryFieldLength {
+			return fmt.Errorf("%w: %s of %d bytes exceeds %d", ErrInvalidTransaction, name, len(value), MaxNotaryFieldLength)
+		}
+	}
+	if n.Size < 0 {
+		return fmt.Errorf("%w: negative document size", ErrInvalidTransaction)
+	}
+	return nil
+}
+
+// applyNotarization applies a transaction carrying a notarization, in either ledger mode. It has no sender and
+// pays no fee, so it may carry nothing but the notarization. Each document can be notarized once: the first
+// notarization is the one that proves when it existed.
+func applyNotarization(state *State, tx *Transaction, height int) error {
+	if bare := (&Transaction{Notarization: tx.Notarization}); tx.ID() != bare.ID() {
+		return fmt.Errorf("%w: a notarization carries no other fields", ErrInvalidTransaction)
+	}
+	if err := tx.Notarization.Validate(); err != nil {
+		return err
+	}
+	key := notaryPrefix + tx.Notarization.Hash
+	if value, ok := state.Get(key); ok {
+		var entry notaryEntry
+		json.Unmarshal([]byte(value), &entry)
+		return fmt.Errorf("%w: document %s was already notarized at height %d", ErrInvalidTransaction, tx.Notarization.Hash, entry.Height)
+	}
+	value, _ := json.Marshal(notaryEntry{TxID: tx.ID(), Height: height})
+	state.Set(key, string(value))
+	return nil
+}
+
+// Notarized returns the notarization of the document with the given hash, with the block that recorded it and
+// the proof of its inclusion.
+// Returns ErrNotNotarized if the document has not been notarized, or an error wrapping ErrPruned if the
+// block's body has been pruned.
+func (bc *Blockchain) Notarized(hash string) (*NotaryRecord, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	value, ok := bc.State.Get(notaryPrefix + hash)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotNotarized, hash)
+	}
+	var entry notaryEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, err
+	}
+	block := bc.Blocks[entry.Height]
+	if block.Pruned {
+		return nil, fmt.Errorf("%w: block %d notarized %s", ErrPruned, entry.Height, hash)
+	}
+	for index, tx := range block.Transactions {
+		if tx.ID() == entry.TxID {
+			return &NotaryRecord{
+				Notarization: *tx.Notarization,
+				TxID:         entry.TxID,
+				Height:       entry.Height,
+				BlockHash:    block.Hash,
+				Timestamp:    block.Timestamp,
+				Proof:        txProof(block, entry.Height, index),
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("notarization %s not found in block %d", entry.TxID, entry.Height)
+}
@@ -0,0 +1,69 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTipChanged is returned by PendingState.Apply when a block has been connected or disconnected since the
+// PendingState was created.
+var ErrTipChanged = errors.New("chain tip changed")
+
+// PendingState is the state of the next block, built up one transaction at a time on top of the chain's tip
+// without changing the chain. Each transaction is applied once, on top of those applied before it, and only a
+// transaction that fails is undone, so a mempool can validate n transactions with n applications.
+// The chain is read-locked for each application only, so blocks can be connected in between; once one is, the
+// PendingState is stale and a new one must be created.
+// A PendingState is not safe for concurrent use.
+type PendingState struct {
+	bc     *Blockchain
+	state  *State // Overlay of the chain's state holding the changes of the applied transactions.
+	block  *Block // Block the transactions are applied in, one at a time.
+	height int
+	tip    string // Hash of the block the state builds on.
+	gas    uint64 // Gas limits of the applied transactions.
+}
+
+// NewPendingState creates a PendingState on top of the chain's tip, with no transactions applied.
+func (bc *Blockchain) NewPendingState() *PendingState {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return &PendingState{
+		bc:     bc,
+		state:  newOverlay(bc.State),
+		block:  &Block{},
+		height: len(bc.Blocks),
+		tip:    bc.Blocks[len(bc.Blocks)-1].Hash,
+	}
+}
+
+// Apply applies tx on top of the transactions applied so far, checking it as the next block would. If tx is
+// invalid there, its changes are undone and the others are kept.
+// Returns:
+// - An error wrapping ErrTipChanged if the chain's tip has changed since the PendingState was created.
+// - An error wrapping ErrInvalidTransaction if tx is a coinbase, which only a block's producer may add, or if
+// the applied transactions would exceed the block's gas limit.
+// - An error wrapping ErrLocked, ErrInvalidTransaction or another state module error if tx is invalid.
+func (p *PendingState) Apply(tx *Transaction) error {
+	if tx.IsCoinbase() {
+		return fmt.Errorf("transaction %s: %w: a coinbase cannot be submitted", tx.ID(), ErrInvalidTransaction)
+	}
+	if tx.GasLimit > MaxGasPerBlock-p.gas {
+		return fmt.Errorf("transaction %s: %w: gas limit %d exceeds the %d left in the block", tx.ID(), ErrInvalidTransaction, tx.GasLimit, MaxGasPerBlock-p.gas)
+	}
+
+	p.bc.mu.RLock()
+	defer p.bc.mu.RUnlock()
+
+	if len(p.bc.Blocks) != p.height || p.bc.Blocks[p.height-1].Hash != p.tip {
+		return fmt.Errorf("%w: pending state built on block %d (%s)", ErrTipChanged, p.height-1, p.tip)
+	}
+	// Stamp each transaction's block like the next block would be now, so that lock times pass as time goes by.
+	p.block.Timestamp = p.bc.nextTimestamp()
+	p.block.Transactions = []*Transaction{tx}
+	if _, err := p.state.applyBlock(p.bc.StateModules, p.block, p.height); err != nil {
+		return err
+	}
+	p.gas += tx.GasLimit
+	return nil
+}
@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"blockchain/pkg/merkle"
+)
+
+// ErrTxNotFound is returned by ProveTransaction when no block with a body includes the transaction.
+var ErrTxNotFound = errors.New("transaction not found")
+
+// ProveAccount returns a proof of the state of the account at address against the state root of the block
+// at height; a negative height selects the tip. The state of an earlier block is rebuilt by undoing the
+// blocks above it.
+// Returns an error wrapping ErrPruned if the undo data needed for height has been discarded, or an error if
+// height is out of range.
+func (bc *Blockchain) ProveAccount(address string, height int) (*merkle.AccountProof, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	tip := len(bc.Blocks) - 1
+	if height < 0 {
+		height = tip
+	}
+	if height > tip {
+		return nil, fmt.Errorf("height %d out of range", height)
+	}
+	if height < tip && height+1 < bc.prunedHeight {
+		return nil, fmt.Errorf("%w: the state at height %d can no longer be rebuilt", ErrPruned, height)
+	}
+
+	state := bc.State
+	if height < tip {
+		state = NewState()
+		for key, value := range bc.State.entries {
+			state.entries[key] = value
+		}
+		for h := tip; h > height; h-- {
+			state.revert(bc.undo[h])
+		}
+	}
+
+	key := accountPrefix + address
+	value, exists := state.Get(key)
+	block := bc.Blocks[height]
+	return &merkle.AccountProof{
+		Address:   address,
+		Height:    height,
+		BlockHash: block.Hash,
+		StateRoot: block.StateRoot,
+		Exists:    exists,
+		Value:     value,
+		Proof:     *merkle.NewSparseTree(state.entries).Prove(key),
+	}, nil
+}
+
+// ProveTransaction returns a proof that the transaction with the given ID is included in a block, which a
+// client can check with merkle.VerifyTxProof knowing only the block's hash. Blocks are searched from the tip
+// down; transactions in pruned blocks cannot be proven.
+// Returns an error wrapping ErrTxNotFound if no block with a body includes the transaction.
+func (bc *Blockchain) ProveTransaction(id string) (*merkle.TxProof, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	for height := len(bc.Blocks) - 1; height >= bc.prunedHeight; height-- {
+		block := bc.Blocks[height]
+		for index, tx := range block.Transactions {
+			if tx.ID() == id {
+				return txProof(block, height, index), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTxNotFound, id)
+}
+
+// txProof returns the proof that the transaction at index is included in block, at height.
+func txProof(block *Block, height, index int) *merkle.TxProof {
+	leaves := make([][]byte, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		leaves[i], _ = hex.DecodeString(tx.ID())
+	}
+	siblings := []string{}
+	for _, sibling := range merkle.Path(leaves, index) {
+		siblings = append(siblings, hex.EncodeToString(sibling))
+	}
+	return &merkle.TxProof{
+		TxID:     block.Transactions[index].ID(),
+		Height:   height,
+		Index:    index,
+		Count:    len(block.Transactions),
+		Siblings: siblings,
+		Header: merkle.BlockHeader{
+			Timestamp:    block.Timestamp,
+			DataHash:     block.DataHash,
+			TxRoot:       block.TxRoot,
+			PreviousHash: block.PreviousHash,
+			StateRoot:    block.StateRoot,
+			LogsBloom:    block.LogsBloom,
+			Hash:         block.Hash,
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrPruned is returned when a block body has been discarded by pruning and only its header is kept.
+var ErrPruned = errors.New("block body pruned")
+
+// Prune discards the bodies of blocks more than PruneDepth blocks below the tip, keeping their headers.
+// It does nothing if PruneDepth is zero. Blocks connected later are pruned automatically.
+// Returns the number of block bodies discarded.
+func (bc *Blockchain) Prune() int {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.prune()
+}
+
+// prune implements Prune; the caller must hold bc.mu.
+func (bc *Blockchain) prune() int {
+	if bc.PruneDepth <= 0 {
+		return 0
+	}
+
+	from, pruned := bc.prunedHeight, 0
+	for height := bc.prunedHeight; height < len(bc.Blocks)-bc.PruneDepth; height++ {
+		if !bc.Blocks[height].Pruned {
+			// Replace rather than modify the block, since readers may still hold the old pointer.
+			bc.Blocks[height] = bc.Blocks[height].Header()
+			pruned++
+		}
+		bc.prunedHeight = height + 1
+	}
+	// A body left in the store is harmless, so a failed write is only logged.
+	if err := bc.persistPruned(from, bc.prunedHeight); err != nil {
+		log.Printf("Failed to store pruned blocks: %v", err)
+	}
+	return pruned
+}
+
+// PrunedHeight returns the height of the first block that still has its body; the bodies of all lower blocks
+// have been pruned. It is zero for a node holding the full history.
+func (bc *Blockchain) PrunedHeight() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.prunedHeight
+}
+
+// FullHistory reports whether the node keeps every block body, i.e. it is not pruning and has not been
+// bootstrapped from a snapshot.
+func (bc *Blockchain) FullHistory() bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.PruneDepth <= 0 && bc.prunedHeight == 0
+}
+
+// BlockBodyAt returns the block at the given height including its body.
+// Returns an error wrapping ErrPruned if only the header is kept, or an error if the height is out of range.
+func (bc *Blockchain) BlockBodyAt(height int) (*Block, error) {
+	block := bc.BlockAt(height)
+	if block == nil {
+		return nil, fmt.Errorf("height %d out of range", height)
+	}
+	if block.Pruned {
+		return nil, fmt.Errorf("%w at height %d", ErrPruned, height)
+	}
+	return block, nil
+}
@@ -0,0 +1,236 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"blockchain/internal/vm"
+)
+
+// Receipt statuses.
+const (
+	ReceiptSuccess = "success"
+	ReceiptFailed  = "failed"
+)
+
+// Receipt records the outcome of a transaction included in a block. The receipts of contract transactions are
+// stored in the state as they are applied; every other transaction in a block succeeded without using gas, so
+// its receipt is derived from the block.
+type Receipt struct {
+	TxID            string   `json:"txid"`
+	Height          int      `json:"height"`                     // Height of the block that included the transaction.
+	BlockHash       string   `json:"block_hash,omitempty"`       // Hash of that block; filled in by Blockchain.Receipt.
+	Status          string   `json:"status"`                     // ReceiptSuccess or ReceiptFailed.
+	GasUsed         uint64   `json:"gas_used"`                   // Gas used, which is at most the gas limit.
+	ContractAddress string   `json:"contract_address,omitempty"` // Deployments only: address of the new contract.
+	Return          string   `json:"return,omitempty"`           // Hex-encoded value returned by the contract.
+	Error           string   `json:"error,omitempty"`            // Why a failed transaction failed.
+	Logs            []vm.Log `json:"logs,omitempty"`             // Logs emitted by a successful call.
+}
+
+// storedReceipt returns the receipt of a contract transaction stored in the state.
+func storedReceipt(state *State, txID string) (*Receipt, bool) {
+	value, ok := state.Get(receiptPrefix + txID)
+	if !ok {
+		return nil, false
+	}
+	var receipt Receipt
+	if err := json.Unmarshal([]byte(value), &receipt); err != nil {
+		return nil, false
+	}
+	return &receipt, true
+}
+
+// Receipt returns the receipt of the transaction with the given ID, if it has been included in a block. The
+// receipts of transactions other than contract transactions are found by searching the blocks from the tip
+// down, so they are not available once the block's body has been pruned.
+func (bc *Blockchain) Receipt(txID string) (*Receipt, bool) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if receipt, ok := storedReceipt(bc.State, txID); ok {
+		receipt.BlockHash = bc.Blocks[receipt.Height].Hash
+		return receipt, true
+	}
+	for height := len(bc.Blocks) - 1; height >= bc.prunedHeight; height-- {
+		block := bc.Blocks[height]
+		for _, tx := range block.Transactions {
+			if tx.ID() == txID {
+				return &Receipt{TxID: txID, Height: height, BlockHash: block.Hash, Status: ReceiptSuccess}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// BloomSize is the size in bytes of a logs bloom.
+const BloomSize = 256
+
+// Bloom is a Bloom filter of the contract addresses and topics of the logs emitted by a block's transactions.
+// It is committed in the block header as LogsBloom, so that log queries can skip blocks that cannot match.
+// Each item sets three bits chosen by its SHA-256 hash.
+type Bloom [BloomSize]byte
+
+// bloomBits returns the positions of the bits set by item.
+func bloomBits(item []byte) [3]uint {
+	hash := sha256.Sum256(item)
+	var bits [3]uint
+	for i := range bits {
+		bits[i] = uint(binary.BigEndian.Uint16(hash[2*i:])) % (BloomSize * 8)
+	}
+	return bits
+}
+
+// Add adds item to the filter.
+func (b *Bloom) Add(item []byte) {
+	for _, bit := range bloomBits(item) {
+		b[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Test reports whether item may have been added to the filter. False positives are possible; false negatives
+// are not.
+func (b *Bloom) Test(item []byte) bool {
+	for _, bit := range bloomBits(item) {
+		if b[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// AddLog adds the address and topics of log to the filter.
+func (b *Bloom) AddLog(log vm.Log) {
+	b.Add([]byte(log.Address))
+	for _, topic := range log.Topics {
+		decoded, _ := hex.DecodeString(topic)
+		b.Add(decoded)
+	}
+}
+
+// ParseBloom decodes a hex-encoded logs bloom.
+func ParseBloom(s string) (*Bloom, error) {
+	decoded, err := hex.DecodeString(s)
+	if err != nil || len(decoded) != BloomSize {
+		return nil, fmt.Errorf("malformed logs bloom")
+	}
+	var bloom Bloom
+	copy(bloom[:], decoded)
+	return &bloom, nil
+}
+
+// logsBloom returns the hex-encoded logs bloom of a block whose transactions have just been applied to state,
+// or the empty string if they emitted no logs.
+func logsBloom(state *State, block *Block) string {
+	var bloom Bloom
+	empty := true
+	for _, tx := range block.Transactions {
+		receipt, ok := storedReceipt(state, tx.ID())
+		if !ok {
+			continue
+		}
+		for _, log := range receipt.Logs {
+			bloom.AddLog(log)
+			empty = false
+		}
+	}
+	if empty {
+		return ""
+	}
+	return hex.EncodeToString(bloom[:])
+}
+
+// logMatches reports whether log was emitted by address and has the hex-encoded topic; empty values match
+// every log.
+func logMatches(log vm.Log, address, topic string) bool {
+	if address != "" && log.Address != address {
+		return false
+	}
+	if topic == "" {
+		return true
+	}
+	for _, t := range log.Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxLogRange is the largest number of blocks a log query may search.
+const MaxLogRange = 10000
+
+// ErrInvalidFilter is returned (possibly wrapped) for a malformed log query.
+var ErrInvalidFilter = errors.New("invalid log filter")
+
+// LogFilter selects logs by block range, contract address and topic. Empty fields match every log.
+type LogFilter struct {
+	FromHeight int    // First block searched; negative searches the MaxLogRange blocks up to ToHeight.
+	ToHeight   int    // Last block searched; negative or beyond the tip searches up to the tip.
+	Address    string // Address of the contract that emitted the log.
+	Topic      string // Hex-encoded topic the log must have, at any position.
+}
+
+// LogEntry is a log together with the transaction and block that emitted it.
+type LogEntry struct {
+	vm.Log
+	TxID      string `json:"txid"`
+	Height    int    `json:"height"`
+	BlockHash string `json:"block_hash"`
+}
+
+// Logs returns the logs matching filter, oldest first. Blocks whose logs bloom rules out the address or topic
+// are skipped, as are blocks whose bodies have been pruned.
+// Returns an error wrapping ErrInvalidFilter if the range is empty or exceeds MaxLogRange, or the topic is not
+// hex-encoded.
+func (bc *Blockchain) Logs(filter LogFilter) ([]LogEntry, error) {
+	topic, err := hex.DecodeString(filter.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: topic is not hex-encoded", ErrInvalidFilter)
+	}
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	to := filter.ToHeight
+	if to < 0 || to >= len(bc.Blocks) {
+		to = len(bc.Blocks) - 1
+	}
+	from := filter.FromHeight
+	if from < 0 {
+		from = max(0, to-MaxLogRange+1)
+	}
+	if from > to || to-from+1 > MaxLogRange {
+		return nil, fmt.Errorf("%w: blocks %d to %d, want a range of 1 to %d blocks", ErrInvalidFilter, from, to, MaxLogRange)
+	}
+
+	entries := []LogEntry{}
+	for height := from; height <= to; height++ {
+		block := bc.Blocks[height]
+		if block.LogsBloom == "" || block.Pruned {
+			continue
+		}
+		bloom, err := ParseBloom(block.LogsBloom)
+		if err != nil ||
+			filter.Address != "" && !bloom.Test([]byte(filter.Address)) ||
+			filter.Topic != "" && !bloom.Test(topic) {
+			continue
+		}
+		for _, tx := range block.Transactions {
+			receipt, ok := storedReceipt(bc.State, tx.ID())
+			if !ok {
+				continue
+			}
+			for _, log := range receipt.Logs {
+				if logMatches(log, filter.Address, hex.EncodeToString(topic)) {
+					entries = append(entries, LogEntry{Log: log, TxID: receipt.TxID, Height: height, BlockHash: block.Hash})
+				}
+			}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,146 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"blockchain/internal/storage"
+)
+
+var (
+	// ErrUnknownForkPoint is returned by Reorganize when the first block does not build on a block of the chain.
+	ErrUnknownForkPoint = errors.New("fork does not connect to the chain")
+
+	// ErrForkNotLonger is returned by Reorganize when the fork would not make the chain longer.
+	ErrForkNotLonger = errors.New("fork is not longer than the chain")
+
+	// ErrForkTooDeep is returned by Reorganize when the blocks it would replace can no longer be undone,
+	// because their bodies were pruned or the chain was bootstrapped from a snapshot above the fork point.
+	ErrForkTooDeep = errors.New("fork point is below the pruned height")
+)
+
+// Reorganize switches the chain to a competing branch: the blocks above the fork point are disconnected,
+// rolling back their state changes, and the given blocks are validated and connected in their place.
+// The branch is only accepted if it makes the chain longer. If any of its blocks is invalid, the original
+// blocks are restored and the error is returned.
+// Parameters:
+// - blocks: The branch, in height order; the first block must build on a block of the chain.
+func (bc *Blockchain) Reorganize(blocks []*Block) error {
+	if len(blocks) == 0 {
+		return ErrForkNotLonger
+	}
+
+	bc.mu.Lock()
+	forkHeight := -1
+	for height := len(bc.Blocks) - 1; height >= 0; height-- {
+		if bc.Blocks[height].Hash == blocks[0].PreviousHash {
+			forkHeight = height
+			break
+		}
+	}
+
+	var err error
+	switch {
+	case forkHeight < 0:
+		err = ErrUnknownForkPoint
+	case forkHeight+1+len(blocks) <= len(bc.Blocks):
+		err = fmt.Errorf("%w: %d blocks after height %d", ErrForkNotLonger, len(blocks), forkHeight)
+	case forkHeight+1 < bc.prunedHeight:
+		err = fmt.Errorf("%w: fork at height %d, bodies kept from height %d", ErrForkTooDeep, forkHeight, bc.prunedHeight)
+	default:
+		err = bc.reorganize(forkHeight, blocks)
+	}
+	bc.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	log.Printf("Reorganized the chain at height %d to %d new blocks", forkHeight, len(blocks))
+	for i, block := range blocks {
+		bc.notify(forkHeight+1+i, block)
+	}
+	return nil
+}
+
+// reorganize replaces the blocks above forkHeight with blocks, restoring them on failure.
+// The caller must hold bc.mu.
+func (bc *Blockchain) reorganize(forkHeight int, blocks []*Block) error {
+	replaced := append([]*Block(nil), bc.Blocks[forkHeight+1:]...)
+	if err := bc.disconnectTo(forkHeight); err != nil {
+		return err
+	}
+
+	for _, block := range blocks {
+		err := bc.appendBlock(block)
+		if err == nil {
+			continue
+		}
+		// Restore the original branch; its blocks were valid before, so reconnecting them cannot fail
+		// unless the store does.
+		if restoreErr := bc.disconnectTo(forkHeight); restoreErr != nil {
+			return fmt.Errorf("%v; restoring the chain failed: %w", err, restoreErr)
+		}
+		for _, original := range replaced {
+			if restoreErr := bc.appendBlock(original); restoreErr != nil {
+				return fmt.Errorf("%v; restoring the chain failed: %w", err, restoreErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// disconnectTo disconnects blocks from the tip until the block at height is the tip.
+// The caller must hold bc.mu.
+func (bc *Blockchain) disconnectTo(height int) error {
+	for len(bc.Blocks)-1 > height {
+		if err := bc.disconnectTip(); err != nil {
+			return err
+		}
+	}
+
+	bc.statusMu.Lock()
+	if bc.verified > height+1 {
+		bc.verified = height + 1
+	}
+	bc.statusMu.Unlock()
+	return nil
+}
+
+// disconnectTip removes the last block, undoing its state changes, and writes the result to the store
+// as one atomic batch. The caller must hold bc.mu.
+func (bc *Blockchain) disconnectTip() error {
+	height := len(bc.Blocks) - 1
+	block, undo := bc.Blocks[height], bc.undo[height]
+	bc.State.revert(undo)
+
+	if bc.store != nil {
+		batch := storage.NewBatch()
+		batch.Delete(heightKey(blockKeyPrefix, height))
+		batch.Delete([]byte(hashKeyPrefix + block.Hash))
+		batch.Delete(heightKey(undoKeyPrefix, height))
+		// Write the changes newest first, so that the oldest value of a key changed twice wins.
+		for i := len(undo) - 1; i >= 0; i-- {
+			change := undo[i]
+			if change.Existed {
+				batch.Put([]byte(stateKeyPrefix+change.Key), []byte(change.Value))
+			} else {
+				batch.Delete([]byte(stateKeyPrefix + change.Key))
+			}
+		}
+		err := putTip(batch, bc.Blocks[height-1], height-1)
+		if err == nil {
+			err = bc.store.Write(batch)
+		}
+		if err != nil {
+			// Reapply the block so that memory keeps matching the store.
+			bc.State.applyBlock(bc.StateModules, block, height)
+			return fmt.Errorf("removing block %d from the store: %w", height, err)
+		}
+	}
+
+	bc.Blocks = bc.Blocks[:height]
+	bc.undo = bc.undo[:height]
+	return nil
+}
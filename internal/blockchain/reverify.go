@@ -0,0 +1,103 @@
+package blockchain
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// reverifyBatchSize is the number of blocks validated per lock acquisition during re-verification.
+const reverifyBatchSize = 256
+
+// ErrReverificationRunning is returned by Reverify when a re-verification is already in progress.
+var ErrReverificationRunning = errors.New("re-verification already running")
+
+// ReverificationProgress reports the state of the background full re-verification.
+type ReverificationProgress struct {
+	Running    bool              `json:"running"`
+	Checked    int               `json:"checked"`
+	Total      int               `json:"total"`
+	Runs       int               `json:"runs"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	LastReport *ValidationReport `json:"last_report,omitempty"`
+}
+
+// ReverificationProgress returns a copy of the current re-verification progress.
+func (bc *Blockchain) ReverificationProgress() ReverificationProgress {
+	bc.statusMu.Lock()
+	defer bc.statusMu.Unlock()
+	return bc.reverifyState
+}
+
+// Reverify re-validates the entire chain from genesis, ignoring checkpoints and the watermark.
+// The chain lock is released between batches so that new blocks can still be added.
+// Returns:
+// - The full validation report, or ErrReverificationRunning if another run is in progress.
+func (bc *Blockchain) Reverify() (*ValidationReport, error) {
+	total := bc.Len()
+
+	bc.statusMu.Lock()
+	if bc.reverifyState.Running {
+		bc.statusMu.Unlock()
+		return nil, ErrReverificationRunning
+	}
+	bc.reverifyState.Running = true
+	bc.reverifyState.Checked = 0
+	bc.reverifyState.Total = total
+	bc.reverifyState.StartedAt = time.Now()
+	bc.statusMu.Unlock()
+
+	report := &ValidationReport{From: 0, To: total - 1, Failures: []BlockFailure{}}
+	for from := 0; from < total; from += reverifyBatchSize {
+		to := min(from+reverifyBatchSize, total) - 1
+
+		bc.mu.RLock()
+		batch, err := bc.validateRange(from, to)
+		bc.mu.RUnlock()
+		if err != nil {
+			// The chain shrank underneath us; report what was checked.
+			report.To = from - 1
+			break
+		}
+		report.Failures = append(report.Failures, batch.Failures...)
+
+		bc.statusMu.Lock()
+		bc.reverifyState.Checked = to + 1
+		bc.statusMu.Unlock()
+	}
+	report.Valid = len(report.Failures) == 0
+	bc.updateWatermark(report)
+
+	bc.statusMu.Lock()
+	bc.reverifyState.Running = false
+	bc.reverifyState.Runs++
+	bc.reverifyState.FinishedAt = time.Now()
+	bc.reverifyState.LastReport = report
+	bc.statusMu.Unlock()
+
+	return report, nil
+}
+
+// StartReverification runs Reverify every interval until stop is closed.
+// It is meant to be run in its own goroutine.
+func (bc *Blockchain) StartReverification(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			report, err := bc.Reverify()
+			if err != nil {
+				log.Printf("Skipping re-verification: %v", err)
+				continue
+			}
+			if !report.Valid {
+				log.Printf("Re-verification found %d failures, first at block %d", len(report.Failures), report.Failures[0].Height)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"blockchain/internal/crypto"
+)
+
+// ErrUnauthorized is returned (possibly wrapped) when a transaction spends funds of an address without a valid
+// signature by its key, or funds of an address that no key can sign for.
+var ErrUnauthorized = errors.New("transaction not authorized")
+
+// TxSignature is a signature of a transaction's signing hash.
+type TxSignature struct {
+	Algorithm crypto.Algorithm `json:"algorithm"`
+	PublicKey string           `json:"public_key"` // Hex-encoded canonical public key.
+	Signature string           `json:"signature"`  // Hex-encoded canonical signature.
+}
+
+// IsKeyAddress reports whether address has the form of an address derived from a public key by
+// crypto.Address, which multisig and contract addresses share. Funds can only be sent to such addresses, and
+// spent with a signature by the key. Other addresses, such as the names used for development allocations, may
+// only be given funds by the genesis block, and those funds can never be spent.
+func IsKeyAddress(address string) bool {
+	if len(address) != 2*crypto.AddressSize {
+		return false
+	}
+	for _, c := range address {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// SigningHash returns the hash signed by the owners of a transaction: the SHA-256 hash of its JSON encoding
+// without signatures. Unlike the ID, it does not change as signatures are added.
+func (tx *Transaction) SigningHash() []byte {
+	unsigned := *tx
+	unsigned.Signatures = nil
+	encoded, _ := json.Marshal(&unsigned)
+	return crypto.HashSHA256Go(encoded)
+}
+
+// Sign adds the signature of signer to the transaction.
+func (tx *Transaction) Sign(signer crypto.Signer) error {
+	signature, err := signer.Sign(tx.SigningHash())
+	if err != nil {
+		return err
+	}
+	tx.Signatures = append(tx.Signatures, TxSignature{
+		Algorithm: signer.Algorithm(),
+		PublicKey: hex.EncodeToString(signer.PublicKey()),
+		Signature: hex.EncodeToString(signature),
+	})
+	return nil
+}
+
+// Signers verifies every signature of the transaction and returns the addresses of the keys that signed it.
+// Returns an error wrapping ErrUnauthorized if any signature is invalid.
+func (tx *Transaction) Signers() (map[string]bool, error) {
+	signers := make(map[string]bool, len(tx.Signatures))
+	hash := tx.SigningHash()
+	for i, signature := range tx.Signatures {
+		address, err := signature.verify(hash)
+		if err != nil {
+			return nil, fmt.Errorf("%w: signature %d: %v", ErrUnauthorized, i, err)
+		}
+		if signers[address] {
+			return nil, fmt.Errorf("%w: signature %d: %s signed twice", ErrUnauthorized, i, address)
+		}
+		signers[address] = true
+	}
+	return signers, nil
+}
+
+// verify checks the signature of hash and returns the address of the key that made it.
+func (s *TxSignature) verify(hash []byte) (string, error) {
+	publicKey, err := hex.DecodeString(s.PublicKey)
+	if err != nil {
+		return "", errors.New("malformed public key")
+	}
+	verifier, err := crypto.NewVerifier(s.Algorithm, publicKey)
+	if err != nil {
+		return "", err
+	}
+	raw, err := hex.DecodeString(s.Signature)
+	if err != nil {
+		return "", errors.New("malformed signature")
+	}
+	if err := verifier.Verify(hash, raw); err != nil {
+		return "", err
+	}
+	return crypto.Address(verifier), nil
+}
+
+// authorize checks that the transaction's signatures are valid and that every address among owners, the
+// addresses whose funds it spends, has signed it. A multisig owner is authorized by its policy, which must be
+// attached to the transaction, and the signatures of at least its threshold of signers. An empty owner, the
+// missing sender of a genesis allocation or coinbase, is left to the caller; any other owner that is not a
+// key-derived address is rejected.
+func authorize(tx *Transaction, owners []string) error {
+	signers, err := tx.Signers()
+	if err != nil {
+		return err
+	}
+	policies, err := tx.multisigPolicies()
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]bool, len(policies))
+	for _, owner := range owners {
+		if owner == "" || signers[owner] {
+			continue
+		}
+		if !IsKeyAddress(owner) {
+			return fmt.Errorf("%w: no key can sign for %q", ErrUnauthorized, owner)
+		}
+		policy, ok := policies[owner]
+		if !ok {
+			return fmt.Errorf("%w: missing signature of %s", ErrUnauthorized, owner)
+		}
+		if signed := policy.signed(signers); signed < policy.Threshold {
+			return fmt.Errorf("%w: %d of %d required signatures for multisig %s", ErrUnauthorized, signed, policy.Threshold, owner)
+		}
+		used[owner] = true
+	}
+	for address := range policies {
+		if !used[address] {
+			return fmt.Errorf("%w: multisig policy of %s does not spend any of its funds", ErrInvalidTransaction, address)
+		}
+	}
+	return nil
+}
+
+// checkRecipient rejects a recipient that is not a key-derived address, except in the genesis block, whose
+// allocations may name any address. Being hex, key-derived addresses are also safe to use in state keys.
+func checkRecipient(address string, height int) error {
+	if height != 0 && !IsKeyAddress(address) {
+		return fmt.Errorf("%w: recipient %q is not a key-derived address", ErrInvalidTransaction, address)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"sort"
+	"strconv"
+
+	"blockchain/pkg/merkle"
+)
+
+// StateEntry is a single key/value pair of the world state.
+type StateEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// StateChange records the value a key had before a block changed it, so the change can be undone.
+type StateChange struct {
+	Key     string
+	Value   string
+	Existed bool
+}
+
+// StateModule applies one aspect of a block to the state.
+// Modules run in order for every connected block; an error rejects the block.
+type StateModule func(state *State, block *Block, height int) error
+
+// DefaultStateModules are the state modules every new blockchain starts with.
+var DefaultStateModules = []StateModule{DataIndexModule, LedgerModule}
+
+// dataIndexPrefix prefixes the data index keys: "data/<data hash>" maps to the height of the first block carrying that data.
+const dataIndexPrefix = "data/"
+
+// DataIndexModule records the height at which each distinct block payload first appeared.
+func DataIndexModule(state *State, block *Block, height int) error {
+	key := dataIndexPrefix + block.DataHash
+	if _, ok := state.Get(key); !ok {
+		state.Set(key, strconv.Itoa(height))
+	}
+	return nil
+}
+
+// State is the key/value world state derived by applying every block in order.
+// Its root hash is committed in each block's StateRoot.
+type State struct {
+	entries map[string]string
+	journal []StateChange // Changes made by the block being applied, oldest first.
+
+	// An overlay holds only its changes to base: keys it has not set are read from base unless deleted. Only
+	// Get, Set and Delete, and so the state modules, see through to base.
+	base    *State
+	deleted map[string]bool
+}
+
+// NewState creates an empty State.
+func NewState() *State {
+	return &State{entries: make(map[string]string)}
+}
+
+// NewStateFromEntries creates a State holding the given entries, e.g. restored from a snapshot.
+func NewStateFromEntries(entries []StateEntry) *State {
+	state := NewState()
+	for _, entry := range entries {
+		state.entries[entry.Key] = entry.Value
+	}
+	return state
+}
+
+// newOverlay creates an empty overlay of base, whose changes leave base untouched. base must not change while the
+// overlay is in use.
+func newOverlay(base *State) *State {
+	return &State{entries: make(map[string]string), base: base, deleted: make(map[string]bool)}
+}
+
+// Get returns the value stored under key and whether it exists.
+func (s *State) Get(key string) (string, bool) {
+	value, ok := s.entries[key]
+	if ok || s.base == nil || s.deleted[key] {
+		return value, ok
+	}
+	return s.base.Get(key)
+}
+
+// Set stores value under key.
+func (s *State) Set(key, value string) {
+	s.record(key)
+	s.put(key, value)
+}
+
+// Delete removes key from the state.
+func (s *State) Delete(key string) {
+	s.record(key)
+	s.remove(key)
+}
+
+// put stores value under key without journaling the change.
+func (s *State) put(key, value string) {
+	s.entries[key] = value
+	if s.base != nil {
+		delete(s.deleted, key)
+	}
+}
+
+// remove deletes key without journaling the change.
+func (s *State) remove(key string) {
+	delete(s.entries, key)
+	if s.base != nil {
+		s.deleted[key] = true
+	}
+}
+
+// Len returns the number of entries in the state.
+func (s *State) Len() int {
+	return len(s.entries)
+}
+
+// Entries returns every entry sorted by key.
+func (s *State) Entries() []StateEntry {
+	entries := make([]StateEntry, 0, len(s.entries))
+	for key, value := range s.entries {
+		entries = append(entries, StateEntry{Key: key, Value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// Root returns the root of the sparse Merkle tree of the entries, hex encoded. Proofs against it can be
+// verified with the merkle package.
+func (s *State) Root() string {
+	return hex.EncodeToString(merkle.NewSparseTree(s.entries).Root())
+}
+
+// record journals the current value of key before it is changed.
+func (s *State) record(key string) {
+	value, existed := s.Get(key)
+	s.journal = append(s.journal, StateChange{Key: key, Value: value, Existed: existed})
+}
+
+// applyBlock runs the modules against a block and returns the changes needed to undo it.
+// If a module fails, the changes made so far are undone.
+func (s *State) applyBlock(modules []StateModule, block *Block, height int) ([]StateChange, error) {
+	s.journal = nil
+	for _, module := range modules {
+		if err := module(s, block, height); err != nil {
+			s.revert(s.journal)
+			s.journal = nil
+			return nil, err
+		}
+	}
+	undo := s.journal
+	s.journal = nil
+	return undo, nil
+}
+
+// snapshot returns a mark of the changes made so far by the block being applied, to which revertTo rolls back.
+func (s *State) snapshot() int {
+	return len(s.journal)
+}
+
+// revertTo undoes the changes made since mark was taken, leaving the earlier changes of the block in place.
+func (s *State) revertTo(mark int) {
+	s.revert(s.journal[mark:])
+	s.journal = s.journal[:mark]
+}
+
+// revert undoes the changes returned by applyBlock.
+func (s *State) revert(changes []StateChange) {
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if change.Existed {
+			s.put(change.Key, change.Value)
+		} else {
+			s.remove(change.Key)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"blockchain/internal/storage"
+)
+
+// Key layout of a chain in a storage.Backend. Heights are fixed-width hex so that keys sort in height order.
+const (
+	blockKeyPrefix = "block/" // "block/<height>" holds the block (or its header, once pruned) as JSON.
+	hashKeyPrefix  = "hash/"  // "hash/<block hash>" holds the height of the block with that hash.
+	stateKeyPrefix = "state/" // "state/<state key>" holds the state value.
+	undoKeyPrefix  = "undo/"  // "undo/<height>" holds the StateChanges that undo the block at that height.
+	tipKey         = "meta/tip"
+)
+
+// tipRecord is stored under tipKey and names the last block of the chain.
+type tipRecord struct {
+	Height int    `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// heightKey returns the key for a height under prefix.
+func heightKey(prefix string, height int) []byte {
+	return []byte(fmt.Sprintf("%s%016x", prefix, height))
+}
+
+// OpenBlockchain loads the chain kept in store, or creates a new chain starting with genesis if the store is empty.
+// The stored chain is checked for consistency first, against the checkpoints of genesis and the extra trusted
+// checkpoints, and repaired if a crash or a damaged store left it diverged; see CheckStore. Every block connected
+// afterwards is written to the store in one atomic batch.
+// Returns:
+// - The loaded blockchain.
+// - A description of each repair made, empty if the store was consistent.
+func OpenBlockchain(store storage.Backend, genesis *Genesis, checkpoints []Checkpoint) (*Blockchain, []string, error) {
+	if _, err := store.Get([]byte(tipKey)); errors.Is(err, storage.ErrNotFound) {
+		bc := NewBlockchainFromGenesis(genesis)
+		bc.Checkpoints = append(bc.Checkpoints, checkpoints...)
+		return bc, nil, bc.AttachStore(store)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	bc, repairs, err := CheckStore(store, genesis, checkpoints)
+	if err != nil {
+		return nil, nil, err
+	}
+	bc.store = store
+	return bc, repairs, nil
+}
+
+// IsStoreEmpty reports whether store holds no chain yet.
+func IsStoreEmpty(store storage.Backend) (bool, error) {
+	_, err := store.Get([]byte(tipKey))
+	if errors.Is(err, storage.ErrNotFound) {
+		return true, nil
+	}
+	return false, err
+}
+
+// AttachStore writes the whole chain to store in one batch and keeps it up to date from then on.
+// It is used for new chains and for chains bootstrapped from a snapshot; store should be empty.
+func (bc *Blockchain) AttachStore(store storage.Backend) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	batch := storage.NewBatch()
+	for height, block := range bc.Blocks {
+		if err := putBlock(batch, block, height); err != nil {
+			return err
+		}
+		if height >= bc.prunedHeight {
+			if err := putUndo(batch, bc.undo[height], height); err != nil {
+				return err
+			}
+		}
+	}
+	for _, entry := range bc.State.Entries() {
+		batch.Put([]byte(stateKeyPrefix+entry.Key), []byte(entry.Value))
+	}
+	if err := putTip(batch, bc.Blocks[len(bc.Blocks)-1], len(bc.Blocks)-1); err != nil {
+		return err
+	}
+	if err := store.Write(batch); err != nil {
+		return err
+	}
+	bc.store = store
+	return nil
+}
+
+// persistBlock writes a newly connected block with its indexes, state changes, undo data and the new tip
+// as a single atomic batch. It does nothing if the chain has no store. The caller must hold bc.mu.
+func (bc *Blockchain) persistBlock(block *Block, height int, undo []StateChange) error {
+	if bc.store == nil {
+		return nil
+	}
+
+	batch := storage.NewBatch()
+	if err := putBlock(batch, block, height); err != nil {
+		return err
+	}
+	for _, change := range undo {
+		if value, ok := bc.State.Get(change.Key); ok {
+			batch.Put([]byte(stateKeyPrefix+change.Key), []byte(value))
+		} else {
+			batch.Delete([]byte(stateKeyPrefix + change.Key))
+		}
+	}
+	if err := putUndo(batch, undo, height); err != nil {
+		return err
+	}
+	if err := putTip(batch, block, height); err != nil {
+		return err
+	}
+	return bc.store.Write(batch)
+}
+
+// persistPruned replaces the stored bodies of the given blocks with their headers.
+// The caller must hold bc.mu.
+func (bc *Blockchain) persistPruned(from, to int) error {
+	if bc.store == nil || from >= to {
+		return nil
+	}
+	batch := storage.NewBatch()
+	for height := from; height < to; height++ {
+		record, err := json.Marshal(bc.Blocks[height])
+		if err != nil {
+			return err
+		}
+		batch.Put(heightKey(blockKeyPrefix, height), record)
+	}
+	return bc.store.Write(batch)
+}
+
+// putBlock adds a block and its hash index entry to batch.
+func putBlock(batch *storage.Batch, block *Block, height int) error {
+	record, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+	batch.Put(heightKey(blockKeyPrefix, height), record)
+	batch.Put([]byte(hashKeyPrefix+block.Hash), []byte(strconv.Itoa(height)))
+	return nil
+}
+
+// putUndo adds the undo data of the block at height to batch.
+func putUndo(batch *storage.Batch, undo []StateChange, height int) error {
+	record, err := json.Marshal(undo)
+	if err != nil {
+		return err
+	}
+	batch.Put(heightKey(undoKeyPrefix, height), record)
+	return nil
+}
+
+// putTip adds the tip pointer to batch.
+func putTip(batch *storage.Batch, block *Block, height int) error {
+	record, err := json.Marshal(tipRecord{Height: height, Hash: block.Hash})
+	if err != nil {
+		return err
+	}
+	batch.Put([]byte(tipKey), record)
+	return nil
+}
@@ -0,0 +1,59 @@
+package blockchain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// MedianTimeSpan is the number of preceding blocks whose median timestamp a new block must exceed.
+	MedianTimeSpan = 11
+
+	// MaxFutureDrift is how far ahead of the network-adjusted time a block timestamp may be.
+	MaxFutureDrift = 2 * time.Minute
+)
+
+// medianTimePast returns the median timestamp, in Unix milliseconds, of the MedianTimeSpan blocks
+// ending at the given height. The caller must hold bc.mu.
+func (bc *Blockchain) medianTimePast(height int) int64 {
+	start := max(0, height-MedianTimeSpan+1)
+	timestamps := make([]int64, 0, height-start+1)
+	for _, block := range bc.Blocks[start : height+1] {
+		timestamps = append(timestamps, block.Timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2]
+}
+
+// MedianTimePastRule rejects blocks whose timestamp is not later than the median time past of their predecessors.
+func MedianTimePastRule(bc *Blockchain, height int) error {
+	median := bc.medianTimePast(height - 1)
+	if timestamp := bc.Blocks[height].Timestamp; timestamp <= median {
+		return fmt.Errorf("timestamp %d is not after median time past %d", timestamp, median)
+	}
+	return nil
+}
+
+// FutureDriftRule rejects blocks whose timestamp is more than MaxFutureDrift ahead of the network-adjusted time.
+func FutureDriftRule(bc *Blockchain, height int) error {
+	limit := bc.now().Add(MaxFutureDrift).UnixMilli()
+	if timestamp := bc.Blocks[height].Timestamp; timestamp > limit {
+		return fmt.Errorf("timestamp %d is more than %s ahead of network time", timestamp, MaxFutureDrift)
+	}
+	return nil
+}
+
+// now returns the current time according to bc.Clock, or the local time if no clock is set.
+func (bc *Blockchain) now() time.Time {
+	if bc.Clock == nil {
+		return time.Now()
+	}
+	return bc.Clock.Now()
+}
+
+// nextTimestamp returns the timestamp for a block appended to the chain: the current time,
+// raised if necessary to just after the median time past. The caller must hold bc.mu.
+func (bc *Blockchain) nextTimestamp() int64 {
+	return max(bc.now().UnixMilli(), bc.medianTimePast(len(bc.Blocks)-1)+1)
+}
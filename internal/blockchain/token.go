@@ -0,0 +1,295 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// State key prefixes of tokens: "token/<symbol>" holds the Token as JSON and "tokenbalance/<symbol>/<address>"
+// holds the decimal balance of an address.
+const (
+	tokenPrefix        = "token/"
+	tokenBalancePrefix = "tokenbalance/"
+)
+
+// Token operations.
+const (
+	TokenCreate   = "create"
+	TokenMint     = "mint"
+	TokenTransfer = "transfer"
+	TokenBurn     = "burn"
+)
+
+const (
+	// MaxTokenDecimals is the most decimal places a token may have.
+	MaxTokenDecimals = 18
+
+	// MaxTokenNameLength is the longest name a token may have, in bytes.
+	MaxTokenNameLength = 64
+)
+
+// tokenSymbol is the form of a token symbol: 1 to 12 upper-case letters and digits.
+var tokenSymbol = regexp.MustCompile(`^[A-Z0-9]{1,12}$`)
+
+// ErrUnknownToken is returned (possibly wrapped) for a token that has not been created.
+var ErrUnknownToken = errors.New("unknown token")
+
+// TokenOperation is the token part of a transaction; see applyTokenTransaction.
+type TokenOperation struct {
+	Op        string `json:"op"`                   // TokenCreate, TokenMint, TokenTransfer or TokenBurn.
+	Symbol    string `json:"symbol"`               // Symbol of the token.
+	Name      string `json:"name,omitempty"`       // Create only: human-readable name.
+	Decimals  int    `json:"decimals,omitempty"`   // Create only: number of decimal places shown by clients.
+	MaxSupply uint64 `json:"max_supply,omitempty"` // Create only: cap on the supply; zero for no cap.
+	Amount    uint64 `json:"amount,omitempty"`     // Amount minted, transferred or burned; for create, the initial supply.
+}
+
+// Token is a fungible token created on the chain. Its issuer is the only address allowed to mint it.
+type Token struct {
+	Symbol    string `json:"symbol"`
+	Name      string `json:"name"`
+	Decimals  int    `json:"decimals"`
+	Issuer    string `json:"issuer"`
+	Supply    uint64 `json:"supply"`     // Amount minted less the amount burned.
+	MaxSupply uint64 `json:"max_supply"` // Cap on the supply; zero for no cap.
+	Height    int    `json:"height"`     // Height of the block that created the token.
+}
+
+// TokenHolder is an address holding a token.
+type TokenHolder struct {
+	Address string `json:"address"`
+	Balance uint64 `json:"balance"`
+}
+
+// token returns the token with the given symbol stored in the state.
+func token(state *State, symbol string) (*Token, bool) {
+	value, ok := state.Get(tokenPrefix + symbol)
+	if !ok {
+		return nil, false
+	}
+	var t Token
+	if err := json.Unmarshal([]byte(value), &t); err != nil {
+		return nil, false
+	}
+	return &t, true
+}
+
+// setToken stores a token in the state.
+func setToken(state *State, t *Token) {
+	value, _ := json.Marshal(t)
+	state.Set(tokenPrefix+t.Symbol, string(value))
+}
+
+// tokenBalance returns the balance of address in the token with the given symbol.
+func tokenBalance(state *State, symbol, address string) uint64 {
+	value, _ := state.Get(tokenBalancePrefix + symbol + "/" + address)
+	balance, _ := strconv.ParseUint(value, 10, 64)
+	return balance
+}
+
+// setTokenBalance stores the balance of address in a token. Zero balances are deleted, so that only holders
+// are listed.
+func setTokenBalance(state *State, symbol, address string, balance uint64) {
+	key := tokenBalancePrefix + symbol + "/" + address
+	if balance == 0 {
+		state.Delete(key)
+		return
+	}
+	state.Set(key, strconv.FormatUint(balance, 10))
+}
+
+// credit adds amount to the balance of address in a token.
+func credit(state *State, symbol, address string, amount uint64) error {
+	balance := tokenBalance(state, symbol, address)
+	if balance > math.MaxUint64-amount {
+		return fmt.Errorf("%w: %s balance of %s would overflow", ErrInvalidTransaction, symbol, address)
+	}
+	setTokenBalance(state, symbol, address, balance+amount)
+	return nil
+}
+
+// debit subtracts amount from the balance of address in a token.
+func debit(state *State, symbol, address string, amount uint64) error {
+	balance := tokenBalance(state, symbol, address)
+	if balance < amount {
+		return fmt.Errorf("%w: %s has %d %s, needs %d", ErrInsufficientBalance, address, balance, symbol, amount)
+	}
+	setTokenBalance(state, symbol, address, balance-amount)
+	return nil
+}
+
+// validate checks the fields of the operation, without looking at the state.
+func (op *TokenOperation) validate(tx *Transaction) error {
+	if !tokenSymbol.MatchString(op.Symbol) {
+		return fmt.Errorf("%w: token symbol %q, want 1 to 12 upper-case letters and digits", ErrInvalidTransaction, op.Symbol)
+	}
+	if op.Op != TokenCreate && (op.Name != "" || op.Decimals != 0 || op.MaxSupply != 0) {
+		return fmt.Errorf("%w: name, decimals and max supply are only allowed when creating a token", ErrInvalidTransaction)
+	}
+	switch op.Op {
+	case TokenCreate:
+		if op.Name == "" || len(op.Name) > MaxTokenNameLength {
+			return fmt.Errorf("%w: token name of %d bytes, want 1 to %d", ErrInvalidTransaction, len(op.Name), MaxTokenNameLength)
+		}
+		if op.Decimals < 0 || op.Decimals > MaxTokenDecimals {
+			return fmt.Errorf("%w: %d decimals, want 0 to %d", ErrInvalidTransaction, op.Decimals, MaxTokenDecimals)
+		}
+		if op.MaxSupply != 0 && op.Amount > op.MaxSupply {
+			return fmt.Errorf("%w: initial supply %d exceeds the max supply %d", ErrInvalidTransaction, op.Amount, op.MaxSupply)
+		}
+		if tx.To != "" {
+			return fmt.Errorf("%w: creating a token has no recipient", ErrInvalidTransaction)
+		}
+	case TokenMint, TokenTransfer:
+		if tx.To == "" {
+			return fmt.Errorf("%w: missing recipient", ErrInvalidTransaction)
+		}
+		fallthrough
+	case TokenBurn:
+		if op.Amount == 0 {
+			return fmt.Errorf("%w: zero token amount", ErrInvalidTransaction)
+		}
+		if op.Op == TokenBurn && tx.To != "" {
+			return fmt.Errorf("%w: burning tokens has no recipient", ErrInvalidTransaction)
+		}
+	default:
+		return fmt.Errorf("%w: unknown token operation %q", ErrInvalidTransaction, op.Op)
+	}
+	return nil
+}
+
+// applyTokenTransaction applies a transaction carrying a token operation. Like a transfer, it needs the
+// sender's signature and nonce and pays its fee, but moves no native funds: its Amount must be zero.
+//
+// Creating a token makes the sender its issuer and credits the sender with the initial supply. Only the issuer
+// may mint more, up to the max supply, to the recipient. Any holder may transfer their tokens to the recipient
+// or burn them, which reduces the supply.
+func applyTokenTransaction(state *State, tx *Transaction, height int) error {
+	op := tx.Token
+	if len(tx.Inputs) > 0 || len(tx.Outputs) > 0 {
+		return fmt.Errorf("%w: inputs and outputs are only allowed in UTXO mode", ErrInvalidTransaction)
+	}
+	if tx.From == "" {
+		return fmt.Errorf("%w: a token transaction needs a sender", ErrInvalidTransaction)
+	}
+	if tx.Amount != 0 || tx.isContract() || tx.Asset != nil {
+		return fmt.Errorf("%w: a token transaction has no amount, contract fields or asset operation", ErrInvalidTransaction)
+	}
+	if strings.Contains(tx.From, "/") || strings.Contains(tx.To, "/") {
+		return fmt.Errorf("%w: address contains \"/\"", ErrInvalidTransaction)
+	}
+	if err := op.validate(tx); err != nil {
+		return err
+	}
+	if tx.To != "" {
+		if err := checkRecipient(tx.To, height); err != nil {
+			return err
+		}
+	}
+	if err := authorize(tx, []string{tx.From}); err != nil {
+		return err
+	}
+
+	if err := chargeFee(state, tx, 0); err != nil {
+		return err
+	}
+
+	t, exists := token(state, op.Symbol)
+	if op.Op == TokenCreate {
+		if exists {
+			return fmt.Errorf("%w: token %s already exists", ErrInvalidTransaction, op.Symbol)
+		}
+		setToken(state, &Token{Symbol: op.Symbol, Name: op.Name, Decimals: op.Decimals, Issuer: tx.From, Supply: op.Amount, MaxSupply: op.MaxSupply, Height: height})
+		return credit(state, op.Symbol, tx.From, op.Amount)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %w: %s", ErrInvalidTransaction, ErrUnknownToken, op.Symbol)
+	}
+
+	switch op.Op {
+	case TokenMint:
+		if tx.From != t.Issuer {
+			return fmt.Errorf("%w: only the issuer %s may mint %s", ErrUnauthorized, t.Issuer, t.Symbol)
+		}
+		if t.Supply > math.MaxUint64-op.Amount || t.MaxSupply != 0 && t.Supply+op.Amount > t.MaxSupply {
+			return fmt.Errorf("%w: minting %d %s would exceed the max supply", ErrInvalidTransaction, op.Amount, t.Symbol)
+		}
+		t.Supply += op.Amount
+		setToken(state, t)
+		return credit(state, t.Symbol, tx.To, op.Amount)
+	case TokenTransfer:
+		if err := debit(state, t.Symbol, tx.From, op.Amount); err != nil {
+			return err
+		}
+		return credit(state, t.Symbol, tx.To, op.Amount)
+	default: // TokenBurn
+		if err := debit(state, t.Symbol, tx.From, op.Amount); err != nil {
+			return err
+		}
+		t.Supply -= op.Amount
+		setToken(state, t)
+		return nil
+	}
+}
+
+// Tokens returns every token created on the chain, by symbol.
+func (bc *Blockchain) Tokens() []*Token {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	tokens := []*Token{}
+	for key := range bc.State.entries {
+		if symbol, ok := strings.CutPrefix(key, tokenPrefix); ok {
+			if t, ok := token(bc.State, symbol); ok {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Symbol < tokens[j].Symbol })
+	return tokens
+}
+
+// Token returns the token with the given symbol.
+// Returns ErrUnknownToken if it has not been created.
+func (bc *Blockchain) Token(symbol string) (*Token, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	t, ok := token(bc.State, symbol)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownToken, symbol)
+	}
+	return t, nil
+}
+
+// TokenHolders returns the addresses holding the token with the given symbol, largest balance first.
+// Returns ErrUnknownToken if it has not been created.
+func (bc *Blockchain) TokenHolders(symbol string) ([]TokenHolder, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if _, ok := token(bc.State, symbol); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownToken, symbol)
+	}
+	holders := []TokenHolder{}
+	prefix := tokenBalancePrefix + symbol + "/"
+	for key, value := range bc.State.entries {
+		if address, ok := strings.CutPrefix(key, prefix); ok {
+			balance, _ := strconv.ParseUint(value, 10, 64)
+			holders = append(holders, TokenHolder{Address: address, Balance: balance})
+		}
+	}
+	sort.Slice(holders, func(i, j int) bool {
+		if holders[i].Balance != holders[j].Balance {
+			return holders[i].Balance > holders[j].Balance
+		}
+		return holders[i].Address < holders[j].Address
+	})
+	return holders, nil
+}
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"blockchain/pkg/merkle"
+)
+
+var (
+	// ErrInvalidTransaction is returned (possibly wrapped) for a transaction that cannot be applied to the state.
+	ErrInvalidTransaction = errors.New("invalid transaction")
+
+	// ErrInsufficientBalance is returned when a sender cannot cover the amount of a transaction.
+	ErrInsufficientBalance = errors.New("insufficient balance")
+
+	// ErrBadNonce is returned when a transaction's nonce is not the sender's next nonce.
+	ErrBadNonce = errors.New("bad nonce")
+)
+
+// Transaction changes the ledger. In account mode it transfers Amount from one account to another; in UTXO mode
+// it spends the outputs named by its inputs and creates new outputs. Only the fields of the chain's ledger mode
+// may be set. Transactions without a sender or inputs are only allowed in the genesis block, where they
+// allocate the initial funds, and as the coinbase transaction of later blocks, where they pay the block
+// reward and fees to the block's producer. Spending the funds of an address derived from a public key
+// requires a signature by that key, and spending the funds of a multisig address requires its policy and the
+// signatures of enough of its signers. In account mode, transactions may also deploy and call contracts, see
+// applyContractTransaction, create and move tokens, see applyTokenTransaction, and mint and move non-fungible
+// assets, see applyAssetTransaction. In either mode, a transaction without a sender may notarize a document,
+// see applyNotarization.
+type Transaction struct {
+	From   string `json:"from,omitempty"`   // Account mode: address of the sending account; empty for genesis allocations.
+	To     string `json:"to,omitempty"`     // Account mode: address of the receiving account.
+	Amount uint64 `json:"amount,omitempty"` // Account mode: amount transferred.
+	Nonce  uint64 `json:"nonce,omitempty"`  // Account mode: must equal the sender's nonce; prevents replaying the transaction.
+
+	Fee      uint64 `json:"fee,omitempty"`      // Paid by the sender, on top of the amount or outputs, to the block's producer.
+	Coinbase int    `json:"coinbase,omitempty"` // Coinbase only: the height of the block it rewards; see IsCoinbase.
+
+	LockHeight int   `json:"lock_height,omitempty"` // Not valid in blocks below this height.
+	LockTime   int64 `json:"lock_time,omitempty"`   // Not valid in blocks timestamped earlier, in Unix milliseconds.
+
+	Code     string   `json:"code,omitempty"`      // Contract deployment: hex-encoded bytecode; To is empty.
+	Args     []string `json:"args,omitempty"`      // Contract call: hex-encoded arguments; To is the contract.
+	GasLimit uint64   `json:"gas_limit,omitempty"` // Contract deployment or call: the most gas it may use.
+
+	Token *TokenOperation `json:"token,omitempty"` // Token operation; see applyTokenTransaction.
+	Asset *AssetOperation `json:"asset,omitempty"` // Asset operation; see applyAssetTransaction.
+
+	Notarization *Notarization `json:"notarization,omitempty"` // Document digest; see applyNotarization.
+
+	Inputs  []TxInput  `json:"inputs,omitempty"`  // UTXO mode: the unspent outputs consumed.
+	Outputs []TxOutput `json:"outputs,omitempty"` // UTXO mode: the outputs created.
+
+	Multisig   []MultisigPolicy `json:"multisig,omitempty"`   // Policies of the multisig addresses whose funds are spent.
+	Signatures []TxSignature    `json:"signatures,omitempty"` // Signatures of the owners of the funds spent; see SigningHash.
+}
+
+// TxInput names an output of an earlier transaction, spent by a UTXO transaction.
+type TxInput struct {
+	TxID  string `json:"txid"`  // ID of the transaction that created the output.
+	Index int    `json:"index"` // Position of the output in that transaction.
+}
+
+// String returns the input's outpoint in the form "<txid>:<index>".
+func (in TxInput) String() string {
+	return fmt.Sprintf("%s:%d", in.TxID, in.Index)
+}
+
+// TxOutput assigns an amount to an address in UTXO mode.
+type TxOutput struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+}
+
+// ID returns the transaction's identifier, the hex-encoded SHA-256 hash of its JSON encoding.
+func (tx *Transaction) ID() string {
+	encoded, _ := json.Marshal(tx)
+	return sha256Hex(string(encoded))
+}
+
+// calculateTxRoot returns the Merkle root of the IDs of txs, committed in the block header as TxRoot.
+func calculateTxRoot(txs []*Transaction) string {
+	leaves := make([][]byte, len(txs))
+	for i, tx := range txs {
+		leaves[i], _ = hex.DecodeString(tx.ID())
+	}
+	return hex.EncodeToString(merkle.Root(leaves))
+}
@@ -0,0 +1,128 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrDoubleSpend is returned (possibly wrapped) when a transaction spends an output that is already spent
+// or does not exist.
+var ErrDoubleSpend = errors.New("double spend")
+
+// utxoPrefix prefixes the UTXO set in the state: "utxo/<txid>:<index>" holds the unspent TxOutput as JSON.
+const utxoPrefix = "utxo/"
+
+// UnspentOutput is an entry of the UTXO set.
+type UnspentOutput struct {
+	TxInput  // The outpoint that spends this output.
+	TxOutput // The address and amount.
+}
+
+// UTXOModule applies the transactions of a block to the UTXO set. Every input must name an unspent output,
+// no output may be spent twice, and the outputs and fee of a transaction may not exceed its inputs.
+// Notarizations are applied by applyNotarization.
+func UTXOModule(state *State, block *Block, height int) error {
+	for i, tx := range block.Transactions {
+		apply := spendOutputs
+		if tx.Notarization != nil {
+			apply = applyNotarization
+		}
+		if err := apply(state, tx, height); err != nil {
+			return fmt.Errorf("transaction %d (%s): %w", i, tx.ID(), err)
+		}
+	}
+	return nil
+}
+
+// spendOutputs removes the outputs spent by tx from the UTXO set and adds the outputs it creates.
+func spendOutputs(state *State, tx *Transaction, height int) error {
+	if tx.From != "" || tx.To != "" || tx.Amount != 0 || tx.Nonce != 0 || tx.isContract() || tx.Token != nil || tx.Asset != nil {
+		return fmt.Errorf("%w: account, contract, token and asset fields are not allowed in UTXO mode", ErrInvalidTransaction)
+	}
+	if len(tx.Outputs) == 0 {
+		return fmt.Errorf("%w: no outputs", ErrInvalidTransaction)
+	}
+	if len(tx.Inputs) == 0 && height != 0 && !tx.IsCoinbase() {
+		return fmt.Errorf("%w: only the genesis block and coinbase transactions may create outputs without inputs", ErrInvalidTransaction)
+	}
+	if len(tx.Inputs) == 0 && tx.Fee != 0 {
+		return fmt.Errorf("%w: a transaction without inputs pays no fee", ErrInvalidTransaction)
+	}
+
+	var in, out uint64
+	owners := make([]string, 0, len(tx.Inputs))
+	for _, input := range tx.Inputs {
+		key := utxoPrefix + input.String()
+		value, ok := state.Get(key)
+		if !ok {
+			return fmt.Errorf("%w: output %s is spent or does not exist", ErrDoubleSpend, input)
+		}
+		var output TxOutput
+		json.Unmarshal([]byte(value), &output)
+		if in > math.MaxUint64-output.Amount {
+			return fmt.Errorf("%w: input amounts overflow", ErrInvalidTransaction)
+		}
+		in += output.Amount
+		owners = append(owners, output.Address)
+		// Deleting the output straight away also catches an input listed twice.
+		state.Delete(key)
+	}
+
+	if err := authorize(tx, owners); err != nil {
+		return err
+	}
+
+	id := tx.ID()
+	for index, output := range tx.Outputs {
+		if output.Address == "" || output.Amount == 0 {
+			return fmt.Errorf("%w: output %d needs an address and a positive amount", ErrInvalidTransaction, index)
+		}
+		if err := checkRecipient(output.Address, height); err != nil {
+			return fmt.Errorf("output %d: %w", index, err)
+		}
+		if out > math.MaxUint64-output.Amount {
+			return fmt.Errorf("%w: output amounts overflow", ErrInvalidTransaction)
+		}
+		out += output.Amount
+		value, _ := json.Marshal(output)
+		state.Set(utxoPrefix+TxInput{TxID: id, Index: index}.String(), string(value))
+	}
+
+	if len(tx.Inputs) > 0 && (out > in || tx.Fee > in-out) {
+		return fmt.Errorf("%w: outputs of %d and fee of %d exceed inputs of %d", ErrInsufficientBalance, out, tx.Fee, in)
+	}
+	return nil
+}
+
+// UnspentOutputs returns the unspent outputs paying address, ordered by outpoint.
+func (bc *Blockchain) UnspentOutputs(address string) []UnspentOutput {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	outputs := []UnspentOutput{}
+	for key, value := range bc.State.entries {
+		if !strings.HasPrefix(key, utxoPrefix) {
+			continue
+		}
+		var output TxOutput
+		if json.Unmarshal([]byte(value), &output) != nil || output.Address != address {
+			continue
+		}
+		outpoint := strings.TrimPrefix(key, utxoPrefix)
+		separator := strings.LastIndex(outpoint, ":")
+		var index int
+		fmt.Sscan(outpoint[separator+1:], &index)
+		outputs = append(outputs, UnspentOutput{TxInput: TxInput{TxID: outpoint[:separator], Index: index}, TxOutput: output})
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		if outputs[i].TxID != outputs[j].TxID {
+			return outputs[i].TxID < outputs[j].TxID
+		}
+		return outputs[i].Index < outputs[j].Index
+	})
+	return outputs
+}
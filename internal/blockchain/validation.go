@@ -0,0 +1,129 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FailureReason identifies why a block failed validation.
+type FailureReason string
+
+const (
+	ReasonHashMismatch FailureReason = "hash_mismatch"       // The stored hash does not match the block contents.
+	ReasonBrokenLink   FailureReason = "broken_link"         // PreviousHash does not match the preceding block.
+	ReasonBadSignature FailureReason = "bad_signature"       // A signature carried by the block does not verify.
+	ReasonConsensus    FailureReason = "consensus_violation" // The block breaks a consensus rule.
+)
+
+// ErrInvalidBlock is returned when a block offered to the chain fails validation.
+var ErrInvalidBlock = errors.New("invalid block")
+
+// ErrBadSignature is returned (possibly wrapped) by rules that reject a block because of a signature.
+var ErrBadSignature = errors.New("bad signature")
+
+// Rule is a consensus rule checked against every non-genesis block during validation.
+// Rules are called with the chain's read lock held and must access bc.Blocks directly.
+// Rules return nil when the block at the given height is acceptable.
+// Errors wrapping ErrBadSignature are reported as ReasonBadSignature, all others as ReasonConsensus.
+type Rule func(bc *Blockchain, height int) error
+
+// DefaultRules are the consensus rules every new blockchain starts with.
+var DefaultRules = []Rule{SizeRule, MedianTimePastRule, FutureDriftRule}
+
+// BlockFailure describes a single block that failed validation.
+type BlockFailure struct {
+	Height int           `json:"height"`
+	Hash   string        `json:"hash"`
+	Reason FailureReason `json:"reason"`
+	Detail string        `json:"detail,omitempty"`
+}
+
+// ValidationReport lists every failing block found in the validated height range.
+type ValidationReport struct {
+	From     int            `json:"from"`
+	To       int            `json:"to"`
+	Valid    bool           `json:"valid"`
+	Failures []BlockFailure `json:"failures"`
+}
+
+// Validate verifies the whole chain and returns a report of every failing block.
+// Validation is stateless: state roots are checked when blocks are connected, not here.
+func (bc *Blockchain) Validate() *ValidationReport {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	report, _ := bc.validateRange(0, len(bc.Blocks)-1)
+	bc.updateWatermark(report)
+	return report
+}
+
+// ValidateRange verifies the blocks with heights from..to (inclusive).
+// The link of block "from" to its predecessor is checked as well.
+// Returns an error if the range is empty or outside the chain.
+func (bc *Blockchain) ValidateRange(from, to int) (*ValidationReport, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.validateRange(from, to)
+}
+
+// validateRange implements ValidateRange; the caller must hold bc.mu.
+func (bc *Blockchain) validateRange(from, to int) (*ValidationReport, error) {
+	if from < 0 || to >= len(bc.Blocks) || from > to {
+		return nil, fmt.Errorf("invalid height range %d..%d for chain of %d blocks", from, to, len(bc.Blocks))
+	}
+
+	report := &ValidationReport{From: from, To: to, Failures: []BlockFailure{}}
+	for height := from; height <= to; height++ {
+		report.Failures = append(report.Failures, bc.checkBlock(height)...)
+	}
+	report.Valid = len(report.Failures) == 0
+	return report, nil
+}
+
+// checkBlock runs every check against the block at the given height.
+func (bc *Blockchain) checkBlock(height int) []BlockFailure {
+	block := bc.Blocks[height]
+	var failures []BlockFailure
+	fail := func(reason FailureReason, detail string) {
+		failures = append(failures, BlockFailure{Height: height, Hash: block.Hash, Reason: reason, Detail: detail})
+	}
+
+	// Recalculate the hash and check if it matches.
+	if calculated := block.calculateHash(); block.Hash != calculated {
+		fail(ReasonHashMismatch, fmt.Sprintf("calculated hash %s", calculated))
+	}
+
+	// Check the body against the header, unless only the header is kept.
+	if !block.Pruned && block.DataHash != calculateDataHash(block.Data) {
+		fail(ReasonHashMismatch, "data does not match data hash")
+	}
+	if !block.Pruned && block.TxRoot != calculateTxRoot(block.Transactions) {
+		fail(ReasonHashMismatch, "transactions do not match transaction root")
+	}
+
+	// The block must match any checkpoint at its height.
+	if checkpoint, ok := bc.checkpointAt(height); ok && block.Hash != checkpoint.Hash {
+		fail(ReasonConsensus, fmt.Sprintf("block does not match checkpoint hash %s", checkpoint.Hash))
+	}
+
+	// The genesis block has no predecessor and is not subject to consensus rules.
+	if height == 0 {
+		return failures
+	}
+
+	// Check if the block's PreviousHash matches the previous block's hash.
+	if previous := bc.Blocks[height-1]; block.PreviousHash != previous.Hash {
+		fail(ReasonBrokenLink, fmt.Sprintf("expected previous hash %s", previous.Hash))
+	}
+
+	for _, rule := range bc.Rules {
+		if err := rule(bc, height); err != nil {
+			if errors.Is(err, ErrBadSignature) {
+				fail(ReasonBadSignature, err.Error())
+			} else {
+				fail(ReasonConsensus, err.Error())
+			}
+		}
+	}
+	return failures
+}
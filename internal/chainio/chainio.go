@@ -0,0 +1,383 @@
+package chainio
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"blockchain/internal/blockchain"
+)
+
+// Format identifies one of the portable chain file formats.
+type Format string
+
+const (
+	// FormatNDJSON writes a header line, one JSON block per line and a trailer line.
+	FormatNDJSON Format = "ndjson"
+
+	// FormatBinary writes a magic header followed by length-prefixed JSON blocks and a binary trailer.
+	FormatBinary Format = "binary"
+)
+
+// binaryMagic starts every binary chain file.
+var binaryMagic = []byte("BCHN\x01")
+
+// maxRecordSize bounds a single record so that a corrupt length prefix cannot exhaust memory.
+const maxRecordSize = 2 * blockchain.MaxBlockSize
+
+var (
+	// ErrChecksumMismatch is returned when the trailer checksum does not match the blocks read.
+	ErrChecksumMismatch = errors.New("chain file checksum mismatch")
+
+	// ErrTruncated is returned when a chain file ends before its trailer.
+	ErrTruncated = errors.New("chain file truncated")
+
+	// ErrUnknownFormat is returned for an unsupported format name or unrecognised file contents.
+	ErrUnknownFormat = errors.New("unknown chain file format")
+)
+
+// header is the first record of an NDJSON chain file.
+type header struct {
+	Format  Format `json:"format"`
+	Version int    `json:"version"`
+	From    int    `json:"from"`
+}
+
+// trailer is the last record of an NDJSON chain file.
+type trailer struct {
+	Count    int    `json:"count"`
+	Checksum string `json:"checksum"`
+}
+
+// ParseFormat converts a format name to a Format.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case FormatNDJSON, FormatBinary:
+		return Format(name), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+}
+
+// ContentType returns the HTTP content type used to transfer a chain file in this format.
+func (f Format) ContentType() string {
+	if f == FormatBinary {
+		return "application/octet-stream"
+	}
+	return "application/x-ndjson"
+}
+
+// Writer streams blocks to a chain file. The checksum is the SHA-256 of every encoded block record.
+type Writer struct {
+	w        *bufio.Writer
+	format   Format
+	checksum hash.Hash
+	count    int
+}
+
+// NewWriter writes the file header and returns a Writer for the given format.
+// Parameters:
+// - w: The destination of the chain file.
+// - format: The file format to write.
+// - from: The height of the first block that will be written.
+func NewWriter(w io.Writer, format Format, from int) (*Writer, error) {
+	cw := &Writer{w: bufio.NewWriter(w), format: format, checksum: sha256.New()}
+
+	var err error
+	switch format {
+	case FormatNDJSON:
+		err = cw.writeLine(header{Format: format, Version: 1, From: from})
+	case FormatBinary:
+		if _, err = cw.w.Write(binaryMagic); err == nil {
+			err = binary.Write(cw.w, binary.BigEndian, uint64(from))
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// WriteBlock appends a block to the file.
+func (cw *Writer) WriteBlock(block *blockchain.Block) error {
+	record, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+	cw.checksum.Write(record)
+	cw.count++
+
+	if cw.format == FormatNDJSON {
+		_, err = cw.w.Write(append(record, '\n'))
+		return err
+	}
+	if err := binary.Write(cw.w, binary.BigEndian, uint32(len(record))); err != nil {
+		return err
+	}
+	_, err = cw.w.Write(record)
+	return err
+}
+
+// Close writes the trailer and flushes the file. It does not close the underlying writer.
+func (cw *Writer) Close() error {
+	sum := cw.checksum.Sum(nil)
+
+	var err error
+	if cw.format == FormatNDJSON {
+		err = cw.writeLine(trailer{Count: cw.count, Checksum: hex.EncodeToString(sum)})
+	} else {
+		// A zero length marks the end of the block records.
+		if err = binary.Write(cw.w, binary.BigEndian, uint32(0)); err == nil {
+			if err = binary.Write(cw.w, binary.BigEndian, uint64(cw.count)); err == nil {
+				_, err = cw.w.Write(sum)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return cw.w.Flush()
+}
+
+// writeLine writes a JSON value followed by a newline.
+func (cw *Writer) writeLine(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = cw.w.Write(append(line, '\n'))
+	return err
+}
+
+// Reader streams blocks from a chain file in either format, detected from its first bytes.
+type Reader struct {
+	r        *bufio.Reader
+	format   Format
+	from     int
+	checksum hash.Hash
+	count    int
+	done     bool
+}
+
+// NewReader reads the file header and returns a Reader.
+func NewReader(r io.Reader) (*Reader, error) {
+	cr := &Reader{r: bufio.NewReaderSize(r, 64<<10), checksum: sha256.New()}
+
+	prefix, err := cr.r.Peek(len(binaryMagic))
+	if err != nil && len(prefix) == 0 {
+		return nil, ErrTruncated
+	}
+
+	if bytes.Equal(prefix, binaryMagic) {
+		cr.format = FormatBinary
+		cr.r.Discard(len(binaryMagic))
+		var from uint64
+		if err := binary.Read(cr.r, binary.BigEndian, &from); err != nil {
+			return nil, ErrTruncated
+		}
+		cr.from = int(from)
+		return cr, nil
+	}
+
+	var h header
+	line, err := cr.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(line, &h); err != nil || h.Format != FormatNDJSON {
+		return nil, ErrUnknownFormat
+	}
+	cr.format = FormatNDJSON
+	cr.from = h.From
+	return cr, nil
+}
+
+// Format returns the detected file format.
+func (cr *Reader) Format() Format {
+	return cr.format
+}
+
+// From returns the height of the first block in the file.
+func (cr *Reader) From() int {
+	return cr.from
+}
+
+// Next returns the next block in the file. After the last block it verifies the trailer
+// and returns io.EOF, or ErrChecksumMismatch or ErrTruncated if the file is damaged.
+func (cr *Reader) Next() (*blockchain.Block, error) {
+	if cr.done {
+		return nil, io.EOF
+	}
+
+	record, last, err := cr.nextRecord()
+	if err != nil {
+		return nil, err
+	}
+	if last {
+		cr.done = true
+		return nil, io.EOF
+	}
+
+	var block blockchain.Block
+	if err := json.Unmarshal(record, &block); err != nil {
+		return nil, fmt.Errorf("invalid block record %d: %w", cr.count, err)
+	}
+	cr.checksum.Write(record)
+	cr.count++
+	return &block, nil
+}
+
+// nextRecord returns the next encoded block, or last=true once the trailer has been read and verified.
+func (cr *Reader) nextRecord() (record []byte, last bool, err error) {
+	if cr.format == FormatNDJSON {
+		line, err := cr.readLine()
+		if err != nil {
+			return nil, false, err
+		}
+		var t trailer
+		if json.Unmarshal(line, &t) == nil && t.Checksum != "" {
+			return nil, true, cr.verify(t.Count, t.Checksum)
+		}
+		return line, false, nil
+	}
+
+	var length uint32
+	if err := binary.Read(cr.r, binary.BigEndian, &length); err != nil {
+		return nil, false, ErrTruncated
+	}
+	if length == 0 {
+		var count uint64
+		sum := make([]byte, sha256.Size)
+		if binary.Read(cr.r, binary.BigEndian, &count) != nil {
+			return nil, false, ErrTruncated
+		}
+		if _, err := io.ReadFull(cr.r, sum); err != nil {
+			return nil, false, ErrTruncated
+		}
+		return nil, true, cr.verify(int(count), hex.EncodeToString(sum))
+	}
+	if length > maxRecordSize {
+		return nil, false, fmt.Errorf("block record %d is %d bytes, exceeding %d", cr.count, length, maxRecordSize)
+	}
+	record = make([]byte, length)
+	if _, err := io.ReadFull(cr.r, record); err != nil {
+		return nil, false, ErrTruncated
+	}
+	return record, false, nil
+}
+
+// verify checks the trailer against the blocks read so far.
+func (cr *Reader) verify(count int, checksum string) error {
+	if count != cr.count || checksum != hex.EncodeToString(cr.checksum.Sum(nil)) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// readLine reads one NDJSON line of at most maxRecordSize bytes, without the newline.
+func (cr *Reader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := cr.r.ReadLine()
+		if err != nil {
+			return nil, ErrTruncated
+		}
+		line = append(line, chunk...)
+		if len(line) > maxRecordSize {
+			return nil, fmt.Errorf("record %d exceeds %d bytes", cr.count, maxRecordSize)
+		}
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
+
+// ErrDiverged is returned by Import when a block in the file differs from the local block at the same height.
+var ErrDiverged = errors.New("chain file diverges from the local chain")
+
+// ImportResult summarises an Import.
+type ImportResult struct {
+	Imported int `json:"imported"` // Blocks validated and appended to the chain.
+	Skipped  int `json:"skipped"`  // Blocks below the resume height or already present locally.
+	Height   int `json:"height"`   // Height of the chain tip after the import.
+}
+
+// Export writes the chain's blocks from the given height onwards as a chain file.
+// Exporting from the height after the tip yields a valid file without blocks.
+// Returns an error wrapping blockchain.ErrPruned, before writing anything, if a block body in the range has been pruned.
+func Export(bc *blockchain.Blockchain, w io.Writer, format Format, from int) error {
+	blocks := bc.AllBlocks()
+	if from < 0 || from > len(blocks) {
+		return fmt.Errorf("export height %d out of range for chain of %d blocks", from, len(blocks))
+	}
+	for height := from; height < len(blocks); height++ {
+		if blocks[height].Pruned {
+			return fmt.Errorf("%w at height %d", blockchain.ErrPruned, height)
+		}
+	}
+
+	cw, err := NewWriter(w, format, from)
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks[from:] {
+		if err := cw.WriteBlock(block); err != nil {
+			return err
+		}
+	}
+	return cw.Close()
+}
+
+// Import reads a chain file and appends its blocks to the chain, fully validating each one.
+// Blocks below from are skipped, so an interrupted import can be resumed; blocks the chain already
+// has must match it. Blocks appended before an error are kept.
+// Parameters:
+// - bc: The chain to import into.
+// - r: The chain file, in either format.
+// - from: The first height to import.
+// Returns:
+// - A summary of the import, valid even when an error is returned.
+func Import(bc *blockchain.Blockchain, r io.Reader, from int) (*ImportResult, error) {
+	result := &ImportResult{}
+	defer func() { result.Height = bc.Len() - 1 }()
+
+	cr, err := NewReader(r)
+	if err != nil {
+		return result, err
+	}
+
+	for height := cr.From(); ; height++ {
+		block, err := cr.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		switch length := bc.Len(); {
+		case height < from:
+			result.Skipped++
+		case height < length:
+			if bc.BlockAt(height).Hash != block.Hash {
+				return result, fmt.Errorf("%w at height %d", ErrDiverged, height)
+			}
+			result.Skipped++
+		case height > length:
+			return result, fmt.Errorf("chain file skips heights %d..%d", length, height-1)
+		default:
+			if err := bc.AppendBlock(block); err != nil {
+				return result, err
+			}
+			result.Imported++
+		}
+	}
+}
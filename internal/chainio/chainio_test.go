@@ -0,0 +1,106 @@
+package chainio
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"blockchain/internal/blockchain"
+	"blockchain/internal/blockchain/chaintest"
+)
+
+// TestExportImportRoundTrip tests that a chain survives export and import in both formats.
+func TestExportImportRoundTrip(t *testing.T) {
+	source := chaintest.AddBlocks(t, blockchain.GetBlockchain("SHA-256"), 5)
+
+	for _, format := range []Format{FormatNDJSON, FormatBinary} {
+		var file bytes.Buffer
+		if err := Export(source, &file, format, 0); err != nil {
+			t.Fatalf("%s: export failed: %v", format, err)
+		}
+
+		target := blockchain.GetBlockchain("SHA-256")
+		result, err := Import(target, &file, 0)
+		if err != nil {
+			t.Fatalf("%s: import failed: %v", format, err)
+		}
+
+		// The genesis block is shared, so it is skipped.
+		if result.Imported != 5 || result.Skipped != 1 || result.Height != 5 {
+			t.Errorf("%s: unexpected import result %+v", format, result)
+		}
+		if target.LastBlock().Hash != source.LastBlock().Hash {
+			t.Errorf("%s: imported tip %s does not match source tip %s", format, target.LastBlock().Hash, source.LastBlock().Hash)
+		}
+	}
+}
+
+// TestImportResume tests that an import can resume from a height on top of a partially imported chain.
+func TestImportResume(t *testing.T) {
+	source := chaintest.AddBlocks(t, blockchain.GetBlockchain("SHA-256"), 5)
+
+	// Import the first three blocks.
+	var partial bytes.Buffer
+	if err := Export(source, &partial, FormatNDJSON, 0); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	target := blockchain.GetBlockchain("SHA-256")
+	for _, block := range source.AllBlocks()[1:4] {
+		if err := target.AppendBlock(block); err != nil {
+			t.Fatalf("Failed to append block: %v", err)
+		}
+	}
+
+	var rest bytes.Buffer
+	if err := Export(source, &rest, FormatBinary, 4); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	result, err := Import(target, &rest, 4)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 2 || result.Height != 5 {
+		t.Errorf("Unexpected import result %+v", result)
+	}
+}
+
+// TestImportRejectsDamagedFiles tests checksum, truncation, divergence and validation failures.
+func TestImportRejectsDamagedFiles(t *testing.T) {
+	source := chaintest.AddBlocks(t, blockchain.GetBlockchain("SHA-256"), 3)
+
+	var file bytes.Buffer
+	if err := Export(source, &file, FormatNDJSON, 0); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	exported := file.Bytes()
+
+	// Corrupting a record's data makes the block invalid.
+	tampered := bytes.Replace(exported, []byte("Test Block 2"), []byte("Test Block X"), 1)
+	if _, err := Import(blockchain.GetBlockchain("SHA-256"), bytes.NewReader(tampered), 0); !errors.Is(err, blockchain.ErrInvalidBlock) {
+		t.Errorf("Expected ErrInvalidBlock for a tampered block, but got %v", err)
+	}
+
+	// Dropping the trailer is detected.
+	truncated := exported[:bytes.LastIndexByte(exported[:len(exported)-1], '\n')+1]
+	if _, err := Import(blockchain.GetBlockchain("SHA-256"), bytes.NewReader(truncated), 0); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, but got %v", err)
+	}
+
+	// A wrong checksum is detected.
+	var binaryFile bytes.Buffer
+	if err := Export(source, &binaryFile, FormatBinary, 0); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	corrupt := binaryFile.Bytes()
+	corrupt[len(corrupt)-1] ^= 0xff
+	if _, err := Import(blockchain.GetBlockchain("SHA-256"), bytes.NewReader(corrupt), 0); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, but got %v", err)
+	}
+
+	// A chain that already has different blocks is not overwritten.
+	other := blockchain.GetBlockchain("SHA-256")
+	other.AddBlock("Other Block")
+	if _, err := Import(other, bytes.NewReader(exported), 0); !errors.Is(err, ErrDiverged) {
+		t.Errorf("Expected ErrDiverged, but got %v", err)
+	}
+}
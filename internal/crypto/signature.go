@@ -0,0 +1,306 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Algorithm names a signature scheme.
+type Algorithm string
+
+const (
+	Ed25519   Algorithm = "ed25519"   // EdDSA over Curve25519 (RFC 8032).
+	Secp256k1 Algorithm = "secp256k1" // ECDSA over secp256k1 with SHA-256 and deterministic nonces (RFC 6979).
+	P256      Algorithm = "p256"      // ECDSA over NIST P-256 with SHA-256.
+)
+
+// Algorithms lists the supported signature schemes.
+var Algorithms = []Algorithm{Ed25519, Secp256k1, P256}
+
+var (
+	// ErrUnknownAlgorithm is returned for an unsupported signature scheme.
+	ErrUnknownAlgorithm = errors.New("unknown signature algorithm")
+
+	// ErrInvalidKey is returned (possibly wrapped) for a malformed public or private key.
+	ErrInvalidKey = errors.New("invalid key")
+
+	// ErrInvalidSignature is returned (possibly wrapped) when a signature is malformed, not canonical or
+	// does not match the message and public key.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Verifier checks signatures made with one public key.
+type Verifier interface {
+	// Algorithm returns the signature scheme of the key.
+	Algorithm() Algorithm
+	// PublicKey returns the canonical encoding of the public key: 32 bytes for Ed25519 and the 33-byte
+	// compressed SEC 1 point for ECDSA.
+	PublicKey() []byte
+	// Verify returns nil if signature is a canonical signature of message by the key, or an error wrapping
+	// ErrInvalidSignature otherwise.
+	Verify(message, signature []byte) error
+}
+
+// Signer signs messages with a private key. Signatures have a fixed size of 64 bytes: the Ed25519 signature,
+// or for ECDSA the big-endian r and s values of 32 bytes each, with s in the lower half of the curve order so
+// that every signature has exactly one valid encoding. ECDSA signs the SHA-256 hash of the message.
+type Signer interface {
+	Verifier
+	// PrivateKey returns the raw private key, for storage in a keystore: the 32-byte seed for Ed25519 and the
+	// 32-byte big-endian scalar for ECDSA.
+	PrivateKey() []byte
+	// Sign returns the signature of message.
+	Sign(message []byte) ([]byte, error)
+}
+
+// SignatureSize is the size of every encoded signature.
+const SignatureSize = 64
+
+// AddressSize is the size of an address in bytes; addresses are hex encoded.
+const AddressSize = 20
+
+// GenerateKey creates a new random key pair for the algorithm, reading randomness from random, or from
+// crypto/rand if random is nil.
+func GenerateKey(algorithm Algorithm, random io.Reader) (Signer, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+	switch algorithm {
+	case Ed25519:
+		_, key, err := ed25519.GenerateKey(random)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519Signer{key}, nil
+	case Secp256k1, P256:
+		// Draw scalars until one is a valid private key; for both curves the first almost always is.
+		for {
+			privateKey := make([]byte, 32)
+			if _, err := io.ReadFull(random, privateKey); err != nil {
+				return nil, err
+			}
+			if signer, err := NewSigner(algorithm, privateKey); err == nil {
+				return signer, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algorithm)
+}
+
+// NewSigner creates a Signer from a raw private key in the format returned by Signer.PrivateKey.
+func NewSigner(algorithm Algorithm, privateKey []byte) (Signer, error) {
+	if len(privateKey) != 32 {
+		return nil, fmt.Errorf("%w: %s private key must be 32 bytes, got %d", ErrInvalidKey, algorithm, len(privateKey))
+	}
+	switch algorithm {
+	case Ed25519:
+		return ed25519Signer{ed25519.NewKeyFromSeed(privateKey)}, nil
+	case Secp256k1:
+		var scalar secp256k1.ModNScalar
+		if overflow := scalar.SetByteSlice(privateKey); overflow || scalar.IsZero() {
+			return nil, fmt.Errorf("%w: secp256k1 private key out of range", ErrInvalidKey)
+		}
+		return secp256k1Signer{secp256k1.NewPrivateKey(&scalar)}, nil
+	case P256:
+		curve := elliptic.P256()
+		d := new(big.Int).SetBytes(privateKey)
+		if d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+			return nil, fmt.Errorf("%w: P-256 private key out of range", ErrInvalidKey)
+		}
+		key := &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve}, D: d}
+		key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(privateKey)
+		return p256Signer{key}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algorithm)
+}
+
+// NewVerifier creates a Verifier from a public key in the format returned by Verifier.PublicKey.
+func NewVerifier(algorithm Algorithm, publicKey []byte) (Verifier, error) {
+	switch algorithm {
+	case Ed25519:
+		if len(publicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: Ed25519 public key must be %d bytes, got %d", ErrInvalidKey, ed25519.PublicKeySize, len(publicKey))
+		}
+		return ed25519Verifier{append(ed25519.PublicKey(nil), publicKey...)}, nil
+	case Secp256k1:
+		if len(publicKey) != secp256k1.PubKeyBytesLenCompressed {
+			return nil, fmt.Errorf("%w: secp256k1 public key must be compressed", ErrInvalidKey)
+		}
+		key, err := secp256k1.ParsePubKey(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		return secp256k1Verifier{key}, nil
+	case P256:
+		curve := elliptic.P256()
+		x, y := elliptic.UnmarshalCompressed(curve, publicKey)
+		if x == nil {
+			return nil, fmt.Errorf("%w: P-256 public key must be a compressed point on the curve", ErrInvalidKey)
+		}
+		return p256Verifier{&ecdsa.PublicKey{Curve: curve, X: x, Y: y}}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algorithm)
+}
+
+// Address derives the address of a public key: the hex-encoded first 20 bytes of the SHA-256 hash of the
+// algorithm name, a zero byte and the canonical public key. The algorithm is included so that the same key
+// bytes under different schemes never share an address.
+func Address(verifier Verifier) string {
+	return DeriveAddress(verifier.Algorithm(), verifier.PublicKey())
+}
+
+// DeriveAddress derives the address of a public key given in its canonical encoding; see Address.
+func DeriveAddress(algorithm Algorithm, publicKey []byte) string {
+	input := append(append([]byte(algorithm), 0), publicKey...)
+	return hex.EncodeToString(HashSHA256Go(input)[:AddressSize])
+}
+
+// ed25519Verifier verifies Ed25519 signatures.
+type ed25519Verifier struct {
+	key ed25519.PublicKey
+}
+
+func (v ed25519Verifier) Algorithm() Algorithm { return Ed25519 }
+func (v ed25519Verifier) PublicKey() []byte    { return append([]byte(nil), v.key...) }
+
+func (v ed25519Verifier) Verify(message, signature []byte) error {
+	if len(signature) != SignatureSize || !ed25519.Verify(v.key, message, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ed25519Signer signs with an Ed25519 private key.
+type ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+func (s ed25519Signer) Algorithm() Algorithm { return Ed25519 }
+func (s ed25519Signer) PublicKey() []byte    { return append([]byte(nil), s.key.Public().(ed25519.PublicKey)...) }
+func (s ed25519Signer) PrivateKey() []byte   { return append([]byte(nil), s.key.Seed()...) }
+
+func (s ed25519Signer) Verify(message, signature []byte) error {
+	return ed25519Verifier{s.key.Public().(ed25519.PublicKey)}.Verify(message, signature)
+}
+
+func (s ed25519Signer) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, message), nil
+}
+
+// secp256k1Verifier verifies ECDSA signatures over secp256k1.
+type secp256k1Verifier struct {
+	key *secp256k1.PublicKey
+}
+
+func (v secp256k1Verifier) Algorithm() Algorithm { return Secp256k1 }
+func (v secp256k1Verifier) PublicKey() []byte    { return v.key.SerializeCompressed() }
+
+func (v secp256k1Verifier) Verify(message, signature []byte) error {
+	if len(signature) != SignatureSize {
+		return fmt.Errorf("%w: must be %d bytes", ErrInvalidSignature, SignatureSize)
+	}
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(signature[:32]) || s.SetByteSlice(signature[32:]) || r.IsZero() || s.IsZero() {
+		return fmt.Errorf("%w: r or s out of range", ErrInvalidSignature)
+	}
+	if s.IsOverHalfOrder() {
+		return fmt.Errorf("%w: s is not canonical", ErrInvalidSignature)
+	}
+	digest := sha256.Sum256(message)
+	if !secp256k1ecdsa.NewSignature(&r, &s).Verify(digest[:], v.key) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// secp256k1Signer signs with a secp256k1 private key.
+type secp256k1Signer struct {
+	key *secp256k1.PrivateKey
+}
+
+func (s secp256k1Signer) Algorithm() Algorithm { return Secp256k1 }
+func (s secp256k1Signer) PublicKey() []byte    { return s.key.PubKey().SerializeCompressed() }
+func (s secp256k1Signer) PrivateKey() []byte   { return s.key.Serialize() }
+
+func (s secp256k1Signer) Verify(message, signature []byte) error {
+	return secp256k1Verifier{s.key.PubKey()}.Verify(message, signature)
+}
+
+// Sign signs with a deterministic RFC 6979 nonce; the library already returns s in the lower half of the order.
+func (s secp256k1Signer) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	signature := secp256k1ecdsa.Sign(s.key, digest[:])
+	r, sv := signature.R(), signature.S()
+	encoded := make([]byte, SignatureSize)
+	r.PutBytesUnchecked(encoded[:32])
+	sv.PutBytesUnchecked(encoded[32:])
+	return encoded, nil
+}
+
+// p256Verifier verifies ECDSA signatures over P-256.
+type p256Verifier struct {
+	key *ecdsa.PublicKey
+}
+
+func (v p256Verifier) Algorithm() Algorithm { return P256 }
+func (v p256Verifier) PublicKey() []byte    { return elliptic.MarshalCompressed(v.key.Curve, v.key.X, v.key.Y) }
+
+func (v p256Verifier) Verify(message, signature []byte) error {
+	if len(signature) != SignatureSize {
+		return fmt.Errorf("%w: must be %d bytes", ErrInvalidSignature, SignatureSize)
+	}
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if s.Cmp(halfOrder(v.key.Curve)) > 0 {
+		return fmt.Errorf("%w: s is not canonical", ErrInvalidSignature)
+	}
+	digest := sha256.Sum256(message)
+	if !ecdsa.Verify(v.key, digest[:], r, s) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// p256Signer signs with a P-256 private key.
+type p256Signer struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s p256Signer) Algorithm() Algorithm { return P256 }
+func (s p256Signer) PublicKey() []byte    { return p256Verifier{&s.key.PublicKey}.PublicKey() }
+func (s p256Signer) PrivateKey() []byte   { return s.key.D.FillBytes(make([]byte, 32)) }
+
+func (s p256Signer) Verify(message, signature []byte) error {
+	return p256Verifier{&s.key.PublicKey}.Verify(message, signature)
+}
+
+// Sign signs with a random nonce and moves s to the lower half of the order.
+func (s p256Signer) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	r, sv, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	if sv.Cmp(halfOrder(s.key.Curve)) > 0 {
+		sv.Sub(s.key.Curve.Params().N, sv)
+	}
+	encoded := make([]byte, SignatureSize)
+	r.FillBytes(encoded[:32])
+	sv.FillBytes(encoded[32:])
+	return encoded, nil
+}
+
+// halfOrder returns half the order of curve, the largest canonical s.
+func halfOrder(curve elliptic.Curve) *big.Int {
+	return new(big.Int).Rsh(curve.Params().N, 1)
+}
@@ -0,0 +1,146 @@
+package crypto
+
+import (
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// mustHex decodes a hex string of a test vector.
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Invalid hex in test vector: %v", err)
+	}
+	return b
+}
+
+// TestSignatureVectors tests key derivation and signatures against published test vectors:
+// RFC 8032 section 7.1 (Ed25519), the RFC 6979 secp256k1 vectors used by Bitcoin libraries, and
+// RFC 6979 appendix A.2.5 (P-256 with SHA-256, s moved to the lower half of the order).
+func TestSignatureVectors(t *testing.T) {
+	vectors := []struct {
+		algorithm  Algorithm
+		privateKey string
+		publicKey  string
+		message    string
+		signature  string
+		exact      bool // Whether Sign must reproduce the signature; P-256 signing uses random nonces.
+	}{
+		{
+			algorithm:  Ed25519,
+			privateKey: "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+			publicKey:  "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+			message:    "",
+			signature:  "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+			exact:      true,
+		},
+		{
+			algorithm:  Secp256k1,
+			privateKey: "0000000000000000000000000000000000000000000000000000000000000001",
+			publicKey:  "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			message:    "Satoshi Nakamoto",
+			signature:  "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d82442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+			exact:      true,
+		},
+		{
+			algorithm:  P256,
+			privateKey: "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+			publicKey:  "0360fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6",
+			message:    "sample",
+			signature:  "efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8",
+		},
+	}
+
+	for _, vector := range vectors {
+		signer, err := NewSigner(vector.algorithm, mustHex(t, vector.privateKey))
+		if err != nil {
+			t.Fatalf("%s: failed to load private key: %v", vector.algorithm, err)
+		}
+		if got := hex.EncodeToString(signer.PublicKey()); got != vector.publicKey {
+			t.Errorf("%s: expected public key %s, but got %s", vector.algorithm, vector.publicKey, got)
+		}
+		if got := hex.EncodeToString(signer.PrivateKey()); got != vector.privateKey {
+			t.Errorf("%s: expected the private key to round-trip, but got %s", vector.algorithm, got)
+		}
+
+		signature := mustHex(t, vector.signature)
+		if vector.algorithm == P256 {
+			// The RFC signature has a high s; its canonical form uses n - s.
+			s := new(big.Int).SetBytes(signature[32:])
+			s.Sub(elliptic.P256().Params().N, s).FillBytes(signature[32:])
+			if err := signer.Verify([]byte(vector.message), mustHex(t, vector.signature)); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("%s: expected a non-canonical signature to be rejected, but got %v", vector.algorithm, err)
+			}
+		}
+
+		verifier, err := NewVerifier(vector.algorithm, mustHex(t, vector.publicKey))
+		if err != nil {
+			t.Fatalf("%s: failed to load public key: %v", vector.algorithm, err)
+		}
+		if err := verifier.Verify([]byte(vector.message), signature); err != nil {
+			t.Errorf("%s: expected the vector signature to verify, but got %v", vector.algorithm, err)
+		}
+		if err := verifier.Verify([]byte(vector.message+"!"), signature); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected a signature of another message to be rejected, but got %v", vector.algorithm, err)
+		}
+
+		if vector.exact {
+			got, err := signer.Sign([]byte(vector.message))
+			if err != nil || hex.EncodeToString(got) != hex.EncodeToString(signature) {
+				t.Errorf("%s: expected signature %x, but got %x (%v)", vector.algorithm, signature, got, err)
+			}
+		}
+	}
+}
+
+// TestSignAndVerify tests that fresh keys of every algorithm sign and verify, and that addresses differ
+// between algorithms.
+func TestSignAndVerify(t *testing.T) {
+	addresses := make(map[string]Algorithm)
+	for _, algorithm := range Algorithms {
+		signer, err := GenerateKey(algorithm, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to generate key: %v", algorithm, err)
+		}
+		message := []byte("transfer 10 to bob")
+		signature, err := signer.Sign(message)
+		if err != nil {
+			t.Fatalf("%s: failed to sign: %v", algorithm, err)
+		}
+		if len(signature) != SignatureSize {
+			t.Errorf("%s: expected a %d-byte signature, but got %d bytes", algorithm, SignatureSize, len(signature))
+		}
+
+		verifier, err := NewVerifier(algorithm, signer.PublicKey())
+		if err != nil {
+			t.Fatalf("%s: failed to load public key: %v", algorithm, err)
+		}
+		if err := verifier.Verify(message, signature); err != nil {
+			t.Errorf("%s: expected the signature to verify, but got %v", algorithm, err)
+		}
+		signature[10] ^= 1
+		if err := verifier.Verify(message, signature); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected a corrupted signature to be rejected, but got %v", algorithm, err)
+		}
+
+		address := Address(verifier)
+		if len(address) != 2*AddressSize || address != DeriveAddress(algorithm, signer.PublicKey()) {
+			t.Errorf("%s: unexpected address %q", algorithm, address)
+		}
+		addresses[address] = algorithm
+	}
+	if len(addresses) != len(Algorithms) {
+		t.Error("Expected every key to have a distinct address")
+	}
+
+	if _, err := NewSigner("rsa", make([]byte, 32)); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Expected ErrUnknownAlgorithm, but got %v", err)
+	}
+	if _, err := NewSigner(Secp256k1, make([]byte, 32)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected a zero private key to be rejected, but got %v", err)
+	}
+}
@@ -0,0 +1,385 @@
+// Package mempool holds transactions that have been submitted but not yet included in a block.
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"blockchain/internal/blockchain"
+)
+
+var (
+	// ErrDuplicate is returned by Add when the transaction is already pending.
+	ErrDuplicate = errors.New("transaction already pending")
+
+	// ErrFeeTooLow is returned by Add when the transaction's fee is below the mempool's minimum.
+	ErrFeeTooLow = errors.New("fee too low")
+
+	// ErrLockTooFar is returned by Add when the transaction is locked beyond the mempool's lock horizon.
+	ErrLockTooFar = errors.New("transaction locked too far ahead")
+
+	// ErrHeldFull is returned by Add when the held transactions are at capacity and the transaction pays no more
+	// than any of them.
+	ErrHeldFull = errors.New("too many held transactions")
+
+	// ErrPoolFull is returned by Add when the pending transactions are at capacity and the transaction pays no
+	// more than any of them.
+	ErrPoolFull = errors.New("too many pending transactions")
+)
+
+// Defaults of the pending and held transaction limits of a new Mempool.
+const (
+	DefaultMaxPending      = 10000
+	DefaultMaxHeld         = 1000
+	DefaultMaxLockBlocks   = 1000
+	DefaultMaxLockDuration = 24 * time.Hour
+)
+
+// Mempool is an ordered set of pending transactions that are valid, in order, on top of the chain's tip.
+// Transactions are checked with the chain's own state modules before they are admitted, each applied once to a
+// pending state holding the transactions admitted before it, and re-checked whenever a block is connected:
+// transactions included in the block are removed, and transactions that are no longer valid, e.g. because the
+// block spent the same outputs, are dropped. At most MaxPending transactions are pending: once full, a transaction
+// is admitted only by evicting the one paying the lowest fee, along with the pending transactions that depend on
+// it. Select takes as many pending transactions as fit in a block and leaves the rest for later blocks.
+//
+// Transactions whose lock height or lock time has not been reached are held, with only their signatures
+// checked, until the next block could include them; they are then validated and become pending like newly
+// submitted transactions. Transactions locked beyond the lock horizon, MaxLockBlocks blocks or MaxLockDuration
+// after the next block, are rejected, and at most MaxHeld transactions are held: once full, a transaction is held
+// only by evicting one paying a lower fee.
+type Mempool struct {
+	MinFee          uint64        // Transactions paying a lower fee, other than notarizations, are not admitted.
+	MaxPending      int           // Most transactions pending at once.
+	MaxHeld         int           // Most transactions held at once.
+	MaxLockBlocks   int           // Most blocks after the next one a held transaction may be locked for.
+	MaxLockDuration time.Duration // Longest time after the next block's timestamp a held transaction may be locked for.
+
+	mu    sync.Mutex
+	bc    *blockchain.Blockchain
+	txs   []*blockchain.Transaction
+	state *blockchain.PendingState // The chain's state with the pending transactions applied.
+	ids   map[string]bool          // IDs of the pending transactions.
+	spent map[string]string        // Outpoints spent by pending UTXO transactions, mapped to the spending transaction's ID.
+	held  map[string]heldTx        // Held transactions by ID.
+	seq   uint64                   // Sequence number of the next held transaction.
+}
+
+// heldTx is a held transaction with the sequence number recording when it was submitted.
+type heldTx struct {
+	tx  *blockchain.Transaction
+	seq uint64
+}
+
+// New creates an empty mempool for bc and registers it to be updated when blocks are connected.
+func New(bc *blockchain.Blockchain) *Mempool {
+	m := &Mempool{
+		MaxPending:      DefaultMaxPending,
+		MaxHeld:         DefaultMaxHeld,
+		MaxLockBlocks:   DefaultMaxLockBlocks,
+		MaxLockDuration: DefaultMaxLockDuration,
+		bc:              bc,
+		ids:             make(map[string]bool),
+		spent:           make(map[string]string),
+		state:           bc.NewPendingState(),
+		held:            make(map[string]heldTx),
+	}
+	bc.OnBlock(func(height int, block *blockchain.Block) { m.update(block) })
+	return m
+}
+
+// Add validates tx against the chain's state with the pending transactions applied, and adds it. A locked
+// transaction is held instead, after checking its signatures.
+// Returns:
+// - ErrDuplicate if tx is already pending or held.
+// - An error wrapping ErrFeeTooLow if tx pays less than MinFee. Notarizations pay no fee, so callers admitting
+// them must limit them otherwise.
+// - An error wrapping ErrLockTooFar if tx is locked beyond the lock horizon.
+// - An error wrapping ErrHeldFull if tx is locked and MaxHeld transactions paying at least as much are held.
+// - An error wrapping ErrPoolFull if MaxPending transactions paying at least as much are pending.
+// - An error wrapping blockchain.ErrDoubleSpend if tx spends an output spent by a pending transaction or
+// by the chain.
+// - An error wrapping blockchain.ErrInvalidTransaction or another state module error if tx is invalid.
+func (m *Mempool) Add(tx *blockchain.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := tx.ID()
+	if _, held := m.held[id]; held || m.ids[id] {
+		return ErrDuplicate
+	}
+	if tx.Fee < m.MinFee && tx.Notarization == nil {
+		return fmt.Errorf("%w: %d is below the minimum of %d", ErrFeeTooLow, tx.Fee, m.MinFee)
+	}
+	if !m.bc.Unlocked(tx) {
+		if tx.IsCoinbase() {
+			return fmt.Errorf("%w: a coinbase cannot be submitted", blockchain.ErrInvalidTransaction)
+		}
+		if m.bc.LockedBeyond(tx, m.MaxLockBlocks, m.MaxLockDuration) {
+			return fmt.Errorf("%w: the horizon is %d blocks or %s after the next block", ErrLockTooFar, m.MaxLockBlocks, m.MaxLockDuration)
+		}
+		if _, err := tx.Signers(); err != nil {
+			return err
+		}
+		return m.hold(tx)
+	}
+	return m.admit(tx)
+}
+
+// admit validates tx against the chain's state with the pending transactions applied, and adds it to them.
+// The caller must hold m.mu.
+func (m *Mempool) admit(tx *blockchain.Transaction) error {
+	for _, input := range tx.Inputs {
+		if other, ok := m.spent[input.String()]; ok {
+			return fmt.Errorf("%w: output %s is spent by pending transaction %s", blockchain.ErrDoubleSpend, input, other)
+		}
+	}
+	if len(m.txs) >= max(m.MaxPending, 1) {
+		return m.replace(tx)
+	}
+	err := m.state.Apply(tx)
+	if errors.Is(err, blockchain.ErrTipChanged) {
+		// A block has been connected and update has yet to run; catch up with it.
+		m.reset(m.pending())
+		err = m.state.Apply(tx)
+	}
+	if err != nil {
+		return err
+	}
+	m.add(tx)
+	return nil
+}
+
+// replace admits tx in place of the pending transaction paying the lowest fee, the latest admitted among equals,
+// and the pending transactions that are no longer valid without it. If tx is invalid, the pending transactions are
+// left as they were.
+// Returns an error wrapping ErrPoolFull if none of them pays less than tx. The caller must hold m.mu.
+func (m *Mempool) replace(tx *blockchain.Transaction) error {
+	evict := 0
+	for i, pending := range m.txs {
+		if pending.Fee <= m.txs[evict].Fee {
+			evict = i
+		}
+	}
+	if lowest := m.txs[evict].Fee; lowest >= tx.Fee {
+		return fmt.Errorf("%w: %d are pending, paying fees of at least %d", ErrPoolFull, len(m.txs), lowest)
+	}
+
+	txs := m.pending()
+	m.reset(append(txs[:evict:evict], txs[evict+1:]...))
+	if err := m.admit(tx); err != nil {
+		m.reset(txs)
+		return err
+	}
+	return nil
+}
+
+// reset rebuilds the pending state on top of the chain's tip from txs, in order, dropping the transactions that
+// are no longer valid. The caller must hold m.mu.
+func (m *Mempool) reset(txs []*blockchain.Transaction) {
+	for {
+		m.txs = nil
+		m.ids = make(map[string]bool)
+		m.spent = make(map[string]string)
+		m.state = m.bc.NewPendingState()
+		stale := false
+		for _, tx := range txs {
+			err := m.state.Apply(tx)
+			if errors.Is(err, blockchain.ErrTipChanged) {
+				stale = true
+				break
+			}
+			if err == nil {
+				m.add(tx)
+			}
+		}
+		if !stale {
+			return
+		}
+	}
+}
+
+// Pending returns the pending transactions in the order they were admitted.
+func (m *Mempool) Pending() []*blockchain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pending()
+}
+
+// hold adds tx to the held transactions, evicting the one paying the lowest fee, the latest submitted among
+// equals, if MaxHeld are held already.
+// Returns an error wrapping ErrHeldFull if none of them pays less than tx. The caller must hold m.mu.
+func (m *Mempool) hold(tx *blockchain.Transaction) error {
+	if len(m.held) >= max(m.MaxHeld, 1) {
+		var evict string
+		var lowest heldTx
+		for id, held := range m.held {
+			if evict == "" || held.tx.Fee < lowest.tx.Fee || held.tx.Fee == lowest.tx.Fee && held.seq > lowest.seq {
+				evict, lowest = id, held
+			}
+		}
+		if lowest.tx.Fee >= tx.Fee {
+			return fmt.Errorf("%w: %d are held, paying fees of at least %d", ErrHeldFull, len(m.held), lowest.tx.Fee)
+		}
+		delete(m.held, evict)
+	}
+	m.held[tx.ID()] = heldTx{tx: tx, seq: m.seq}
+	m.seq++
+	return nil
+}
+
+// Held returns the transactions held until their lock height or lock time, in the order they were submitted.
+func (m *Mempool) Held() []*blockchain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.heldInOrder()
+}
+
+// IsPending reports whether the transaction with the given ID is pending.
+func (m *Mempool) IsPending(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ids[id]
+}
+
+// IsHeld reports whether the transaction with the given ID is held until its lock height or lock time.
+func (m *Mempool) IsHeld(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, held := m.held[id]
+	return held
+}
+
+// heldInOrder returns the held transactions in the order they were submitted. The caller must hold m.mu.
+func (m *Mempool) heldInOrder() []*blockchain.Transaction {
+	held := make([]heldTx, 0, len(m.held))
+	for _, h := range m.held {
+		held = append(held, h)
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].seq < held[j].seq })
+	txs := make([]*blockchain.Transaction, len(held))
+	for i, h := range held {
+		txs[i] = h.tx
+	}
+	return txs
+}
+
+// release admits the held transactions that the next block could include, in the order they were submitted, and
+// drops those that are invalid. The caller must hold m.mu.
+func (m *Mempool) release() {
+	for _, tx := range m.heldInOrder() {
+		if m.bc.Unlocked(tx) {
+			delete(m.held, tx.ID())
+			m.admit(tx)
+		}
+	}
+}
+
+// Select returns the pending transactions in the order a block should include them. It goes through them by
+// decreasing fee, taking each that is valid after the ones taken before it, and goes through the ones it skipped
+// again for as long as that takes more, so that a transaction still comes after those it depends on, such as the
+// transactions with its sender's earlier nonces or the transactions creating the outputs it spends. Transactions
+// with equal fees keep the order they were admitted in. At most count transactions are selected, whose JSON
+// encodings take at most size bytes, plus one each to separate them; see blockchain.Blockchain.BlockSpace. The rest
+// stay pending. Held transactions that have become unlocked are admitted first.
+func (m *Mempool) Select(count, size int) []*blockchain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.release()
+
+	candidates := m.pending()
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Fee > candidates[j].Fee })
+	for {
+		selected, err := m.selectFrom(candidates, count, size)
+		if !errors.Is(err, blockchain.ErrTipChanged) {
+			return selected
+		}
+	}
+}
+
+// selectFrom selects from candidates, sorted by decreasing fee, for Select. Each pass applies every remaining
+// candidate that fits once to the same pending state.
+// Returns an error wrapping blockchain.ErrTipChanged if a block was connected meanwhile.
+func (m *Mempool) selectFrom(candidates []*blockchain.Transaction, count, size int) ([]*blockchain.Transaction, error) {
+	state := m.bc.NewPendingState()
+	selected := make([]*blockchain.Transaction, 0, min(len(candidates), max(count, 0)))
+	for len(candidates) > 0 && len(selected) < count {
+		var skipped []*blockchain.Transaction
+		for _, tx := range candidates {
+			if len(selected) == count {
+				return selected, nil
+			}
+			txSize := tx.Size() + 1
+			if txSize > size {
+				skipped = append(skipped, tx)
+				continue
+			}
+			err := state.Apply(tx)
+			if errors.Is(err, blockchain.ErrTipChanged) {
+				return nil, err
+			}
+			if err != nil {
+				skipped = append(skipped, tx)
+				continue
+			}
+			selected = append(selected, tx)
+			size -= txSize
+		}
+		if len(skipped) == len(candidates) {
+			break
+		}
+		candidates = skipped
+	}
+	return selected, nil
+}
+
+// Len returns the number of pending transactions.
+func (m *Mempool) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.txs)
+}
+
+// pending returns a copy of the pending transactions. The caller must hold m.mu.
+func (m *Mempool) pending() []*blockchain.Transaction {
+	return append([]*blockchain.Transaction(nil), m.txs...)
+}
+
+// add appends tx to the pending transactions. The caller must hold m.mu.
+func (m *Mempool) add(tx *blockchain.Transaction) {
+	id := tx.ID()
+	m.txs = append(m.txs, tx)
+	m.ids[id] = true
+	for _, input := range tx.Inputs {
+		m.spent[input.String()] = id
+	}
+}
+
+// update removes the transactions included in block, drops those that are no longer valid on top of it and
+// admits the held transactions it unlocks.
+func (m *Mempool) update(block *blockchain.Block) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	included := make(map[string]bool, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		included[tx.ID()] = true
+	}
+
+	var remaining []*blockchain.Transaction
+	for _, tx := range m.txs {
+		if !included[tx.ID()] {
+			remaining = append(remaining, tx)
+		}
+	}
+	m.reset(remaining)
+
+	for id := range included {
+		delete(m.held, id)
+	}
+	m.release()
+}
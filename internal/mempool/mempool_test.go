@@ -0,0 +1,286 @@
+package mempool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"blockchain/internal/blockchain"
+	"blockchain/internal/blockchain/chaintest"
+)
+
+// TestMempool tests admitting, rejecting and evicting UTXO transactions.
+func TestMempool(t *testing.T) {
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{
+		Timestamp: blockchain.GenesisTimestamp,
+		Ledger:    blockchain.LedgerUTXO,
+		Alloc:     map[string]uint64{chaintest.Addr("alice"): 100, chaintest.Addr("bob"): 50},
+	})
+	m := New(bc)
+	alice, bob := bc.UnspentOutputs(chaintest.Addr("alice"))[0].TxInput, bc.UnspentOutputs(chaintest.Addr("bob"))[0].TxInput
+
+	pay := chaintest.Signed(&blockchain.Transaction{Inputs: []blockchain.TxInput{alice}, Outputs: []blockchain.TxOutput{{Address: chaintest.Addr("carol"), Amount: 100}}}, chaintest.Addr("alice"))
+	if err := m.Add(pay); err != nil {
+		t.Fatalf("Failed to add a transaction: %v", err)
+	}
+	if err := m.Add(pay); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate, but got %v", err)
+	}
+	conflict := chaintest.Signed(&blockchain.Transaction{Inputs: []blockchain.TxInput{alice}, Outputs: []blockchain.TxOutput{{Address: chaintest.Addr("dave"), Amount: 100}}}, chaintest.Addr("alice"))
+	if err := m.Add(conflict); !errors.Is(err, blockchain.ErrDoubleSpend) {
+		t.Errorf("Expected ErrDoubleSpend for a conflicting transaction, but got %v", err)
+	}
+
+	// A transaction may spend the output of a pending one.
+	chained := chaintest.Signed(&blockchain.Transaction{Inputs: []blockchain.TxInput{{TxID: pay.ID(), Index: 0}}, Outputs: []blockchain.TxOutput{{Address: chaintest.Addr("dave"), Amount: 100}}}, chaintest.Addr("carol"))
+	if err := m.Add(chained); err != nil {
+		t.Fatalf("Failed to add a transaction spending a pending output: %v", err)
+	}
+	fromBob := chaintest.Signed(&blockchain.Transaction{Inputs: []blockchain.TxInput{bob}, Outputs: []blockchain.TxOutput{{Address: chaintest.Addr("erin"), Amount: 50}}}, chaintest.Addr("bob"))
+	if err := m.Add(fromBob); err != nil {
+		t.Fatalf("Failed to add a transaction: %v", err)
+	}
+	if m.Len() != 3 {
+		t.Fatalf("Expected 3 pending transactions, but got %d", m.Len())
+	}
+
+	// A block that includes the payment and spends bob's output elsewhere removes the payment, drops bob's
+	// transaction as a double spend and keeps the chained transaction, which is still valid.
+	bobElsewhere := chaintest.Signed(&blockchain.Transaction{Inputs: []blockchain.TxInput{bob}, Outputs: []blockchain.TxOutput{{Address: chaintest.Addr("frank"), Amount: 50}}}, chaintest.Addr("bob"))
+	if err := bc.AddBlock("block", pay, bobElsewhere); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	pending := m.Pending()
+	if len(pending) != 1 || pending[0].ID() != chained.ID() {
+		t.Errorf("Expected only the chained transaction to remain pending, but got %+v", pending)
+	}
+}
+
+// TestSelect tests that block assembly orders transactions by fee without breaking nonce order, and that
+// transactions below the minimum fee are not admitted.
+func TestSelect(t *testing.T) {
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{
+		Timestamp: blockchain.GenesisTimestamp,
+		Alloc:     map[string]uint64{chaintest.Addr("alice"): 100, chaintest.Addr("bob"): 100},
+	})
+	m := New(bc)
+	m.MinFee = 1
+
+	if err := m.Add(chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("carol"), Amount: 10})); !errors.Is(err, ErrFeeTooLow) {
+		t.Errorf("Expected ErrFeeTooLow for a transaction without a fee, but got %v", err)
+	}
+	aliceFirst := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("carol"), Amount: 10, Fee: 1, Nonce: 0})
+	aliceSecond := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("carol"), Amount: 10, Fee: 9, Nonce: 1})
+	bobFirst := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("carol"), Amount: 10, Fee: 5, Nonce: 0})
+	for _, tx := range []*blockchain.Transaction{aliceFirst, aliceSecond, bobFirst} {
+		if err := m.Add(tx); err != nil {
+			t.Fatalf("Failed to add a transaction: %v", err)
+		}
+	}
+
+	// Alice's second transaction pays the most but must follow her first.
+	want := []*blockchain.Transaction{bobFirst, aliceFirst, aliceSecond}
+	selected := m.Select(blockchain.MaxBlockTransactions, blockchain.MaxBlockSize)
+	if len(selected) != len(want) {
+		t.Fatalf("Expected %d selected transactions, but got %d", len(want), len(selected))
+	}
+	for i := range want {
+		if selected[i].ID() != want[i].ID() {
+			t.Errorf("Selected transaction %d pays fee %d, want the one paying %d", i, selected[i].Fee, want[i].Fee)
+		}
+	}
+	if err := bc.AddBlock("block", selected...); err != nil {
+		t.Errorf("Failed to add the selected transactions: %v", err)
+	}
+}
+
+// testClock is a blockchain.Clock set by the test.
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+// TestHeldTransactions tests that locked transactions are held until the next block could include them.
+func TestHeldTransactions(t *testing.T) {
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{
+		Timestamp: blockchain.GenesisTimestamp,
+		Alloc:     map[string]uint64{chaintest.Addr("alice"): 100},
+	})
+	clock := &testClock{now: time.Now()}
+	bc.Clock = clock
+	m := New(bc)
+
+	byHeight := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 10, LockHeight: 2})
+	byTime := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 10, Nonce: 1, LockTime: clock.now.Add(time.Hour).UnixMilli()})
+	for _, tx := range []*blockchain.Transaction{byHeight, byTime} {
+		if err := m.Add(tx); err != nil {
+			t.Fatalf("Failed to add a locked transaction: %v", err)
+		}
+		if !m.IsHeld(tx.ID()) {
+			t.Errorf("Expected a locked transaction to be held")
+		}
+	}
+	if err := m.Add(byHeight); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for a held transaction, but got %v", err)
+	}
+	if len(m.Select(blockchain.MaxBlockTransactions, blockchain.MaxBlockSize)) != 0 || len(m.Held()) != 2 {
+		t.Fatalf("Expected both transactions to stay held, but got %d pending and %d held", len(m.Pending()), len(m.Held()))
+	}
+
+	// The block at height 1 unlocks the transaction locked until height 2.
+	if err := bc.AddBlock("block"); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	if pending := m.Pending(); len(pending) != 1 || pending[0].ID() != byHeight.ID() {
+		t.Errorf("Expected the height-locked transaction to be pending, but got %+v", pending)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	selected := m.Select(blockchain.MaxBlockTransactions, blockchain.MaxBlockSize)
+	if len(selected) != 2 || len(m.Held()) != 0 {
+		t.Fatalf("Expected both transactions to be selected once unlocked, but got %d", len(selected))
+	}
+	if err := bc.AddBlock("block", selected...); err != nil {
+		t.Errorf("Failed to add the unlocked transactions: %v", err)
+	}
+}
+
+// TestHeldLimits tests that transactions locked beyond the lock horizon are rejected and that a full held pool
+// only takes a transaction by evicting one paying a lower fee.
+func TestHeldLimits(t *testing.T) {
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{
+		Timestamp: blockchain.GenesisTimestamp,
+		Alloc:     map[string]uint64{chaintest.Addr("alice"): 100},
+	})
+	clock := &testClock{now: time.Now()}
+	bc.Clock = clock
+	m := New(bc)
+	m.MaxHeld = 2
+	m.MaxLockBlocks = 10
+	m.MaxLockDuration = time.Hour
+
+	tooHigh := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 1, LockHeight: 12})
+	tooLate := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 1, LockTime: clock.now.Add(2 * time.Hour).UnixMilli()})
+	for _, tx := range []*blockchain.Transaction{tooHigh, tooLate} {
+		if err := m.Add(tx); !errors.Is(err, ErrLockTooFar) {
+			t.Errorf("Expected ErrLockTooFar for a transaction locked beyond the horizon, but got %v", err)
+		}
+	}
+
+	locked := func(nonce, fee uint64) *blockchain.Transaction {
+		return chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("bob"), Amount: 1, Fee: fee, Nonce: nonce, LockHeight: 11})
+	}
+	cheap, dear := locked(0, 1), locked(1, 3)
+	for _, tx := range []*blockchain.Transaction{cheap, dear} {
+		if err := m.Add(tx); err != nil {
+			t.Fatalf("Failed to add a locked transaction: %v", err)
+		}
+	}
+	if err := m.Add(locked(2, 1)); !errors.Is(err, ErrHeldFull) {
+		t.Errorf("Expected ErrHeldFull for a transaction paying no more than the held ones, but got %v", err)
+	}
+	better := locked(2, 2)
+	if err := m.Add(better); err != nil {
+		t.Fatalf("Failed to add a transaction paying more than a held one: %v", err)
+	}
+	held := m.Held()
+	if len(held) != 2 || held[0].ID() != dear.ID() || held[1].ID() != better.ID() || m.IsHeld(cheap.ID()) {
+		t.Errorf("Expected the cheapest transaction to be evicted, but %d are held", len(held))
+	}
+}
+
+// TestSelectLimits tests that a pool larger than a block drains over several blocks, highest fee first.
+func TestSelectLimits(t *testing.T) {
+	alloc := map[string]uint64{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		alloc[chaintest.Addr(name)] = 100
+	}
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{Timestamp: blockchain.GenesisTimestamp, Alloc: alloc})
+	m := New(bc)
+
+	fee := uint64(0)
+	for sender := range alloc {
+		fee++
+		if err := m.Add(chaintest.Signed(&blockchain.Transaction{From: sender, To: chaintest.Addr("carol"), Amount: 1, Fee: fee})); err != nil {
+			t.Fatalf("Failed to add a transaction: %v", err)
+		}
+	}
+
+	// Blocks of at most 4 transactions take 3 blocks.
+	var blocks []int
+	for m.Len() > 0 && len(blocks) < 5 {
+		selected := m.Select(4, blockchain.MaxBlockSize)
+		for i := 1; i < len(selected); i++ {
+			if selected[i].Fee > selected[i-1].Fee {
+				t.Errorf("Expected the selected transactions to pay decreasing fees, but got %d after %d", selected[i].Fee, selected[i-1].Fee)
+			}
+		}
+		if len(selected) > 0 && selected[0].Fee != fee {
+			t.Errorf("Expected the highest pending fee %d to be selected first, but got %d", fee, selected[0].Fee)
+		}
+		fee -= uint64(len(selected))
+		if err := bc.AddBlock("block", selected...); err != nil {
+			t.Fatalf("Failed to add the selected transactions: %v", err)
+		}
+		blocks = append(blocks, len(selected))
+	}
+	if len(blocks) != 3 || blocks[0] != 4 || blocks[1] != 4 || blocks[2] != 2 {
+		t.Errorf("Expected blocks of 4, 4 and 2 transactions, but got %v", blocks)
+	}
+
+	// The size bound leaves out what does not fit.
+	first := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("a"), To: chaintest.Addr("carol"), Amount: 1, Fee: 2, Nonce: 1})
+	second := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("b"), To: chaintest.Addr("carol"), Amount: 1, Fee: 1, Nonce: 1})
+	for _, tx := range []*blockchain.Transaction{first, second} {
+		if err := m.Add(tx); err != nil {
+			t.Fatalf("Failed to add a transaction: %v", err)
+		}
+	}
+	if selected := m.Select(blockchain.MaxBlockTransactions, first.Size()+second.Size()+1); len(selected) != 1 || selected[0].ID() != first.ID() {
+		t.Errorf("Expected only the first transaction to fit, but got %d", len(selected))
+	}
+	if selected := m.Select(blockchain.MaxBlockTransactions, first.Size()+second.Size()+2); len(selected) != 2 {
+		t.Errorf("Expected both transactions to fit, but got %d", len(selected))
+	}
+}
+
+// TestPendingLimit tests that a full mempool only takes a transaction by evicting the one paying the lowest fee,
+// along with those depending on it.
+func TestPendingLimit(t *testing.T) {
+	bc := blockchain.NewBlockchainFromGenesis(&blockchain.Genesis{
+		Timestamp: blockchain.GenesisTimestamp,
+		Alloc:     map[string]uint64{chaintest.Addr("alice"): 100, chaintest.Addr("bob"): 100},
+	})
+	m := New(bc)
+	m.MaxPending = 3
+
+	aliceFirst := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("carol"), Amount: 1, Fee: 1})
+	aliceSecond := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("alice"), To: chaintest.Addr("carol"), Amount: 1, Fee: 5, Nonce: 1})
+	bobFirst := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("carol"), Amount: 1, Fee: 3})
+	for _, tx := range []*blockchain.Transaction{aliceFirst, aliceSecond, bobFirst} {
+		if err := m.Add(tx); err != nil {
+			t.Fatalf("Failed to add a transaction: %v", err)
+		}
+	}
+	if err := m.Add(chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("carol"), Amount: 1, Fee: 1, Nonce: 1})); !errors.Is(err, ErrPoolFull) {
+		t.Errorf("Expected ErrPoolFull for a transaction paying no more than the pending ones, but got %v", err)
+	}
+
+	// Evicting alice's first transaction drops her second, which can no longer be included.
+	bobSecond := chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("carol"), Amount: 1, Fee: 2, Nonce: 1})
+	if err := m.Add(bobSecond); err != nil {
+		t.Fatalf("Failed to add a transaction paying more than a pending one: %v", err)
+	}
+	pending := m.Pending()
+	if len(pending) != 2 || pending[0].ID() != bobFirst.ID() || pending[1].ID() != bobSecond.ID() {
+		t.Errorf("Expected only bob's transactions to remain pending, but got %d", len(pending))
+	}
+
+	// A transaction that is invalid without the evicted one leaves the pending transactions as they were.
+	m.MaxPending = 2
+	if err := m.Add(chaintest.Signed(&blockchain.Transaction{From: chaintest.Addr("bob"), To: chaintest.Addr("carol"), Amount: 1, Fee: 9, Nonce: 2})); err == nil {
+		t.Error("Expected a transaction depending on the evicted one to be rejected")
+	}
+	if m.Len() != 2 {
+		t.Errorf("Expected the pending transactions to be restored, but %d are pending", m.Len())
+	}
+}
@@ -2,10 +2,16 @@ package network
 
 import (
 	"bufio"
+	"errors"
 	"log"
 	"net"
+	"strings"
 )
 
+// MaxMessageSize is the longest message line accepted from a peer. It leaves room for a
+// maximum-size block plus encoding overhead; peers sending longer lines are disconnected.
+const MaxMessageSize = 2 << 20
+
 // Peer represents a connection to another node in the network.
 type Peer struct {
 	Address string   // The address of the peer node.
@@ -15,6 +21,10 @@ type Peer struct {
 // Network manages all peer-to-peer connections for a node.
 type Network struct {
 	Peers map[string]*Peer // A map of connected peers, keyed by their address.
+
+	OnConnect    func(peer *Peer)                 // Called when a peer connects, e.g. to send a handshake.
+	OnDisconnect func(peer *Peer)                 // Called after a peer's connection is closed.
+	Handler      func(peer *Peer, message string) // Called for every message received; see HandleMessage.
 }
 
 // NewNetwork creates and initializes a new Network instance.
@@ -38,14 +48,34 @@ func (n *Network) ConnectToPeer(address string) error {
 		Conn:    conn,
 	}
 
-	n.Peers[address] = peer
-
-	go n.HandleConnection(peer) // Start handling the connection in a new goroutine.
+	n.AddPeer(peer)
 
 	log.Printf("Connected to peer: %s\n", address)
 	return nil
 }
 
+// AddPeer registers a connected peer, runs the OnConnect hook and starts handling its messages.
+// Parameters:
+// - peer: The newly connected peer.
+func (n *Network) AddPeer(peer *Peer) {
+	n.Peers[peer.Address] = peer
+
+	if n.OnConnect != nil {
+		n.OnConnect(peer)
+	}
+
+	go n.HandleConnection(peer) // Start handling the connection in a new goroutine.
+}
+
+// Send sends a single message to one peer.
+// Parameters:
+// - peer: The peer to send the message to.
+// - message: The message to send, without a trailing newline.
+func (n *Network) Send(peer *Peer, message string) error {
+	_, err := peer.Conn.Write([]byte(message + "\n"))
+	return err
+}
+
 // Broadcast sends a message to all connected peers.
 // Parameters:
 // - message: The message to broadcast to all peers.
@@ -63,27 +93,37 @@ func (n *Network) Broadcast(message string) {
 // - peer: The peer connection to handle.
 func (n *Network) HandleConnection(peer *Peer) {
 	defer peer.Conn.Close() // Ensure the connection is closed when done.
-	reader := bufio.NewReader(peer.Conn)
-
-	for {
-		// Read messages from the peer, one line at a time.
-		message, err := reader.ReadString('\n')
-		if err != nil {
-			log.Printf("Connection closed with peer: %s", peer.Address)
-			delete(n.Peers, peer.Address) // Remove the peer from the list if the connection is closed.
-			return
-		}
+	scanner := bufio.NewScanner(peer.Conn)
+	scanner.Buffer(make([]byte, 0, 4096), MaxMessageSize)
 
+	// Read messages from the peer, one line at a time.
+	for scanner.Scan() {
+		message := scanner.Text()
 		log.Printf("Received message from peer %s: %s", peer.Address, message)
-		n.HandleMessage(message)
+		n.HandleMessage(peer, strings.TrimSuffix(message, "\r"))
+	}
+
+	if err := scanner.Err(); errors.Is(err, bufio.ErrTooLong) {
+		log.Printf("Disconnecting peer %s: message exceeds %d bytes", peer.Address, MaxMessageSize)
+	} else {
+		log.Printf("Connection closed with peer: %s", peer.Address)
+	}
+	delete(n.Peers, peer.Address) // Remove the peer from the list if the connection is closed.
+	if n.OnDisconnect != nil {
+		n.OnDisconnect(peer)
 	}
 }
 
 // HandleMessage processes an incoming message from a peer.
 // Parameters:
-// - message: The message received from a peer.
-func (n *Network) HandleMessage(message string) {
-	// This is where you would handle different types of messages.
-	// For now, we'll just log the received message.
+// - peer: The peer the message was received from.
+// - message: The message received from a peer, without the trailing newline.
+func (n *Network) HandleMessage(peer *Peer, message string) {
+	if n.Handler != nil {
+		n.Handler(peer, message)
+		return
+	}
+
+	// Without a handler, we'll just log the received message.
 	log.Printf("Handling message: %s", message)
 }
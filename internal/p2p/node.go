@@ -4,16 +4,35 @@ import (
 	"blockchain/internal/blockchain"
 	"blockchain/internal/network"
 	"blockchain/internal/utils"
+	"fmt"
 	"log"
 	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionPrefix starts the handshake message each side sends on connect: "VERSION <unix millis> <history>".
+const versionPrefix = "VERSION "
+
+// History values advertised in the handshake: whether a node serves every block body or only recent ones.
+const (
+	historyFull   = "full"
+	historyPruned = "pruned"
 )
 
 // Node represents a single node in the P2P network.
 type Node struct {
-	Blockchain *blockchain.Blockchain // The blockchain instance managed by the node.
-	Network    *network.Network       // The network instance to handle peer connections.
-	Logger     *utils.Logger          // Logger for logging node activities.
-	Address    string                 // The address this node is listening on.
+	Blockchain *blockchain.Blockchain    // The blockchain instance managed by the node.
+	Network    *network.Network          // The network instance to handle peer connections.
+	Logger     *utils.Logger             // Logger for logging node activities.
+	Address    string                    // The address this node is listening on.
+	Clock      *blockchain.AdjustedClock // Network-adjusted clock fed by peer handshakes; optional.
+
+	peersMu     sync.Mutex      // Guards fullHistory.
+	fullHistory map[string]bool // Whether each handshaken peer serves full history, keyed by address.
 }
 
 // NewNode creates and initializes a new Node instance.
@@ -24,12 +43,17 @@ type Node struct {
 // Returns:
 // - A new Node instance.
 func NewNode(address string, blockchain *blockchain.Blockchain, logger *utils.Logger) *Node {
-	return &Node{
-		Blockchain: blockchain,
-		Network:    network.NewNetwork(),
-		Logger:     logger,
-		Address:    address,
+	n := &Node{
+		Blockchain:  blockchain,
+		Network:     network.NewNetwork(),
+		Logger:      logger,
+		Address:     address,
+		fullHistory: make(map[string]bool),
 	}
+	n.Network.OnConnect = n.sendVersion
+	n.Network.OnDisconnect = n.forgetPeer
+	n.Network.Handler = n.handlePeerMessage
+	return n
 }
 
 // Start starts the node's server to listen for incoming connections from peers.
@@ -54,8 +78,7 @@ func (n *Node) Start() {
 			Conn:    conn,
 		}
 
-		n.Network.Peers[peer.Address] = peer
-		go n.Network.HandleConnection(peer)
+		n.Network.AddPeer(peer)
 	}
 }
 
@@ -88,11 +111,83 @@ func (n *Node) HandleMessage(message string) {
 
 	// This is where different types of messages can be handled.
 	// For example, if a message contains a new block, add it to the blockchain.
-	if message[:6] == "BLOCK " {
-		blockData := message[6:]
-		n.Blockchain.AddBlockWithRust(blockData)
+	if strings.HasPrefix(message, "BLOCK ") {
+		blockData := strings.TrimPrefix(message, "BLOCK ")
+		if err := n.Blockchain.AddBlockWithRust(blockData); err != nil {
+			n.Logger.Warn("Rejected block from peer:", err)
+			return
+		}
 		n.Logger.Info("New block added with data:", blockData)
 	} else {
 		n.Logger.Warn("Unknown message type received:", message)
 	}
 }
+
+// FullHistoryPeers returns the addresses of connected peers that advertised serving every block body.
+func (n *Node) FullHistoryPeers() []string {
+	n.peersMu.Lock()
+	defer n.peersMu.Unlock()
+
+	var peers []string
+	for address, full := range n.fullHistory {
+		if full {
+			peers = append(peers, address)
+		}
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// sendVersion sends the handshake carrying this node's current time and history mode to a newly connected peer.
+func (n *Node) sendVersion(peer *network.Peer) {
+	history := historyPruned
+	if n.Blockchain.FullHistory() {
+		history = historyFull
+	}
+	message := fmt.Sprintf("%s%d %s", versionPrefix, time.Now().UnixMilli(), history)
+	if err := n.Network.Send(peer, message); err != nil {
+		n.Logger.Error("Failed to send handshake to peer:", peer.Address, err)
+	}
+}
+
+// forgetPeer drops the clock sample and history mode of a disconnected peer.
+func (n *Node) forgetPeer(peer *network.Peer) {
+	if n.Clock != nil {
+		n.Clock.RemoveSample(peer.Address)
+	}
+	n.peersMu.Lock()
+	delete(n.fullHistory, peer.Address)
+	n.peersMu.Unlock()
+}
+
+// handlePeerMessage handles handshakes itself and passes every other message to HandleMessage.
+func (n *Node) handlePeerMessage(peer *network.Peer, message string) {
+	if !strings.HasPrefix(message, versionPrefix) {
+		n.HandleMessage(message)
+		return
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(message, versionPrefix))
+	if len(fields) != 2 || (fields[1] != historyFull && fields[1] != historyPruned) {
+		n.Logger.Warn("Invalid handshake from peer:", peer.Address, message)
+		return
+	}
+	millis, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		n.Logger.Warn("Invalid handshake from peer:", peer.Address, message)
+		return
+	}
+	full := fields[1] == historyFull
+
+	n.peersMu.Lock()
+	n.fullHistory[peer.Address] = full
+	n.peersMu.Unlock()
+	if !full {
+		n.Logger.Info("Peer", peer.Address, "serves pruned history only")
+	}
+
+	if n.Clock != nil {
+		n.Clock.AddSample(peer.Address, time.UnixMilli(millis))
+		n.Logger.Info("Network time offset is now", n.Clock.Offset())
+	}
+}
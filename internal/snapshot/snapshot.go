@@ -0,0 +1,287 @@
+package snapshot
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"blockchain/internal/blockchain"
+	"blockchain/internal/utils"
+)
+
+// formatName identifies snapshot files in their header line.
+const formatName = "snapshot"
+
+// maxLineSize bounds a single line of a snapshot file.
+const maxLineSize = 2 * blockchain.MaxBlockSize
+
+var (
+	// ErrChecksumMismatch is returned when the trailer checksum does not match the file contents.
+	ErrChecksumMismatch = errors.New("snapshot checksum mismatch")
+
+	// ErrNoSnapshot is returned by Store.Latest when no snapshot has been saved yet.
+	ErrNoSnapshot = errors.New("no snapshot available")
+)
+
+// Snapshot is the state at a height together with the headers needed to verify it from genesis.
+type Snapshot struct {
+	Height    int                     // Height of the block the state belongs to.
+	BlockHash string                  // Hash of that block.
+	StateRoot string                  // State root committed in that block's header.
+	Headers   []*blockchain.Block     // Headers of blocks 0..Height, without bodies.
+	Entries   []blockchain.StateEntry // State entries sorted by key.
+}
+
+// header is the first line of a snapshot file.
+type header struct {
+	Format    string `json:"format"`
+	Version   int    `json:"version"`
+	Height    int    `json:"height"`
+	BlockHash string `json:"block_hash"`
+	StateRoot string `json:"state_root"`
+	Headers   int    `json:"headers"`
+	Entries   int    `json:"entries"`
+}
+
+// trailer is the last line of a snapshot file; the checksum covers every line between header and trailer.
+type trailer struct {
+	Checksum string `json:"checksum"`
+}
+
+// New captures a snapshot of the chain at its current tip.
+func New(bc *blockchain.Blockchain) *Snapshot {
+	headers, entries := bc.StateSnapshot()
+	tip := headers[len(headers)-1]
+	return &Snapshot{
+		Height:    len(headers) - 1,
+		BlockHash: tip.Hash,
+		StateRoot: tip.StateRoot,
+		Headers:   headers,
+		Entries:   entries,
+	}
+}
+
+// Bootstrap verifies the snapshot against its headers and creates a blockchain from it.
+// Parameters:
+// - genesis: The genesis the header chain must start with.
+// - checkpoints: Extra trusted checkpoints the header chain must match.
+func (s *Snapshot) Bootstrap(genesis *blockchain.Genesis, checkpoints []blockchain.Checkpoint) (*blockchain.Blockchain, error) {
+	if len(s.Headers) != s.Height+1 || s.Headers[s.Height].Hash != s.BlockHash {
+		return nil, fmt.Errorf("%w: headers do not end at block %d %s", blockchain.ErrSnapshotMismatch, s.Height, s.BlockHash)
+	}
+	return blockchain.NewBlockchainFromSnapshot(genesis, s.Headers, s.Entries, checkpoints)
+}
+
+// Write writes the snapshot as NDJSON: a header line, one line per block header and state entry, and a checksum trailer.
+func (s *Snapshot) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	checksum := sha256.New()
+
+	writeLine := func(v interface{}, summed bool) error {
+		line, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if summed {
+			checksum.Write(line)
+		}
+		_, err = bw.Write(append(line, '\n'))
+		return err
+	}
+
+	h := header{Format: formatName, Version: 1, Height: s.Height, BlockHash: s.BlockHash, StateRoot: s.StateRoot, Headers: len(s.Headers), Entries: len(s.Entries)}
+	if err := writeLine(h, false); err != nil {
+		return err
+	}
+	for _, block := range s.Headers {
+		if err := writeLine(block, true); err != nil {
+			return err
+		}
+	}
+	for _, entry := range s.Entries {
+		if err := writeLine(entry, true); err != nil {
+			return err
+		}
+	}
+	if err := writeLine(trailer{Checksum: hex.EncodeToString(checksum.Sum(nil))}, false); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Read reads a snapshot written by Write and verifies its checksum.
+// The contents are only trusted once Bootstrap has verified them against the header chain.
+func Read(r io.Reader) (*Snapshot, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	checksum := sha256.New()
+
+	var h header
+	if err := readLine(scanner, &h, nil); err != nil {
+		return nil, err
+	}
+	if h.Format != formatName {
+		return nil, fmt.Errorf("not a snapshot file: format %q", h.Format)
+	}
+
+	s := &Snapshot{Height: h.Height, BlockHash: h.BlockHash, StateRoot: h.StateRoot}
+	for i := 0; i < h.Headers; i++ {
+		var block blockchain.Block
+		if err := readLine(scanner, &block, checksum); err != nil {
+			return nil, err
+		}
+		s.Headers = append(s.Headers, &block)
+	}
+	for i := 0; i < h.Entries; i++ {
+		var entry blockchain.StateEntry
+		if err := readLine(scanner, &entry, checksum); err != nil {
+			return nil, err
+		}
+		s.Entries = append(s.Entries, entry)
+	}
+
+	var t trailer
+	if err := readLine(scanner, &t, nil); err != nil {
+		return nil, err
+	}
+	if t.Checksum != hex.EncodeToString(checksum.Sum(nil)) {
+		return nil, ErrChecksumMismatch
+	}
+	return s, nil
+}
+
+// readLine decodes the next line into v, adding it to checksum if one is given.
+func readLine(scanner *bufio.Scanner, v interface{}, checksum hash.Hash) error {
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return io.ErrUnexpectedEOF
+	}
+	if checksum != nil {
+		checksum.Write(scanner.Bytes())
+	}
+	return json.Unmarshal(scanner.Bytes(), v)
+}
+
+// Fetch reads a snapshot from a file path, or from the /snapshot endpoint of a peer if source is an http(s) URL.
+func Fetch(source string) (*Snapshot, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return Read(file)
+	}
+
+	resp, err := http.Get(strings.TrimSuffix(source, "/") + "/snapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching snapshot from %s: %s", source, resp.Status)
+	}
+	return Read(resp.Body)
+}
+
+// Store keeps periodic snapshots as files in a directory.
+type Store struct {
+	Dir  string // Directory holding the snapshot files.
+	Keep int    // Number of most recent snapshots to keep; older ones are deleted. Zero keeps all.
+}
+
+// Save writes a snapshot to the store and deletes snapshots beyond Keep.
+// Returns the path of the new snapshot file.
+func (st *Store) Save(s *Snapshot) (string, error) {
+	if err := os.MkdirAll(st.Dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so that a crash never leaves a partial snapshot behind.
+	path := filepath.Join(st.Dir, fmt.Sprintf("snapshot-%010d.ndjson", s.Height))
+	tmp, err := os.CreateTemp(st.Dir, "snapshot-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := s.Write(tmp); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, st.prune()
+}
+
+// Latest returns the path of the most recent snapshot, or ErrNoSnapshot.
+func (st *Store) Latest() (string, error) {
+	paths, err := st.list()
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", ErrNoSnapshot
+	}
+	return paths[len(paths)-1], nil
+}
+
+// Schedule saves a snapshot whenever a block at a multiple of interval is connected to the chain.
+func (st *Store) Schedule(bc *blockchain.Blockchain, interval int, logger *utils.Logger) {
+	bc.OnBlock(func(height int, block *blockchain.Block) {
+		if height%interval != 0 {
+			return
+		}
+		path, err := st.Save(New(bc))
+		if err != nil {
+			logger.Error("Failed to save snapshot:", err)
+			return
+		}
+		logger.Info("Saved snapshot", path)
+	})
+}
+
+// list returns the snapshot files in the store, oldest first.
+func (st *Store) list() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(st.Dir, "snapshot-*.ndjson"))
+	if err != nil {
+		return nil, err
+	}
+	// Heights are zero-padded, so lexical order is height order.
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// prune deletes all but the Keep most recent snapshots.
+func (st *Store) prune() error {
+	if st.Keep <= 0 {
+		return nil
+	}
+	paths, err := st.list()
+	if err != nil {
+		return err
+	}
+	for len(paths) > st.Keep {
+		if err := os.Remove(paths[0]); err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"blockchain/internal/blockchain"
+	"blockchain/internal/blockchain/chaintest"
+	"blockchain/internal/utils"
+)
+
+// TestBootstrapFromSnapshot tests that a chain bootstrapped from a snapshot accepts the blocks that follow it.
+func TestBootstrapFromSnapshot(t *testing.T) {
+	source := chaintest.AddBlocks(t, blockchain.GetBlockchain("SHA-256"), 3)
+
+	var file bytes.Buffer
+	if err := New(source).Write(&file); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	// The source moves on after the snapshot.
+	source.AddBlock("Test Block 4")
+	source.AddBlock("Test Block 5")
+
+	snap, err := Read(&file)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	bc, err := snap.Bootstrap(blockchain.DefaultGenesis, nil)
+	if err != nil {
+		t.Fatalf("Failed to bootstrap: %v", err)
+	}
+
+	// Block bodies before the snapshot are not available.
+	if !bc.BlockAt(1).Pruned || bc.BlockAt(1).Data != "" {
+		t.Errorf("Expected block 1 to be header-only, but got %+v", bc.BlockAt(1))
+	}
+
+	for _, block := range source.AllBlocks()[4:] {
+		if err := bc.AppendBlock(block); err != nil {
+			t.Fatalf("Failed to append block after bootstrap: %v", err)
+		}
+	}
+	if bc.LastBlock().Hash != source.LastBlock().Hash || bc.State.Root() != source.State.Root() {
+		t.Error("Expected the bootstrapped chain to match the source chain")
+	}
+	if !bc.IsChainValid() {
+		t.Errorf("Expected the bootstrapped chain to be valid, but got %+v", bc.Validate().Failures)
+	}
+}
+
+// TestBootstrapCustomGenesis tests that a chain with a custom genesis bootstraps when the node is configured with
+// that genesis, and only then.
+func TestBootstrapCustomGenesis(t *testing.T) {
+	genesis := &blockchain.Genesis{Timestamp: blockchain.GenesisTimestamp, Issuance: blockchain.IssuanceSchedule{InitialReward: 50}}
+	source := blockchain.NewBlockchainFromGenesis(genesis)
+	if err := source.AddBlock("Test Block 1"); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+
+	snap := New(source)
+	if _, err := snap.Bootstrap(genesis, nil); err != nil {
+		t.Errorf("Failed to bootstrap with the chain's own genesis: %v", err)
+	}
+	if _, err := snap.Bootstrap(blockchain.DefaultGenesis, nil); !errors.Is(err, blockchain.ErrInvalidBlock) {
+		t.Errorf("Expected ErrInvalidBlock for a snapshot of another chain, but got %v", err)
+	}
+}
+
+// TestBootstrapRejectsTamperedSnapshot tests that snapshots whose state or headers were altered are rejected.
+func TestBootstrapRejectsTamperedSnapshot(t *testing.T) {
+	snap := New(chaintest.AddBlocks(t, blockchain.GetBlockchain("SHA-256"), 3))
+	snap.Entries[0].Value = "999"
+	if _, err := snap.Bootstrap(blockchain.DefaultGenesis, nil); !errors.Is(err, blockchain.ErrSnapshotMismatch) {
+		t.Errorf("Expected ErrSnapshotMismatch for altered state, but got %v", err)
+	}
+
+	snap = New(chaintest.AddBlocks(t, blockchain.GetBlockchain("SHA-256"), 3))
+	snap.Headers[2].Timestamp++
+	if _, err := snap.Bootstrap(blockchain.DefaultGenesis, nil); !errors.Is(err, blockchain.ErrInvalidBlock) {
+		t.Errorf("Expected ErrInvalidBlock for an altered header, but got %v", err)
+	}
+
+	var file bytes.Buffer
+	New(chaintest.AddBlocks(t, blockchain.GetBlockchain("SHA-256"), 1)).Write(&file)
+	corrupt := bytes.Replace(file.Bytes(), []byte(`"value":"0"`), []byte(`"value":"1"`), 1)
+	if _, err := Read(bytes.NewReader(corrupt)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, but got %v", err)
+	}
+}
+
+// TestStore tests saving, pruning and finding the latest snapshot.
+func TestStore(t *testing.T) {
+	store := &Store{Dir: t.TempDir(), Keep: 2}
+	if _, err := store.Latest(); !errors.Is(err, ErrNoSnapshot) {
+		t.Errorf("Expected ErrNoSnapshot for an empty store, but got %v", err)
+	}
+
+	bc := blockchain.GetBlockchain("SHA-256")
+	store.Schedule(bc, 2, utils.NewLogger("Test: ", 0))
+	chaintest.AddBlocks(t, bc, 6)
+
+	paths, err := store.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("Expected 2 snapshots to be kept, but got %v", paths)
+	}
+	latest, _ := store.Latest()
+	if filepath.Base(latest) != "snapshot-0000000006.ndjson" {
+		t.Errorf("Expected the snapshot at height 6 to be the latest, but got %s", latest)
+	}
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket is the single bucket holding every key.
+var boltBucket = []byte("data")
+
+// BoltBackend stores data in an embedded bbolt database. Every Write is one bbolt transaction.
+type BoltBackend struct {
+	db *bolt.DB
+}
+
+// OpenBoltBackend opens or creates the bbolt database at path.
+// It fails after a second if another process holds the database open.
+func OpenBoltBackend(path string) (*BoltBackend, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltBackend{db: db}, nil
+}
+
+// Get returns the value stored under key, or ErrNotFound.
+func (b *BoltBackend) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := b.view(func(bucket *bolt.Bucket) error {
+		stored := bucket.Get(key)
+		if stored == nil {
+			return ErrNotFound
+		}
+		// Values are only valid for the life of the transaction.
+		value = clone(stored)
+		return nil
+	})
+	return value, err
+}
+
+// Put stores value under key.
+func (b *BoltBackend) Put(key, value []byte) error {
+	batch := NewBatch()
+	batch.Put(key, value)
+	return b.Write(batch)
+}
+
+// Delete removes key.
+func (b *BoltBackend) Delete(key []byte) error {
+	batch := NewBatch()
+	batch.Delete(key)
+	return b.Write(batch)
+}
+
+// Iterate calls fn for every key starting with prefix, in ascending key order.
+func (b *BoltBackend) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return b.view(func(bucket *bolt.Bucket) error {
+		cursor := bucket.Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			if err := fn(clone(key), clone(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Write applies the batch in a single read-write transaction.
+func (b *BoltBackend) Write(batch *Batch) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, o := range batch.ops {
+			var err error
+			if o.kind == opPut {
+				err = bucket.Put(o.key, o.value)
+			} else {
+				err = bucket.Delete(o.key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == bolt.ErrDatabaseNotOpen {
+		return ErrClosed
+	}
+	return err
+}
+
+// Close closes the database.
+func (b *BoltBackend) Close() error {
+	return b.db.Close()
+}
+
+// view runs fn in a read-only transaction on the data bucket.
+func (b *BoltBackend) view(fn func(bucket *bolt.Bucket) error) error {
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(boltBucket))
+	})
+	if err == bolt.ErrDatabaseNotOpen {
+		return ErrClosed
+	}
+	return err
+}
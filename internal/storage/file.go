@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// recordHeaderSize is the size of a log record header: the payload length and its CRC-32.
+const recordHeaderSize = 8
+
+// ErrBroken is returned by a FileBackend's writes once a failed append could not be undone.
+var ErrBroken = errors.New("storage log broken")
+
+// logFile is the part of *os.File a FileBackend uses.
+type logFile interface {
+	io.ReadWriteSeeker
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// FileBackend stores data in a single append-only log file and keeps an index of it in memory.
+// Each Write appends one checksummed record holding the whole batch and syncs the file, so a crash
+// leaves at most a torn last record, which is discarded when the file is opened again. A Write that fails is
+// truncated away at once, so that later records follow the last complete one.
+type FileBackend struct {
+	mu     sync.RWMutex
+	path   string
+	file   logFile
+	mem    *MemoryBackend // The current contents, rebuilt from the log on open.
+	closed bool
+	broken error // Why a failed append could not be undone; writes fail once it is set.
+}
+
+// OpenFileBackend opens or creates the log file at path and replays it.
+// A torn or corrupt record at the end of the file is truncated away.
+func OpenFileBackend(path string) (*FileBackend, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FileBackend{path: path, file: file, mem: NewMemoryBackend()}
+	valid, err := f.replay()
+	if err == nil {
+		// Drop anything after the last complete record and continue appending from there.
+		if err = file.Truncate(valid); err == nil {
+			_, err = file.Seek(valid, io.SeekStart)
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// replay applies every complete record in the log and returns the offset just after the last one.
+func (f *FileBackend) replay() (int64, error) {
+	r := bufio.NewReader(f.file)
+	var offset int64
+	for {
+		header := make([]byte, recordHeaderSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return offset, nil
+		}
+		length := binary.BigEndian.Uint32(header[0:4])
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return offset, nil
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return offset, nil
+		}
+
+		batch, err := decodeBatch(payload)
+		if err != nil {
+			return offset, nil
+		}
+		if err := f.mem.Write(batch); err != nil {
+			return offset, err
+		}
+		offset += recordHeaderSize + int64(length)
+	}
+}
+
+// Get returns the value stored under key, or ErrNotFound.
+func (f *FileBackend) Get(key []byte) ([]byte, error) {
+	return f.mem.Get(key)
+}
+
+// Put stores value under key.
+func (f *FileBackend) Put(key, value []byte) error {
+	batch := NewBatch()
+	batch.Put(key, value)
+	return f.Write(batch)
+}
+
+// Delete removes key.
+func (f *FileBackend) Delete(key []byte) error {
+	batch := NewBatch()
+	batch.Delete(key)
+	return f.Write(batch)
+}
+
+// Iterate calls fn for every key starting with prefix, in ascending key order.
+func (f *FileBackend) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return f.mem.Iterate(prefix, fn)
+}
+
+// Write appends the batch to the log as a single record, syncs it, and then applies it to the index.
+// If the append fails, the log is truncated back to where the record started; if that fails too, the backend
+// is broken and every later write returns an error wrapping ErrBroken.
+func (f *FileBackend) Write(batch *Batch) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrClosed
+	}
+	if f.broken != nil {
+		return f.broken
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	payload := encodeBatch(batch)
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	record = append(record, payload...)
+
+	offset, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := f.file.Write(record); err != nil {
+		return f.rollback(offset, err)
+	}
+	if err := f.file.Sync(); err != nil {
+		return f.rollback(offset, err)
+	}
+	return f.mem.Write(batch)
+}
+
+// rollback truncates the log back to offset, where a record whose append failed with err started, and returns
+// err. If the log cannot be truncated, the backend is marked broken. The caller must hold f.mu.
+func (f *FileBackend) rollback(offset int64, err error) error {
+	truncErr := f.file.Truncate(offset)
+	if truncErr == nil {
+		_, truncErr = f.file.Seek(offset, io.SeekStart)
+	}
+	if truncErr != nil {
+		f.broken = fmt.Errorf("%w: removing a failed append at offset %d: %w", ErrBroken, offset, truncErr)
+		return fmt.Errorf("%w (%w)", err, f.broken)
+	}
+	return err
+}
+
+// Compact rewrites the log so that it holds only the current contents, dropping overwritten and deleted values.
+// The new log is written to a temporary file and renamed over the old one.
+func (f *FileBackend) Compact() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrClosed
+	}
+	if f.broken != nil {
+		return f.broken
+	}
+
+	batch := NewBatch()
+	f.mem.Iterate(nil, func(key, value []byte) error {
+		batch.Put(key, value)
+		return nil
+	})
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	compacted := &FileBackend{path: tmp.Name(), file: tmp, mem: NewMemoryBackend()}
+	if err := compacted.Write(batch); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		tmp.Close()
+		return err
+	}
+	f.file.Close()
+	f.file = tmp
+	return nil
+}
+
+// Close closes the log file.
+func (f *FileBackend) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	f.mem.Close()
+	return f.file.Close()
+}
+
+// encodeBatch encodes a batch as a sequence of operations: a kind byte, the length-prefixed key and,
+// for puts, the length-prefixed value.
+func encodeBatch(batch *Batch) []byte {
+	var buf bytes.Buffer
+	for _, o := range batch.ops {
+		buf.WriteByte(byte(o.kind))
+		buf.Write(binary.AppendUvarint(nil, uint64(len(o.key))))
+		buf.Write(o.key)
+		if o.kind == opPut {
+			buf.Write(binary.AppendUvarint(nil, uint64(len(o.value))))
+			buf.Write(o.value)
+		}
+	}
+	return buf.Bytes()
+}
+
+// errCorruptRecord is returned by decodeBatch for a payload that does not decode.
+var errCorruptRecord = errors.New("corrupt log record")
+
+// decodeBatch decodes a payload written by encodeBatch.
+func decodeBatch(payload []byte) (*Batch, error) {
+	r := bytes.NewReader(payload)
+	readBytes := func() ([]byte, error) {
+		length, err := binary.ReadUvarint(r)
+		if err != nil || length > uint64(r.Len()) {
+			return nil, errCorruptRecord
+		}
+		b := make([]byte, length)
+		r.Read(b)
+		return b, nil
+	}
+
+	batch := NewBatch()
+	for r.Len() > 0 {
+		kind, _ := r.ReadByte()
+		key, err := readBytes()
+		if err != nil {
+			return nil, err
+		}
+		switch opKind(kind) {
+		case opPut:
+			value, err := readBytes()
+			if err != nil {
+				return nil, err
+			}
+			batch.Put(key, value)
+		case opDelete:
+			batch.Delete(key)
+		default:
+			return nil, errCorruptRecord
+		}
+	}
+	return batch, nil
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// faultyFile is a logFile that fails the next write after writing only part of it, and fails truncation if
+// failTruncate is set.
+type faultyFile struct {
+	logFile
+	failWrite    bool
+	failTruncate bool
+}
+
+var errInjected = errors.New("injected failure")
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.failWrite {
+		f.failWrite = false
+		n, _ := f.logFile.Write(p[:len(p)/2])
+		return n, errInjected
+	}
+	return f.logFile.Write(p)
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return errInjected
+	}
+	return f.logFile.Truncate(size)
+}
+
+// TestFileBackendShortWrite tests that a short write is removed from the log, so that the batches written after
+// it survive a reopen, and that a backend whose log cannot be repaired refuses further writes.
+func TestFileBackendShortWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	b, err := OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &faultyFile{logFile: b.file}
+	b.file = file
+
+	b.Put([]byte("before"), []byte("value"))
+	file.failWrite = true
+	if err := b.Put([]byte("torn"), []byte("value")); !errors.Is(err, errInjected) {
+		t.Fatalf("Expected the injected write failure, but got %v", err)
+	}
+	if err := b.Put([]byte("after"), []byte("value")); err != nil {
+		t.Fatalf("Failed to write after a short write: %v", err)
+	}
+	b.Close()
+
+	b, err = OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen after a short write: %v", err)
+	}
+	for _, key := range []string{"before", "after"} {
+		if _, err := b.Get([]byte(key)); err != nil {
+			t.Errorf("Expected %q to survive the short write, but got %v", key, err)
+		}
+	}
+	if _, err := b.Get([]byte("torn")); err != ErrNotFound {
+		t.Errorf("Expected the failed batch not to be stored, but got %v", err)
+	}
+
+	// A short write that cannot be truncated away breaks the backend.
+	file = &faultyFile{logFile: b.file, failWrite: true, failTruncate: true}
+	b.file = file
+	if err := b.Put([]byte("torn"), []byte("value")); !errors.Is(err, errInjected) || !errors.Is(err, ErrBroken) {
+		t.Errorf("Expected the write failure and ErrBroken, but got %v", err)
+	}
+	if err := b.Put([]byte("later"), []byte("value")); !errors.Is(err, ErrBroken) {
+		t.Errorf("Expected ErrBroken from a later write, but got %v", err)
+	}
+	b.Close()
+}
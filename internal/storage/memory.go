@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+// MemoryBackend keeps every key in memory. It is meant for tests and for nodes that do not need persistence.
+type MemoryBackend struct {
+	mu     sync.RWMutex
+	data   map[string][]byte
+	closed bool
+}
+
+// NewMemoryBackend creates an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{data: make(map[string][]byte)}
+}
+
+// Get returns the value stored under key, or ErrNotFound.
+func (m *MemoryBackend) Get(key []byte) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	value, ok := m.data[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(value), nil
+}
+
+// Put stores value under key.
+func (m *MemoryBackend) Put(key, value []byte) error {
+	batch := NewBatch()
+	batch.Put(key, value)
+	return m.Write(batch)
+}
+
+// Delete removes key.
+func (m *MemoryBackend) Delete(key []byte) error {
+	batch := NewBatch()
+	batch.Delete(key)
+	return m.Write(batch)
+}
+
+// Iterate calls fn for every key starting with prefix, in ascending key order.
+func (m *MemoryBackend) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return ErrClosed
+	}
+
+	keys := make([]string, 0, len(m.data))
+	for key := range m.data {
+		if bytes.HasPrefix([]byte(key), prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn([]byte(key), clone(m.data[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write applies the batch under a single lock, so readers never see it half applied.
+func (m *MemoryBackend) Write(batch *Batch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	for _, o := range batch.ops {
+		if o.kind == opPut {
+			m.data[string(o.key)] = o.value
+		} else {
+			delete(m.data, string(o.key))
+		}
+	}
+	return nil
+}
+
+// Close discards the data.
+func (m *MemoryBackend) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	m.data = nil
+	return nil
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by Get when a key does not exist.
+var ErrNotFound = errors.New("key not found")
+
+// ErrClosed is returned by operations on a backend that has been closed.
+var ErrClosed = errors.New("storage backend closed")
+
+// Backend is an ordered key/value store that persists the node's data.
+// Keys must not be empty and are compared byte-wise; values returned by Get and Iterate may be retained by the caller.
+type Backend interface {
+	// Get returns the value stored under key, or ErrNotFound.
+	Get(key []byte) ([]byte, error)
+
+	// Put stores value under key, replacing any existing value.
+	Put(key, value []byte) error
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(key []byte) error
+
+	// Iterate calls fn for every key starting with prefix, in ascending key order.
+	// Iteration stops at the first error returned by fn, which Iterate returns.
+	// fn must not modify the backend.
+	Iterate(prefix []byte, fn func(key, value []byte) error) error
+
+	// Write applies every operation in the batch atomically: after a crash either all or none of them are visible.
+	Write(batch *Batch) error
+
+	// Close releases the backend's resources.
+	Close() error
+}
+
+// Kind names a Backend implementation, as selected in the configuration.
+type Kind string
+
+const (
+	KindMemory Kind = "memory" // In-memory, lost on exit; see NewMemoryBackend.
+	KindFile   Kind = "file"   // Append-only log file; see OpenFileBackend.
+	KindBolt   Kind = "bolt"   // Embedded bbolt database; see OpenBoltBackend.
+)
+
+// Open opens a backend of the given kind.
+// Parameters:
+// - kind: The backend implementation to use.
+// - path: The file the backend stores its data in; ignored for KindMemory.
+func Open(kind Kind, path string) (Backend, error) {
+	switch kind {
+	case KindMemory:
+		return NewMemoryBackend(), nil
+	case KindFile:
+		return OpenFileBackend(path)
+	case KindBolt:
+		return OpenBoltBackend(path)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", kind)
+}
+
+// opKind distinguishes the operations of a Batch.
+type opKind byte
+
+const (
+	opPut    opKind = 1
+	opDelete opKind = 2
+)
+
+// op is a single operation of a Batch.
+type op struct {
+	kind  opKind
+	key   []byte
+	value []byte
+}
+
+// Batch collects puts and deletes to be applied atomically with Backend.Write.
+// Operations are applied in the order they were added.
+type Batch struct {
+	ops []op
+}
+
+// NewBatch creates an empty Batch.
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Put adds a put of value under key to the batch. The key and value are copied; a nil value is stored as empty.
+func (b *Batch) Put(key, value []byte) {
+	b.ops = append(b.ops, op{kind: opPut, key: clone(key), value: append([]byte{}, value...)})
+}
+
+// Delete adds a delete of key to the batch. The key is copied.
+func (b *Batch) Delete(key []byte) {
+	b.ops = append(b.ops, op{kind: opDelete, key: clone(key)})
+}
+
+// Len returns the number of operations in the batch.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// clone returns a copy of b that does not share its backing array; nil stays nil.
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,109 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"blockchain/internal/storage"
+	"blockchain/internal/storage/storagetest"
+)
+
+// TestMemoryBackend runs the conformance suite against the in-memory backend.
+func TestMemoryBackend(t *testing.T) {
+	storagetest.Run(t, func(path string) (storage.Backend, error) {
+		return storage.NewMemoryBackend(), nil
+	}, false)
+}
+
+// TestFileBackend runs the conformance suite against the append-only file backend.
+func TestFileBackend(t *testing.T) {
+	storagetest.Run(t, func(path string) (storage.Backend, error) {
+		return storage.OpenFileBackend(path)
+	}, true)
+}
+
+// TestBoltBackend runs the conformance suite against the bbolt backend.
+func TestBoltBackend(t *testing.T) {
+	storagetest.Run(t, func(path string) (storage.Backend, error) {
+		return storage.OpenBoltBackend(path)
+	}, true)
+}
+
+// TestFileBackendTornWrite tests that a partially written last record is discarded on open.
+func TestFileBackendTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	b, err := storage.OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Put([]byte("kept"), []byte("value"))
+	b.Close()
+	info, _ := os.Stat(path)
+
+	// Simulate a crash halfway through appending a batch.
+	b, _ = storage.OpenFileBackend(path)
+	batch := storage.NewBatch()
+	batch.Put([]byte("torn1"), []byte("value"))
+	batch.Put([]byte("torn2"), []byte("value"))
+	b.Write(batch)
+	b.Close()
+	full, _ := os.Stat(path)
+	os.Truncate(path, info.Size()+(full.Size()-info.Size())/2)
+
+	b, err = storage.OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen after a torn write: %v", err)
+	}
+	if _, err := b.Get([]byte("kept")); err != nil {
+		t.Errorf("Expected the complete record to survive, but got %v", err)
+	}
+	if _, err := b.Get([]byte("torn1")); err != storage.ErrNotFound {
+		t.Errorf("Expected no part of the torn batch to survive, but got %v", err)
+	}
+
+	// Appending after the repair must produce a readable log.
+	b.Put([]byte("after"), []byte("value"))
+	b.Close()
+	b, _ = storage.OpenFileBackend(path)
+	defer b.Close()
+	if _, err := b.Get([]byte("after")); err != nil {
+		t.Errorf("Expected a write after the repair to survive, but got %v", err)
+	}
+}
+
+// TestFileBackendCompact tests that compaction shrinks the log and keeps the current contents.
+func TestFileBackendCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	b, err := storage.OpenFileBackend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		b.Put([]byte("key"), []byte("a value that is overwritten many times"))
+	}
+	b.Put([]byte("deleted"), []byte("value"))
+	b.Delete([]byte("deleted"))
+	before, _ := os.Stat(path)
+
+	if err := b.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	b.Put([]byte("after"), []byte("value"))
+	b.Close()
+
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("Expected compaction to shrink the log from %d bytes, but it is %d bytes", before.Size(), after.Size())
+	}
+	b, _ = storage.OpenFileBackend(path)
+	defer b.Close()
+	for _, key := range []string{"key", "after"} {
+		if _, err := b.Get([]byte(key)); err != nil {
+			t.Errorf("Expected %q to survive compaction, but got %v", key, err)
+		}
+	}
+	if _, err := b.Get([]byte("deleted")); err != storage.ErrNotFound {
+		t.Errorf("Expected the deleted key to stay deleted, but got %v", err)
+	}
+}
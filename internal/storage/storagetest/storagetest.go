@@ -0,0 +1,250 @@
+// Package storagetest provides the conformance suite every storage.Backend implementation must pass.
+package storagetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"blockchain/internal/storage"
+)
+
+// Opener opens a backend storing its data at path. Opening the same path again after Close must
+// return the stored data if the backend is persistent.
+type Opener func(path string) (storage.Backend, error)
+
+// Run runs the conformance suite against the backends returned by open.
+// Parameters:
+// - t: The test to run the suite in.
+// - open: Opens a backend; each subtest uses a fresh path in a temporary directory.
+// - persistent: Whether data must survive closing and reopening the backend.
+func Run(t *testing.T, open Opener, persistent bool) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b storage.Backend)
+	}{
+		{"GetMissing", testGetMissing},
+		{"PutGet", testPutGet},
+		{"Overwrite", testOverwrite},
+		{"Delete", testDelete},
+		{"ValuesAreCopied", testValuesAreCopied},
+		{"IterateOrder", testIterateOrder},
+		{"IteratePrefix", testIteratePrefix},
+		{"IterateStops", testIterateStops},
+		{"Batch", testBatch},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := mustOpen(t, open, filepath.Join(t.TempDir(), "db"))
+			defer b.Close()
+			test.fn(t, b)
+		})
+	}
+
+	t.Run("Closed", func(t *testing.T) {
+		b := mustOpen(t, open, filepath.Join(t.TempDir(), "db"))
+		if err := b.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if err := b.Put([]byte("key"), []byte("value")); err == nil {
+			t.Error("Expected Put on a closed backend to fail")
+		}
+		if _, err := b.Get([]byte("key")); err == nil {
+			t.Error("Expected Get on a closed backend to fail")
+		}
+	})
+
+	if persistent {
+		t.Run("Reopen", testReopen(open))
+	}
+}
+
+// mustOpen opens a backend or fails the test.
+func mustOpen(t *testing.T, open Opener, path string) storage.Backend {
+	t.Helper()
+	b, err := open(path)
+	if err != nil {
+		t.Fatalf("Failed to open backend: %v", err)
+	}
+	return b
+}
+
+// expectValue fails the test unless key holds want.
+func expectValue(t *testing.T, b storage.Backend, key, want string) {
+	t.Helper()
+	value, err := b.Get([]byte(key))
+	if err != nil {
+		t.Fatalf("Get(%q) failed: %v", key, err)
+	}
+	if string(value) != want {
+		t.Errorf("Get(%q) = %q, want %q", key, value, want)
+	}
+}
+
+// expectMissing fails the test unless key is absent.
+func expectMissing(t *testing.T, b storage.Backend, key string) {
+	t.Helper()
+	if _, err := b.Get([]byte(key)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get(%q): expected ErrNotFound, got %v", key, err)
+	}
+}
+
+// keys returns the keys Iterate visits for prefix, in order.
+func keys(t *testing.T, b storage.Backend, prefix string) []string {
+	t.Helper()
+	var visited []string
+	err := b.Iterate([]byte(prefix), func(key, value []byte) error {
+		visited = append(visited, string(key))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Iterate(%q) failed: %v", prefix, err)
+	}
+	return visited
+}
+
+func testGetMissing(t *testing.T, b storage.Backend) {
+	expectMissing(t, b, "missing")
+}
+
+func testPutGet(t *testing.T, b storage.Backend) {
+	if err := b.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := b.Put([]byte("empty"), nil); err != nil {
+		t.Fatalf("Put of an empty value failed: %v", err)
+	}
+	expectValue(t, b, "key", "value")
+	expectValue(t, b, "empty", "")
+
+	// Binary keys and values must round trip unchanged.
+	binary := []byte{0, 1, 0xff, '\n'}
+	if err := b.Put(binary, binary); err != nil {
+		t.Fatalf("Put of a binary key failed: %v", err)
+	}
+	if value, err := b.Get(binary); err != nil || !bytes.Equal(value, binary) {
+		t.Errorf("Get of a binary key = %v, %v", value, err)
+	}
+}
+
+func testOverwrite(t *testing.T, b storage.Backend) {
+	b.Put([]byte("key"), []byte("first"))
+	b.Put([]byte("key"), []byte("second"))
+	expectValue(t, b, "key", "second")
+}
+
+func testDelete(t *testing.T, b storage.Backend) {
+	b.Put([]byte("key"), []byte("value"))
+	if err := b.Delete([]byte("key")); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	expectMissing(t, b, "key")
+	if err := b.Delete([]byte("missing")); err != nil {
+		t.Errorf("Delete of a missing key failed: %v", err)
+	}
+}
+
+func testValuesAreCopied(t *testing.T, b storage.Backend) {
+	key, value := []byte("key"), []byte("value")
+	b.Put(key, value)
+	key[0], value[0] = 'X', 'X'
+	expectValue(t, b, "key", "value")
+
+	got, _ := b.Get([]byte("key"))
+	got[0] = 'X'
+	expectValue(t, b, "key", "value")
+}
+
+func testIterateOrder(t *testing.T, b storage.Backend) {
+	for _, key := range []string{"b", "a", "c", "ab"} {
+		b.Put([]byte(key), []byte("value-"+key))
+	}
+	if got, want := fmt.Sprint(keys(t, b, "")), "[a ab b c]"; got != want {
+		t.Errorf("Iterate visited %s, want %s", got, want)
+	}
+
+	b.Iterate(nil, func(key, value []byte) error {
+		if string(value) != "value-"+string(key) {
+			t.Errorf("Iterate passed value %q for key %q", value, key)
+		}
+		return nil
+	})
+}
+
+func testIteratePrefix(t *testing.T, b storage.Backend) {
+	for _, key := range []string{"block/1", "block/2", "blocks", "state/1", "a"} {
+		b.Put([]byte(key), []byte("value"))
+	}
+	if got, want := fmt.Sprint(keys(t, b, "block/")), "[block/1 block/2]"; got != want {
+		t.Errorf("Iterate visited %s, want %s", got, want)
+	}
+	if got := keys(t, b, "missing/"); len(got) != 0 {
+		t.Errorf("Iterate of an unused prefix visited %v", got)
+	}
+}
+
+func testIterateStops(t *testing.T, b storage.Backend) {
+	for _, key := range []string{"a", "b", "c"} {
+		b.Put([]byte(key), []byte("value"))
+	}
+	stop := errors.New("stop")
+	visited := 0
+	err := b.Iterate(nil, func(key, value []byte) error {
+		visited++
+		return stop
+	})
+	if !errors.Is(err, stop) || visited != 1 {
+		t.Errorf("Expected Iterate to stop after the first key with its error, got %v after %d keys", err, visited)
+	}
+}
+
+func testBatch(t *testing.T, b storage.Backend) {
+	b.Put([]byte("deleted"), []byte("value"))
+
+	batch := storage.NewBatch()
+	batch.Put([]byte("a"), []byte("1"))
+	batch.Put([]byte("b"), []byte("2"))
+	batch.Delete([]byte("deleted"))
+	// Operations apply in order: the later put wins, and a delete followed by a put leaves the key.
+	batch.Put([]byte("a"), []byte("3"))
+	batch.Delete([]byte("c"))
+	batch.Put([]byte("c"), []byte("4"))
+
+	expectMissing(t, b, "a") // Nothing is applied before Write.
+	if err := b.Write(batch); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	expectValue(t, b, "a", "3")
+	expectValue(t, b, "b", "2")
+	expectValue(t, b, "c", "4")
+	expectMissing(t, b, "deleted")
+
+	if err := b.Write(storage.NewBatch()); err != nil {
+		t.Errorf("Write of an empty batch failed: %v", err)
+	}
+}
+
+// testReopen checks that data written before Close is returned after reopening the same path.
+func testReopen(open Opener) func(t *testing.T) {
+	return func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db")
+		b := mustOpen(t, open, path)
+		b.Put([]byte("kept"), []byte("value"))
+		b.Put([]byte("deleted"), []byte("value"))
+		batch := storage.NewBatch()
+		batch.Put([]byte("batched"), []byte("value"))
+		batch.Delete([]byte("deleted"))
+		b.Write(batch)
+		if err := b.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		b = mustOpen(t, open, path)
+		defer b.Close()
+		expectValue(t, b, "kept", "value")
+		expectValue(t, b, "batched", "value")
+		expectMissing(t, b, "deleted")
+	}
+}
@@ -0,0 +1,158 @@
+package vm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Assemble translates assembly source into bytecode.
+//
+// The source is a sequence of instructions separated by whitespace; a semicolon starts a comment running to
+// the end of the line. An instruction is a mnemonic, case-insensitive, followed by its operand if it has one:
+// PUSH takes a value (see ParseValue) or @label, the address of a label; DUP, SWAP and LOG take a number. A
+// token ending in a colon defines a label and assembles to JUMPDEST.
+func Assemble(source string) ([]byte, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	mnemonics := make(map[string]Opcode, len(opcodes))
+	for op, info := range opcodes {
+		mnemonics[info.name] = op
+	}
+
+	type fixup struct {
+		position int
+		label    string
+	}
+	var code []byte
+	var fixups []fixup
+	labels := make(map[string]int)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if label, ok := strings.CutSuffix(token, ":"); ok {
+			if _, ok := labels[label]; ok || label == "" {
+				return nil, fmt.Errorf("%w: label %q defined twice or empty", ErrInvalidCode, label)
+			}
+			labels[label] = len(code)
+			code = append(code, byte(JUMPDEST))
+			continue
+		}
+
+		op, ok := mnemonics[strings.ToUpper(token)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown instruction %q", ErrInvalidCode, token)
+		}
+		code = append(code, byte(op))
+		if opcodes[op].immediate == 0 {
+			continue
+		}
+		if i++; i == len(tokens) {
+			return nil, fmt.Errorf("%w: %s needs an operand", ErrInvalidCode, opcodes[op].name)
+		}
+		operand := tokens[i]
+
+		switch {
+		case op == PUSH && strings.HasPrefix(operand, "@"):
+			// Label addresses take two bytes so that the layout does not depend on them.
+			fixups = append(fixups, fixup{len(code) + 1, operand[1:]})
+			code = append(code, 2, 0, 0)
+		case op == PUSH:
+			value, err := ParseValue(operand)
+			if err != nil {
+				return nil, err
+			}
+			if len(value) > 255 {
+				return nil, fmt.Errorf("%w: PUSH of %d bytes, at most 255", ErrInvalidCode, len(value))
+			}
+			code = append(code, byte(len(value)))
+			code = append(code, value...)
+		default:
+			n, err := strconv.ParseUint(operand, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s operand %q", ErrInvalidCode, opcodes[op].name, operand)
+			}
+			code = append(code, byte(n))
+		}
+	}
+
+	for _, f := range fixups {
+		position, ok := labels[f.label]
+		if !ok {
+			return nil, fmt.Errorf("%w: undefined label %q", ErrInvalidCode, f.label)
+		}
+		if position > 0xffff {
+			return nil, fmt.Errorf("%w: label %q at %d is out of range", ErrInvalidCode, f.label, position)
+		}
+		code[f.position] = byte(position >> 8)
+		code[f.position+1] = byte(position)
+	}
+	if err := Validate(code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// ParseValue parses a literal value: a decimal number, encoded in minimal form, 0x followed by hex-encoded
+// bytes, or a double-quoted string in Go syntax.
+func ParseValue(s string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(s, "0x"):
+		value, err := hex.DecodeString(s[2:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed hex value %q", ErrInvalidCode, s)
+		}
+		return value, nil
+	case strings.HasPrefix(s, `"`):
+		value, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed string %s", ErrInvalidCode, s)
+		}
+		return []byte(value), nil
+	default:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed value %q", ErrInvalidCode, s)
+		}
+		return EncodeUint(n), nil
+	}
+}
+
+// tokenize splits assembly source into tokens, dropping comments. Double-quoted strings are single tokens.
+func tokenize(source string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(source); {
+		switch c := source[i]; {
+		case c == ';':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidCode)
+			}
+			tokens = append(tokens, source[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(source) && !unicode.IsSpace(rune(source[end])) && source[end] != ';' {
+				end++
+			}
+			tokens = append(tokens, source[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,117 @@
+package vm
+
+// Opcode is an instruction of the machine.
+type Opcode byte
+
+// Opcodes. PUSH is followed by a length byte and that many bytes of data; DUP, SWAP and LOG are followed by a
+// one-byte operand.
+const (
+	STOP Opcode = 0x00 // Stop executing successfully.
+	PUSH Opcode = 0x01 // Push the immediate data.
+	POP  Opcode = 0x02 // Remove the top value.
+	DUP  Opcode = 0x03 // DUP n: push a copy of the nth value from the top, 1 being the top.
+	SWAP Opcode = 0x04 // SWAP n: exchange the top value with the one n below it.
+
+	ADD    Opcode = 0x10 // a b -> a+b, failing on overflow.
+	SUB    Opcode = 0x11 // a b -> a-b, failing on underflow.
+	MUL    Opcode = 0x12 // a b -> a*b, failing on overflow.
+	DIV    Opcode = 0x13 // a b -> a/b, failing on division by zero.
+	MOD    Opcode = 0x14 // a b -> a%b, failing on division by zero.
+	LT     Opcode = 0x15 // a b -> a<b.
+	GT     Opcode = 0x16 // a b -> a>b.
+	EQ     Opcode = 0x17 // a b -> whether a and b are the same bytes.
+	ISZERO Opcode = 0x18 // a -> whether a is zero or false.
+	AND    Opcode = 0x19 // a b -> whether both are true.
+	OR     Opcode = 0x1a // a b -> whether either is true.
+
+	SHA256 Opcode = 0x20 // a -> SHA-256 hash of a.
+	CONCAT Opcode = 0x21 // a b -> a followed by b.
+	SIZE   Opcode = 0x22 // a -> length of a in bytes.
+	SLICE  Opcode = 0x23 // a start length -> a[start:start+length].
+
+	CALLER    Opcode = 0x30 // -> address of the caller.
+	CALLVALUE Opcode = 0x31 // -> amount transferred by the call.
+	ADDRESS   Opcode = 0x32 // -> address of the contract.
+	HEIGHT    Opcode = 0x33 // -> height of the block.
+	TIMESTAMP Opcode = 0x34 // -> timestamp of the block.
+	BALANCE   Opcode = 0x35 // address -> balance of address.
+
+	SLOAD  Opcode = 0x40 // key -> value stored under key.
+	SSTORE Opcode = 0x41 // key value -> store value under key.
+
+	JUMP     Opcode = 0x50 // dest -> continue at dest, which must be a JUMPDEST.
+	JUMPI    Opcode = 0x51 // condition dest -> continue at dest if condition is true.
+	JUMPDEST Opcode = 0x52 // Mark a jump destination.
+
+	LOG      Opcode = 0x60 // LOG n: topic1 ... topicn data -> emit a log.
+	TRANSFER Opcode = 0x61 // address amount -> transfer amount of the contract's balance to address.
+
+	RETURN Opcode = 0xf0 // a -> stop executing successfully, returning a.
+	REVERT Opcode = 0xf1 // message -> fail, undoing every change of the call.
+)
+
+// Gas costs beyond the base cost of an instruction.
+const (
+	gasPerWord  = 6  // Per 32 bytes hashed by SHA256.
+	gasPerTopic = 50 // Per topic of a LOG; LOG also costs a unit of gas per byte of its topics and data.
+)
+
+// opInfo describes an opcode.
+type opInfo struct {
+	name      string
+	immediate int    // Size of the immediate operand; PUSH has a length byte followed by the data.
+	gas       uint64 // Base cost.
+}
+
+var opcodes = map[Opcode]opInfo{
+	STOP: {"STOP", 0, 0},
+	PUSH: {"PUSH", 1, 2},
+	POP:  {"POP", 0, 1},
+	DUP:  {"DUP", 1, 2},
+	SWAP: {"SWAP", 1, 2},
+
+	ADD:    {"ADD", 0, 3},
+	SUB:    {"SUB", 0, 3},
+	MUL:    {"MUL", 0, 5},
+	DIV:    {"DIV", 0, 5},
+	MOD:    {"MOD", 0, 5},
+	LT:     {"LT", 0, 3},
+	GT:     {"GT", 0, 3},
+	EQ:     {"EQ", 0, 3},
+	ISZERO: {"ISZERO", 0, 3},
+	AND:    {"AND", 0, 3},
+	OR:     {"OR", 0, 3},
+
+	SHA256: {"SHA256", 0, 30},
+	CONCAT: {"CONCAT", 0, 3},
+	SIZE:   {"SIZE", 0, 2},
+	SLICE:  {"SLICE", 0, 3},
+
+	CALLER:    {"CALLER", 0, 2},
+	CALLVALUE: {"CALLVALUE", 0, 2},
+	ADDRESS:   {"ADDRESS", 0, 2},
+	HEIGHT:    {"HEIGHT", 0, 2},
+	TIMESTAMP: {"TIMESTAMP", 0, 2},
+	BALANCE:   {"BALANCE", 0, 20},
+
+	SLOAD:  {"SLOAD", 0, 50},
+	SSTORE: {"SSTORE", 0, 200},
+
+	JUMP:     {"JUMP", 0, 8},
+	JUMPI:    {"JUMPI", 0, 10},
+	JUMPDEST: {"JUMPDEST", 0, 1},
+
+	LOG:      {"LOG", 1, 100},
+	TRANSFER: {"TRANSFER", 0, 100},
+
+	RETURN: {"RETURN", 0, 0},
+	REVERT: {"REVERT", 0, 0},
+}
+
+// String returns the mnemonic of the opcode.
+func (op Opcode) String() string {
+	if info, ok := opcodes[op]; ok {
+		return info.name
+	}
+	return "INVALID"
+}
@@ -0,0 +1,564 @@
+// Package vm implements the deterministic stack machine that runs smart contracts.
+//
+// A contract is a sequence of one-byte opcodes, some followed by immediate operands. Values on the stack are
+// byte strings of at most MaxValueSize bytes. Arithmetic and comparisons treat values as unsigned big-endian
+// integers of at most 8 bytes and push their results in minimal form: zero and false are the empty string,
+// true is 0x01 and numbers have no leading zero bytes. Binary operators take the top of the stack as their
+// right operand.
+//
+// Every instruction costs gas, and execution fails with ErrOutOfGas when the gas runs out. A contract sees only
+// its arguments, its context and its storage; it has no access to the clock, randomness, the file system or
+// the network, so every node computes the same result.
+package vm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// MaxCodeSize is the maximum size in bytes of a contract's code.
+	MaxCodeSize = 24 << 10
+
+	// MaxStackDepth is the maximum number of values on the stack.
+	MaxStackDepth = 1024
+
+	// MaxValueSize is the maximum size in bytes of a value on the stack.
+	MaxValueSize = 1024
+
+	// MaxLogTopics is the maximum number of topics of a log.
+	MaxLogTopics = 4
+)
+
+var (
+	// ErrInvalidCode is returned (possibly wrapped) for malformed bytecode.
+	ErrInvalidCode = errors.New("invalid code")
+
+	// ErrOutOfGas is returned when execution needs more gas than it was given.
+	ErrOutOfGas = errors.New("out of gas")
+
+	// ErrReverted is returned (wrapped with the contract's message) when a contract executes REVERT.
+	ErrReverted = errors.New("reverted")
+
+	// ErrExecution is returned (possibly wrapped) when an instruction fails, e.g. on a stack underflow, an
+	// invalid jump or an arithmetic overflow.
+	ErrExecution = errors.New("execution failed")
+)
+
+// Host gives a contract access to its persistent storage and to account balances.
+type Host interface {
+	// Load returns the value stored under key in the contract's storage, or nil.
+	Load(key []byte) []byte
+
+	// Store sets key to value in the contract's storage; an empty value deletes the key.
+	Store(key, value []byte)
+
+	// Balance returns the balance of address.
+	Balance(address string) uint64
+
+	// Transfer moves amount from the contract's balance to address. The address comes straight from the stack,
+	// so the host must reject any that is not a valid address.
+	Transfer(to string, amount uint64) error
+}
+
+// Context is the environment of an execution.
+type Context struct {
+	Address   string   // Address of the contract.
+	Caller    string   // Address of the account that called the contract.
+	Value     uint64   // Amount transferred to the contract by the call.
+	Height    int      // Height of the block including the call.
+	Timestamp int64    // Timestamp of the block including the call, in Unix milliseconds.
+	Args      [][]byte // Arguments of the call, pushed so that the first is on top of the stack.
+	Gas       uint64   // Gas available to the execution.
+}
+
+// Log is an event emitted by a contract.
+type Log struct {
+	Address string   `json:"address"` // Address of the contract that emitted the log.
+	Topics  []string `json:"topics"`  // Hex-encoded topics, for filtering.
+	Data    string   `json:"data"`    // Hex-encoded data.
+}
+
+// Result is the outcome of an execution.
+type Result struct {
+	Return  []byte // Value passed to RETURN, if any.
+	GasUsed uint64 // Gas consumed, including by a failed execution.
+	Logs    []Log  // Logs emitted; none if the execution failed.
+}
+
+// machine is the state of an execution.
+type machine struct {
+	code      []byte
+	jumpdests map[int]bool
+	ctx       *Context
+	host      Host
+	pc        int
+	gas       uint64
+	stack     [][]byte
+	logs      []Log
+	ret       []byte
+}
+
+// Execute runs code in ctx against host. The result is returned even if execution fails, so that the gas
+// used can be charged.
+func Execute(code []byte, ctx *Context, host Host) (*Result, error) {
+	jumpdests, err := analyze(code)
+	if err != nil {
+		return &Result{}, err
+	}
+	m := &machine{code: code, jumpdests: jumpdests, ctx: ctx, host: host, gas: ctx.Gas}
+	for i := len(ctx.Args) - 1; i >= 0; i-- {
+		if err := m.push(ctx.Args[i]); err != nil {
+			return &Result{}, err
+		}
+	}
+
+	err = m.run()
+	result := &Result{Return: m.ret, GasUsed: ctx.Gas - m.gas}
+	if err == nil {
+		result.Logs = m.logs
+	}
+	return result, err
+}
+
+// Validate checks that code is well formed: every opcode is known and every immediate operand is complete
+// and in range.
+func Validate(code []byte) error {
+	_, err := analyze(code)
+	return err
+}
+
+// analyze validates code and returns the positions of its JUMPDEST instructions.
+func analyze(code []byte) (map[int]bool, error) {
+	if len(code) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidCode)
+	}
+	if len(code) > MaxCodeSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrInvalidCode, len(code), MaxCodeSize)
+	}
+	jumpdests := make(map[int]bool)
+	for pc := 0; pc < len(code); {
+		op := Opcode(code[pc])
+		info, ok := opcodes[op]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown opcode 0x%02x at %d", ErrInvalidCode, byte(op), pc)
+		}
+		if pc+1+info.immediate > len(code) {
+			return nil, fmt.Errorf("%w: %s at %d is truncated", ErrInvalidCode, info.name, pc)
+		}
+		size := 1 + info.immediate
+		switch op {
+		case PUSH:
+			size += int(code[pc+1])
+			if pc+size > len(code) {
+				return nil, fmt.Errorf("%w: PUSH at %d is truncated", ErrInvalidCode, pc)
+			}
+		case DUP, SWAP:
+			if n := code[pc+1]; n < 1 || n > 16 {
+				return nil, fmt.Errorf("%w: %s %d at %d, want 1 to 16", ErrInvalidCode, info.name, n, pc)
+			}
+		case LOG:
+			if n := code[pc+1]; n > MaxLogTopics {
+				return nil, fmt.Errorf("%w: LOG %d at %d, want 0 to %d", ErrInvalidCode, n, pc, MaxLogTopics)
+			}
+		case JUMPDEST:
+			jumpdests[pc] = true
+		}
+		pc += size
+	}
+	return jumpdests, nil
+}
+
+// run executes instructions until the code ends, STOP, RETURN or an error.
+func (m *machine) run() error {
+	for m.pc < len(m.code) {
+		op := Opcode(m.code[m.pc])
+		info := opcodes[op]
+		if err := m.useGas(info.gas); err != nil {
+			return err
+		}
+		next := m.pc + 1 + info.immediate
+
+		switch op {
+		case STOP:
+			return nil
+
+		case PUSH:
+			n := int(m.code[m.pc+1])
+			next += n
+			if err := m.push(append([]byte(nil), m.code[m.pc+2:m.pc+2+n]...)); err != nil {
+				return err
+			}
+
+		case POP:
+			if _, err := m.pop(); err != nil {
+				return err
+			}
+
+		case DUP:
+			n := int(m.code[m.pc+1])
+			if len(m.stack) < n {
+				return m.fail("stack underflow")
+			}
+			if err := m.push(m.stack[len(m.stack)-n]); err != nil {
+				return err
+			}
+
+		case SWAP:
+			n := int(m.code[m.pc+1])
+			if len(m.stack) < n+1 {
+				return m.fail("stack underflow")
+			}
+			top := len(m.stack) - 1
+			m.stack[top], m.stack[top-n] = m.stack[top-n], m.stack[top]
+
+		case ADD, SUB, MUL, DIV, MOD, LT, GT:
+			b, err := m.popUint()
+			if err != nil {
+				return err
+			}
+			a, err := m.popUint()
+			if err != nil {
+				return err
+			}
+			result, err := m.arithmetic(op, a, b)
+			if err != nil {
+				return err
+			}
+			m.push(result)
+
+		case EQ:
+			b, a, err := m.pop2()
+			if err != nil {
+				return err
+			}
+			m.push(encodeBool(bytes.Equal(a, b)))
+
+		case ISZERO:
+			a, err := m.pop()
+			if err != nil {
+				return err
+			}
+			m.push(encodeBool(isZero(a)))
+
+		case AND, OR:
+			b, a, err := m.pop2()
+			if err != nil {
+				return err
+			}
+			if op == AND {
+				m.push(encodeBool(!isZero(a) && !isZero(b)))
+			} else {
+				m.push(encodeBool(!isZero(a) || !isZero(b)))
+			}
+
+		case SHA256:
+			a, err := m.pop()
+			if err != nil {
+				return err
+			}
+			if err := m.useGas(uint64(len(a)+31) / 32 * gasPerWord); err != nil {
+				return err
+			}
+			hash := sha256.Sum256(a)
+			m.push(hash[:])
+
+		case CONCAT:
+			b, a, err := m.pop2()
+			if err != nil {
+				return err
+			}
+			if err := m.push(append(append([]byte(nil), a...), b...)); err != nil {
+				return err
+			}
+
+		case SIZE:
+			a, err := m.pop()
+			if err != nil {
+				return err
+			}
+			m.push(EncodeUint(uint64(len(a))))
+
+		case SLICE:
+			length, err := m.popUint()
+			if err != nil {
+				return err
+			}
+			start, err := m.popUint()
+			if err != nil {
+				return err
+			}
+			a, err := m.pop()
+			if err != nil {
+				return err
+			}
+			if start > uint64(len(a)) || length > uint64(len(a))-start {
+				return m.fail(fmt.Sprintf("slice [%d:+%d] out of range of %d bytes", start, length, len(a)))
+			}
+			m.push(append([]byte(nil), a[start:start+length]...))
+
+		case CALLER:
+			if err := m.push([]byte(m.ctx.Caller)); err != nil {
+				return err
+			}
+		case CALLVALUE:
+			if err := m.push(EncodeUint(m.ctx.Value)); err != nil {
+				return err
+			}
+		case ADDRESS:
+			if err := m.push([]byte(m.ctx.Address)); err != nil {
+				return err
+			}
+		case HEIGHT:
+			if err := m.push(EncodeUint(uint64(m.ctx.Height))); err != nil {
+				return err
+			}
+		case TIMESTAMP:
+			if err := m.push(EncodeUint(uint64(m.ctx.Timestamp))); err != nil {
+				return err
+			}
+
+		case BALANCE:
+			address, err := m.pop()
+			if err != nil {
+				return err
+			}
+			m.push(EncodeUint(m.host.Balance(string(address))))
+
+		case SLOAD:
+			key, err := m.pop()
+			if err != nil {
+				return err
+			}
+			m.push(m.host.Load(key))
+
+		case SSTORE:
+			value, key, err := m.pop2()
+			if err != nil {
+				return err
+			}
+			m.host.Store(key, value)
+
+		case JUMP:
+			dest, err := m.popUint()
+			if err != nil {
+				return err
+			}
+			if next, err = m.jump(dest); err != nil {
+				return err
+			}
+
+		case JUMPI:
+			dest, err := m.popUint()
+			if err != nil {
+				return err
+			}
+			condition, err := m.pop()
+			if err != nil {
+				return err
+			}
+			if !isZero(condition) {
+				if next, err = m.jump(dest); err != nil {
+					return err
+				}
+			}
+
+		case JUMPDEST:
+
+		case LOG:
+			n := int(m.code[m.pc+1])
+			data, err := m.pop()
+			if err != nil {
+				return err
+			}
+			log := Log{Address: m.ctx.Address, Topics: make([]string, n), Data: hex.EncodeToString(data)}
+			size := len(data)
+			for i := 0; i < n; i++ {
+				topic, err := m.pop()
+				if err != nil {
+					return err
+				}
+				log.Topics[n-1-i] = hex.EncodeToString(topic)
+				size += len(topic)
+			}
+			if err := m.useGas(uint64(n)*gasPerTopic + uint64(size)); err != nil {
+				return err
+			}
+			m.logs = append(m.logs, log)
+
+		case TRANSFER:
+			amount, err := m.popUint()
+			if err != nil {
+				return err
+			}
+			to, err := m.pop()
+			if err != nil {
+				return err
+			}
+			if err := m.host.Transfer(string(to), amount); err != nil {
+				return m.fail(err.Error())
+			}
+
+		case RETURN:
+			value, err := m.pop()
+			if err != nil {
+				return err
+			}
+			m.ret = value
+			return nil
+
+		case REVERT:
+			message, err := m.pop()
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %s", ErrReverted, message)
+		}
+		m.pc = next
+	}
+	return nil
+}
+
+// arithmetic applies a binary numeric operator.
+func (m *machine) arithmetic(op Opcode, a, b uint64) ([]byte, error) {
+	switch op {
+	case ADD:
+		if a > math.MaxUint64-b {
+			return nil, m.fail("addition overflows")
+		}
+		return EncodeUint(a + b), nil
+	case SUB:
+		if a < b {
+			return nil, m.fail("subtraction underflows")
+		}
+		return EncodeUint(a - b), nil
+	case MUL:
+		if a != 0 && b > math.MaxUint64/a {
+			return nil, m.fail("multiplication overflows")
+		}
+		return EncodeUint(a * b), nil
+	case DIV, MOD:
+		if b == 0 {
+			return nil, m.fail("division by zero")
+		}
+		if op == DIV {
+			return EncodeUint(a / b), nil
+		}
+		return EncodeUint(a % b), nil
+	case LT:
+		return encodeBool(a < b), nil
+	default: // GT
+		return encodeBool(a > b), nil
+	}
+}
+
+// useGas consumes gas, failing with ErrOutOfGas if there is not enough left.
+func (m *machine) useGas(gas uint64) error {
+	if gas > m.gas {
+		m.gas = 0
+		return ErrOutOfGas
+	}
+	m.gas -= gas
+	return nil
+}
+
+// jump returns dest if it is the position of a JUMPDEST instruction.
+func (m *machine) jump(dest uint64) (int, error) {
+	if dest >= uint64(len(m.code)) || !m.jumpdests[int(dest)] {
+		return 0, m.fail(fmt.Sprintf("jump to %d, which is not a JUMPDEST", dest))
+	}
+	return int(dest), nil
+}
+
+// push pushes a value onto the stack.
+func (m *machine) push(value []byte) error {
+	if len(m.stack) >= MaxStackDepth {
+		return m.fail("stack overflow")
+	}
+	if len(value) > MaxValueSize {
+		return m.fail(fmt.Sprintf("value of %d bytes exceeds %d", len(value), MaxValueSize))
+	}
+	m.stack = append(m.stack, value)
+	return nil
+}
+
+// pop removes the value on top of the stack.
+func (m *machine) pop() ([]byte, error) {
+	if len(m.stack) == 0 {
+		return nil, m.fail("stack underflow")
+	}
+	value := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return value, nil
+}
+
+// pop2 removes the two values on top of the stack, the top one first.
+func (m *machine) pop2() (top, second []byte, err error) {
+	if top, err = m.pop(); err != nil {
+		return nil, nil, err
+	}
+	if second, err = m.pop(); err != nil {
+		return nil, nil, err
+	}
+	return top, second, nil
+}
+
+// popUint removes the value on top of the stack and decodes it as a number.
+func (m *machine) popUint() (uint64, error) {
+	value, err := m.pop()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := DecodeUint(value)
+	if !ok {
+		return 0, m.fail(fmt.Sprintf("value of %d bytes is not a number", len(value)))
+	}
+	return n, nil
+}
+
+// fail returns an error wrapping ErrExecution that names the failing instruction.
+func (m *machine) fail(reason string) error {
+	return fmt.Errorf("%w: %s at %d: %s", ErrExecution, opcodes[Opcode(m.code[m.pc])].name, m.pc, reason)
+}
+
+// EncodeUint returns the minimal big-endian encoding of n; zero is the empty string.
+func EncodeUint(n uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	return bytes.TrimLeft(buf[:], "\x00")
+}
+
+// DecodeUint decodes a big-endian number of at most 8 bytes. Leading zero bytes are allowed.
+func DecodeUint(value []byte) (uint64, bool) {
+	if len(value) > 8 {
+		return 0, false
+	}
+	var n uint64
+	for _, b := range value {
+		n = n<<8 | uint64(b)
+	}
+	return n, true
+}
+
+// encodeBool returns 0x01 for true and the empty string for false.
+func encodeBool(b bool) []byte {
+	if b {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+// isZero reports whether value is zero or false: empty or made only of zero bytes.
+func isZero(value []byte) bool {
+	for _, b := range value {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
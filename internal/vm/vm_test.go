@@ -0,0 +1,184 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testHost is an in-memory Host for a contract at contractAddress.
+type testHost struct {
+	storage  map[string][]byte
+	balances map[string]uint64
+}
+
+const contractAddress = "contract"
+
+func newTestHost() *testHost {
+	return &testHost{storage: make(map[string][]byte), balances: make(map[string]uint64)}
+}
+
+func (h *testHost) Load(key []byte) []byte { return h.storage[string(key)] }
+
+func (h *testHost) Store(key, value []byte) {
+	if len(value) == 0 {
+		delete(h.storage, string(key))
+		return
+	}
+	h.storage[string(key)] = value
+}
+
+func (h *testHost) Balance(address string) uint64 { return h.balances[address] }
+
+func (h *testHost) Transfer(to string, amount uint64) error {
+	if h.balances[contractAddress] < amount {
+		return fmt.Errorf("insufficient balance")
+	}
+	h.balances[contractAddress] -= amount
+	h.balances[to] += amount
+	return nil
+}
+
+// counter stores a count that "inc" increments, logging the new count, and "get" returns.
+const counter = `
+	DUP 1 PUSH "inc" EQ PUSH @inc JUMPI
+	DUP 1 PUSH "get" EQ PUSH @get JUMPI
+	PUSH "unknown function" REVERT
+
+inc:
+	PUSH "count" PUSH "count" SLOAD PUSH 1 ADD ; "count" count+1
+	DUP 1 PUSH "incremented" SWAP 1 LOG 1
+	SSTORE STOP
+
+get:
+	PUSH "count" SLOAD RETURN
+`
+
+// TestCounter tests assembling and running a contract that keeps state in storage and emits logs.
+func TestCounter(t *testing.T) {
+	code, err := Assemble(counter)
+	if err != nil {
+		t.Fatalf("Failed to assemble: %v", err)
+	}
+	host := newTestHost()
+	call := func(function string) (*Result, error) {
+		return Execute(code, &Context{Address: contractAddress, Args: [][]byte{[]byte(function)}, Gas: 10000}, host)
+	}
+
+	for i := 1; i <= 3; i++ {
+		result, err := call("inc")
+		if err != nil {
+			t.Fatalf("Failed to increment: %v", err)
+		}
+		if len(result.Logs) != 1 || result.Logs[0].Topics[0] != fmt.Sprintf("%x", "incremented") ||
+			result.Logs[0].Data != fmt.Sprintf("%02x", i) || result.Logs[0].Address != contractAddress {
+			t.Errorf("Expected a log of count %d, but got %+v", i, result.Logs)
+		}
+		if result.GasUsed == 0 {
+			t.Errorf("Expected gas to be used")
+		}
+	}
+	result, err := call("get")
+	if err != nil || !bytes.Equal(result.Return, []byte{3}) {
+		t.Errorf("Expected get to return 3, but got %x (%v)", result.Return, err)
+	}
+
+	result, err = call("set")
+	if !errors.Is(err, ErrReverted) || len(result.Logs) != 0 {
+		t.Errorf("Expected an unknown function to revert, but got %v", err)
+	}
+
+	result, err = Execute(code, &Context{Args: [][]byte{[]byte("inc")}, Gas: 100}, host)
+	if !errors.Is(err, ErrOutOfGas) || result.GasUsed != 100 || len(result.Logs) != 0 {
+		t.Errorf("Expected to run out of gas using all of it, but got %v using %d", err, result.GasUsed)
+	}
+}
+
+// TestPayout tests a contract that forwards what it is paid to an address given as an argument.
+func TestPayout(t *testing.T) {
+	code, err := Assemble(`
+		CALLVALUE TRANSFER      ; pay the first argument
+		ADDRESS BALANCE RETURN  ; return what is left
+	`)
+	if err != nil {
+		t.Fatalf("Failed to assemble: %v", err)
+	}
+	host := newTestHost()
+	host.balances[contractAddress] = 15
+	ctx := &Context{Address: contractAddress, Caller: "alice", Value: 10, Args: [][]byte{[]byte("bob")}, Gas: 1000}
+	result, err := Execute(code, ctx, host)
+	if err != nil || host.balances["bob"] != 10 || !bytes.Equal(result.Return, []byte{5}) {
+		t.Errorf("Expected bob to be paid 10 leaving 5, but got %d and %x (%v)", host.balances["bob"], result.Return, err)
+	}
+	ctx.Value = 10
+	if _, err := Execute(code, ctx, host); !errors.Is(err, ErrExecution) {
+		t.Errorf("Expected a transfer beyond the balance to fail, but got %v", err)
+	}
+}
+
+// TestExecutionErrors tests that failing instructions and malformed code are rejected.
+func TestExecutionErrors(t *testing.T) {
+	for _, source := range []string{
+		"POP",
+		"PUSH 1 PUSH 0 DIV",
+		"PUSH 1 PUSH 2 SUB",
+		"PUSH 0xffffffffffffffff PUSH 1 ADD",
+		"PUSH 0x010203040506070809 PUSH 1 ADD",
+		"PUSH 3 JUMP STOP",
+		`PUSH "abc" PUSH 2 PUSH 2 SLICE`,
+		"PUSH 1 DUP 2",
+	} {
+		code, err := Assemble(source)
+		if err != nil {
+			t.Fatalf("Failed to assemble %q: %v", source, err)
+		}
+		if _, err := Execute(code, &Context{Gas: 1000}, newTestHost()); !errors.Is(err, ErrExecution) {
+			t.Errorf("Expected %q to fail, but got %v", source, err)
+		}
+	}
+
+	for _, code := range [][]byte{
+		nil,
+		{0xff},
+		{byte(PUSH), 3, 1},
+		{byte(DUP), 0},
+		{byte(LOG), 5},
+	} {
+		if err := Validate(code); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("Expected %x to be invalid, but got %v", code, err)
+		}
+	}
+	for _, source := range []string{"PUSH", "PUSH @missing", "FOO", "a: a:", `PUSH "open`} {
+		if _, err := Assemble(source); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("Expected %q not to assemble, but got %v", source, err)
+		}
+	}
+
+	// A loop runs until the gas runs out.
+	code, _ := Assemble("loop: PUSH @loop JUMP")
+	if _, err := Execute(code, &Context{Gas: 1000}, newTestHost()); !errors.Is(err, ErrOutOfGas) {
+		t.Errorf("Expected an endless loop to run out of gas, but got %v", err)
+	}
+}
+
+// TestLogGas tests that LOG charges for the bytes of its topics like those of its data.
+func TestLogGas(t *testing.T) {
+	gasUsed := func(topic string) uint64 {
+		code, err := Assemble(fmt.Sprintf(`PUSH %q PUSH "data" LOG 1 STOP`, topic))
+		if err != nil {
+			t.Fatalf("Failed to assemble: %v", err)
+		}
+		result, err := Execute(code, &Context{Address: contractAddress, Gas: 10000}, newTestHost())
+		if err != nil {
+			t.Fatalf("Failed to log: %v", err)
+		}
+		return result.GasUsed
+	}
+
+	short, long := gasUsed("t"), gasUsed(strings.Repeat("t", 201))
+	if long-short != 200 {
+		t.Errorf("Expected 200 more topic bytes to cost 200 gas more, but they cost %d", long-short)
+	}
+}
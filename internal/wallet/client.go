@@ -0,0 +1,114 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+
+	"blockchain/internal/blockchain"
+)
+
+// Client talks to a node's API to build and submit transactions.
+type Client struct {
+	Node string // Base URL of the node's API, e.g. http://localhost:8080.
+}
+
+// Account returns the balance and nonce of address.
+func (c *Client) Account(address string) (*blockchain.AccountInfo, error) {
+	var info blockchain.AccountInfo
+	if err := c.get("/accounts/"+url.PathEscape(address), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// UnspentOutputs returns the unspent outputs paying address.
+func (c *Client) UnspentOutputs(address string) ([]blockchain.UnspentOutput, error) {
+	var outputs []blockchain.UnspentOutput
+	if err := c.get("/utxos/"+url.PathEscape(address), &outputs); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// Submit sends tx to the node's mempool and returns its ID.
+func (c *Client) Submit(tx *blockchain.Transaction) (string, error) {
+	body, err := json.Marshal(tx)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.Post(c.Node+"/transactions", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		message, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("transaction rejected: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+// get decodes the JSON response to a GET request for path into v.
+func (c *Client) get(path string, v interface{}) error {
+	resp, err := http.Get(c.Node + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s: %s", path, resp.Status, bytes.TrimSpace(message))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Transfer builds an unsigned account-mode transaction sending amount from address to to with the sender's
+// current nonce, paying fee.
+func (c *Client) Transfer(from, to string, amount, fee uint64) (*blockchain.Transaction, error) {
+	info, err := c.Account(from)
+	if err != nil {
+		return nil, err
+	}
+	return &blockchain.Transaction{From: from, To: to, Amount: amount, Fee: fee, Nonce: info.Nonce}, nil
+}
+
+// TransferUTXO builds an unsigned UTXO-mode transaction sending amount from the outputs of address to to,
+// paying fee. It spends outputs in order until they cover amount and fee and returns the change to address.
+func (c *Client) TransferUTXO(from, to string, amount, fee uint64) (*blockchain.Transaction, error) {
+	outputs, err := c.UnspentOutputs(from)
+	if err != nil {
+		return nil, err
+	}
+	if amount > math.MaxUint64-fee {
+		return nil, fmt.Errorf("%w: amount and fee overflow", blockchain.ErrInvalidTransaction)
+	}
+	needed := amount + fee
+	tx := &blockchain.Transaction{Fee: fee}
+	var total uint64
+	for _, output := range outputs {
+		if total >= needed {
+			break
+		}
+		tx.Inputs = append(tx.Inputs, output.TxInput)
+		total += output.Amount
+	}
+	if total < needed {
+		return nil, fmt.Errorf("%w: %s has %d, needs %d", blockchain.ErrInsufficientBalance, from, total, needed)
+	}
+	tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Address: to, Amount: amount})
+	if total > needed {
+		tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Address: from, Amount: total - needed})
+	}
+	return tx, nil
+}
@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+
+	"blockchain/internal/crypto"
+)
+
+// HardenedOffset is added to a child index to select hardened derivation, written with a ' in paths.
+const HardenedOffset uint32 = 0x80000000
+
+// ErrInvalidPath is returned (possibly wrapped) for a malformed derivation path, or one that uses
+// non-hardened derivation with Ed25519.
+var ErrInvalidPath = errors.New("invalid derivation path")
+
+// DefaultPath returns the derivation path of the key at index under the default account:
+// m/44'/1'/0'/0'/index'. Every level is hardened so that the same path works for every algorithm.
+func DefaultPath(index uint32) string {
+	return fmt.Sprintf("m/44'/1'/0'/0'/%d'", index)
+}
+
+// ExtendedKey is a private key together with the chain code needed to derive its children, following
+// BIP-32 for secp256k1 and its generalization SLIP-0010 for Ed25519 and P-256.
+type ExtendedKey struct {
+	algorithm crypto.Algorithm
+	key       []byte // 32-byte private key.
+	chainCode []byte // 32-byte chain code.
+}
+
+// curveSeeds are the HMAC keys of master key generation for each algorithm.
+var curveSeeds = map[crypto.Algorithm]string{
+	crypto.Secp256k1: "Bitcoin seed",
+	crypto.P256:      "Nist256p1 seed",
+	crypto.Ed25519:   "ed25519 seed",
+}
+
+// curveOrder returns the order of the algorithm's curve, or nil for Ed25519, whose keys are not reduced.
+func curveOrder(algorithm crypto.Algorithm) *big.Int {
+	switch algorithm {
+	case crypto.Secp256k1:
+		return secp256k1.S256().Params().N
+	case crypto.P256:
+		return elliptic.P256().Params().N
+	}
+	return nil
+}
+
+// NewMasterKey derives the master key of the algorithm from a seed, e.g. one returned by MnemonicSeed.
+func NewMasterKey(algorithm crypto.Algorithm, seed []byte) (*ExtendedKey, error) {
+	curveSeed, ok := curveSeeds[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", crypto.ErrUnknownAlgorithm, algorithm)
+	}
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("seed must be 16 to 64 bytes, got %d", len(seed))
+	}
+
+	sum := hmacSHA512([]byte(curveSeed), seed)
+	// SLIP-0010: an out-of-range key, which is astronomically unlikely, is replaced by hashing again.
+	for order := curveOrder(algorithm); order != nil; sum = hmacSHA512([]byte(curveSeed), sum) {
+		if k := new(big.Int).SetBytes(sum[:32]); k.Sign() != 0 && k.Cmp(order) < 0 {
+			break
+		}
+	}
+	return &ExtendedKey{algorithm: algorithm, key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// Child derives the child key at index; indexes from HardenedOffset up select hardened derivation, which is
+// the only kind Ed25519 supports.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	hardened := index >= HardenedOffset
+	order := curveOrder(k.algorithm)
+	if !hardened && order == nil {
+		return nil, fmt.Errorf("%w: %s only supports hardened derivation", ErrInvalidPath, k.algorithm)
+	}
+
+	var data []byte
+	if hardened {
+		data = append([]byte{0}, k.key...)
+	} else {
+		signer, err := crypto.NewSigner(k.algorithm, k.key)
+		if err != nil {
+			return nil, err
+		}
+		data = signer.PublicKey()
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+	sum := hmacSHA512(k.chainCode, data)
+	if order == nil {
+		return &ExtendedKey{algorithm: k.algorithm, key: sum[:32], chainCode: sum[32:]}, nil
+	}
+
+	for {
+		tweak := new(big.Int).SetBytes(sum[:32])
+		child := new(big.Int).Add(tweak, new(big.Int).SetBytes(k.key))
+		child.Mod(child, order)
+		if tweak.Cmp(order) < 0 && child.Sign() != 0 {
+			return &ExtendedKey{algorithm: k.algorithm, key: child.FillBytes(make([]byte, 32)), chainCode: sum[32:]}, nil
+		}
+		// SLIP-0010: derive again from the right half, which is astronomically unlikely to be needed.
+		sum = hmacSHA512(k.chainCode, binary.BigEndian.AppendUint32(append([]byte{1}, sum[32:]...), index))
+	}
+}
+
+// Derive derives the key at path relative to k, which must be a master key, e.g. "m/44'/1'/0'/0'/0'".
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := k
+	for _, index := range indexes {
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Algorithm returns the signature scheme of the key.
+func (k *ExtendedKey) Algorithm() crypto.Algorithm { return k.algorithm }
+
+// PrivateKey returns the raw private key, in the format of crypto.Signer.PrivateKey.
+func (k *ExtendedKey) PrivateKey() []byte { return append([]byte(nil), k.key...) }
+
+// ChainCode returns the chain code.
+func (k *ExtendedKey) ChainCode() []byte { return append([]byte(nil), k.chainCode...) }
+
+// Signer returns a signer for the key.
+func (k *ExtendedKey) Signer() (crypto.Signer, error) {
+	return crypto.NewSigner(k.algorithm, k.key)
+}
+
+// ParsePath parses a derivation path such as "m/44'/1'/0'/0/5" into child indexes; a ' or h suffix marks a
+// hardened index.
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("%w: %q must start with m", ErrInvalidPath, path)
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		offset := uint32(0)
+		if trimmed := strings.TrimRight(part, "'h"); trimmed != part {
+			if len(part)-len(trimmed) != 1 {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPath, part)
+			}
+			part, offset = trimmed, HardenedOffset
+		}
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, part)
+		}
+		indexes = append(indexes, uint32(index)+offset)
+	}
+	return indexes, nil
+}
+
+// hmacSHA512 returns HMAC-SHA512 of data under key.
+func hmacSHA512(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,120 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"blockchain/internal/crypto"
+)
+
+// TestMnemonicVectors tests mnemonic encoding and seed derivation against the BIP-39 reference vectors,
+// which use the passphrase "TREZOR".
+func TestMnemonicVectors(t *testing.T) {
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			strings.Repeat("zoo ", 23) + "vote",
+			"dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+		},
+	}
+	for _, vector := range vectors {
+		entropy, _ := hex.DecodeString(vector.entropy)
+		mnemonic, err := MnemonicFromEntropy(entropy)
+		if err != nil || mnemonic != vector.mnemonic {
+			t.Errorf("Expected mnemonic %q, but got %q (%v)", vector.mnemonic, mnemonic, err)
+		}
+		decoded, err := ValidateMnemonic(vector.mnemonic)
+		if err != nil || hex.EncodeToString(decoded) != vector.entropy {
+			t.Errorf("Expected %q to decode to %s, but got %x (%v)", vector.mnemonic, vector.entropy, decoded, err)
+		}
+		seed, err := MnemonicSeed(vector.mnemonic, "TREZOR")
+		if err != nil || hex.EncodeToString(seed) != vector.seed {
+			t.Errorf("Expected seed %s for %q, but got %x (%v)", vector.seed, vector.mnemonic, seed, err)
+		}
+	}
+
+	for _, invalid := range []string{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon bitcoinz",
+	} {
+		if _, err := ValidateMnemonic(invalid); !errors.Is(err, ErrInvalidMnemonic) {
+			t.Errorf("Expected ErrInvalidMnemonic for %q, but got %v", invalid, err)
+		}
+	}
+
+	generated, err := NewMnemonic(256)
+	if err != nil || len(strings.Fields(generated)) != 24 {
+		t.Fatalf("Expected a 24-word mnemonic, but got %q (%v)", generated, err)
+	}
+	if _, err := ValidateMnemonic(generated); err != nil {
+		t.Errorf("Expected a generated mnemonic to validate, but got %v", err)
+	}
+}
+
+// TestDeriveVectors tests key derivation against test vector 1 of BIP-32 (secp256k1) and of SLIP-0010
+// (Ed25519 and P-256), which share the seed 000102030405060708090a0b0c0d0e0f.
+func TestDeriveVectors(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	vectors := []struct {
+		algorithm  crypto.Algorithm
+		path       string
+		chainCode  string
+		privateKey string
+	}{
+		{crypto.Secp256k1, "m", "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{crypto.Secp256k1, "m/0'", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{crypto.Secp256k1, "m/0'/1", "2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{crypto.Secp256k1, "m/0'/1/2'", "04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{crypto.Secp256k1, "m/0'/1/2'/2", "cfb71883f01676f587d023cc53a35bc7f88f724b1f8c2892ac1275ac822a3edd", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{crypto.Secp256k1, "m/0'/1/2'/2/1000000000", "c783e67b921d2beb8f6b389cc646d7263b4145701dadd2161548a8b078e65e9e", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+		{crypto.Ed25519, "m", "90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb", "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7"},
+		{crypto.Ed25519, "m/0'", "8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69", "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3"},
+		{crypto.Ed25519, "m/0'/1'", "a320425f77d1b5c2505a6b1b27382b37368ee640e3557c315416801243552f14", "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2"},
+		{crypto.Ed25519, "m/0'/1'/2'", "2e69929e00b5ab250f49c3fb1c12f252de4fed2c1db88387094a0f8c4c9ccd6c", "92a5b23c0b8a99e37d07df3fb9966917f5d06e02ddbd909c7e184371463e9fc9"},
+		{crypto.P256, "m", "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea", "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2"},
+		{crypto.P256, "m/0'", "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11", "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c"},
+	}
+	for _, vector := range vectors {
+		master, err := NewMasterKey(vector.algorithm, seed)
+		if err != nil {
+			t.Fatalf("%s: failed to derive master key: %v", vector.algorithm, err)
+		}
+		key, err := master.Derive(vector.path)
+		if err != nil {
+			t.Fatalf("%s %s: failed to derive: %v", vector.algorithm, vector.path, err)
+		}
+		if got := hex.EncodeToString(key.ChainCode()); got != vector.chainCode {
+			t.Errorf("%s %s: expected chain code %s, but got %s", vector.algorithm, vector.path, vector.chainCode, got)
+		}
+		if got := hex.EncodeToString(key.PrivateKey()); got != vector.privateKey {
+			t.Errorf("%s %s: expected private key %s, but got %s", vector.algorithm, vector.path, vector.privateKey, got)
+		}
+	}
+
+	master, _ := NewMasterKey(crypto.Ed25519, seed)
+	if _, err := master.Derive("m/0'/1"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("Expected non-hardened Ed25519 derivation to fail, but got %v", err)
+	}
+	for _, path := range []string{"", "0'/1", "m/x", "m/1''", "m/2147483648"} {
+		if _, err := ParsePath(path); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("Expected ErrInvalidPath for %q, but got %v", path, err)
+		}
+	}
+}
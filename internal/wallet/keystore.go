@@ -0,0 +1,382 @@
+// Package wallet keeps signing keys, random or derived from mnemonic seeds, in a passphrase-encrypted keystore
+// file and signs transactions with them.
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+
+	"blockchain/internal/crypto"
+)
+
+// keystoreVersion is the version of the keystore file format.
+const keystoreVersion = 1
+
+// Key derivation functions for encrypting private keys.
+const (
+	KDFScrypt   = "scrypt"
+	KDFArgon2id = "argon2id"
+)
+
+var (
+	// ErrWrongPassphrase is returned when a key cannot be decrypted with the given passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+
+	// ErrKeyNotFound is returned when no key in the keystore has the given name or address.
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrDuplicateKey is returned when a key or seed with the same name, or a key with the same address, is
+	// already in the keystore.
+	ErrDuplicateKey = errors.New("key already in keystore")
+
+	// ErrSeedNotFound is returned when no seed in the keystore has the given name.
+	ErrSeedNotFound = errors.New("seed not found")
+)
+
+// KDFParams are the parameters of the key derivation function that turns a passphrase into an AES-256 key.
+type KDFParams struct {
+	Name string `json:"name"` // KDFScrypt or KDFArgon2id.
+
+	// scrypt: cost N, block size r and parallelism p.
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+
+	// argon2id: passes, memory in KiB and threads.
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+// DefaultKDF is the key derivation used for new keys: scrypt with the interactive-login parameters
+// recommended by its authors.
+var DefaultKDF = KDFParams{Name: KDFScrypt, N: 1 << 15, R: 8, P: 1}
+
+// Keystore is a file of named keys and mnemonic seeds. Names, algorithms, public keys and addresses are
+// stored in the clear, so keys can be listed without the passphrase; private keys and mnemonics are encrypted
+// with AES-256-GCM under a key derived from the passphrase with a fresh salt per entry.
+type Keystore struct {
+	path  string
+	KDF   KDFParams // Key derivation used to encrypt new entries; DefaultKDF when opened.
+	Keys  []*Key
+	Seeds []*Seed
+}
+
+// Key is an entry of the keystore.
+type Key struct {
+	Name       string           `json:"name"`
+	Algorithm  crypto.Algorithm `json:"algorithm"`
+	PublicKey  string           `json:"public_key"` // Hex-encoded canonical public key.
+	Address    string           `json:"address"`
+	Seed       string           `json:"seed,omitempty"` // Name of the seed the key was derived from, if any.
+	Path       string           `json:"path,omitempty"` // Derivation path from the seed.
+	Encryption encryptedKey     `json:"encryption"`
+}
+
+// Seed is a BIP-39 mnemonic kept in the keystore, from which keys are derived.
+type Seed struct {
+	Name       string       `json:"name"`
+	Encryption encryptedKey `json:"encryption"` // The encrypted mnemonic.
+}
+
+// encryptedKey is a private key encrypted with AES-256-GCM.
+type encryptedKey struct {
+	KDF        KDFParams `json:"kdf"`
+	Salt       string    `json:"salt"`       // Hex-encoded salt of the key derivation.
+	Nonce      string    `json:"nonce"`      // Hex-encoded GCM nonce.
+	Ciphertext string    `json:"ciphertext"` // Hex-encoded encrypted private key with the GCM tag.
+}
+
+// keystoreFile is the JSON layout of a keystore file.
+type keystoreFile struct {
+	Version int     `json:"version"`
+	Keys    []*Key  `json:"keys"`
+	Seeds   []*Seed `json:"seeds,omitempty"`
+}
+
+// Open reads the keystore at path. A missing file opens an empty keystore, which is created by Save.
+func Open(path string) (*Keystore, error) {
+	ks := &Keystore{path: path, KDF: DefaultKDF}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading keystore %s: %w", path, err)
+	}
+	if file.Version != keystoreVersion {
+		return nil, fmt.Errorf("keystore %s has unsupported version %d", path, file.Version)
+	}
+	ks.Keys, ks.Seeds = file.Keys, file.Seeds
+	return ks, nil
+}
+
+// Save writes the keystore to its file, readable only by the owner. The file is replaced atomically, so an
+// interrupted write never loses keys.
+func (ks *Keystore) Save() error {
+	data, err := json.MarshalIndent(keystoreFile{Version: keystoreVersion, Keys: ks.Keys, Seeds: ks.Seeds}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ks.path), 0o700); err != nil {
+		return err
+	}
+	tmp := ks.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ks.path)
+}
+
+// Generate creates a new random key for the algorithm, encrypts it with passphrase and adds it under name.
+func (ks *Keystore) Generate(name string, algorithm crypto.Algorithm, passphrase string) (*Key, error) {
+	signer, err := crypto.GenerateKey(algorithm, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ks.Add(name, signer, passphrase)
+}
+
+// Import adds a raw private key, in the format of crypto.Signer.PrivateKey, under name.
+func (ks *Keystore) Import(name string, algorithm crypto.Algorithm, privateKey []byte, passphrase string) (*Key, error) {
+	signer, err := crypto.NewSigner(algorithm, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return ks.Add(name, signer, passphrase)
+}
+
+// Add encrypts the private key of signer with passphrase and adds it under name.
+// Returns ErrDuplicateKey if the name or the key's address is already in use.
+func (ks *Keystore) Add(name string, signer crypto.Signer, passphrase string) (*Key, error) {
+	address := crypto.Address(signer)
+	if name == "" {
+		name = address
+	}
+	for _, key := range ks.Keys {
+		if key.Name == name || key.Address == address {
+			return nil, fmt.Errorf("%w: %s (%s)", ErrDuplicateKey, key.Name, key.Address)
+		}
+	}
+
+	encryption, err := encrypt(signer.PrivateKey(), passphrase, ks.KDF)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{
+		Name:       name,
+		Algorithm:  signer.Algorithm(),
+		PublicKey:  hex.EncodeToString(signer.PublicKey()),
+		Address:    address,
+		Encryption: *encryption,
+	}
+	ks.Keys = append(ks.Keys, key)
+	return key, nil
+}
+
+// Find returns the key with the given name or address.
+func (ks *Keystore) Find(nameOrAddress string) (*Key, error) {
+	for _, key := range ks.Keys {
+		if key.Name == nameOrAddress || key.Address == nameOrAddress {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, nameOrAddress)
+}
+
+// Unlock decrypts the key with passphrase.
+// Returns ErrWrongPassphrase if the passphrase is wrong or the encrypted key has been altered.
+func (k *Key) Unlock(passphrase string) (crypto.Signer, error) {
+	privateKey, err := decrypt(&k.Encryption, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := crypto.NewSigner(k.Algorithm, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if crypto.Address(signer) != k.Address {
+		return nil, fmt.Errorf("key %s does not match its address %s", k.Name, k.Address)
+	}
+	return signer, nil
+}
+
+// AddSeed validates a BIP-39 mnemonic, encrypts it with passphrase and adds it under name.
+func (ks *Keystore) AddSeed(name, mnemonic, passphrase string) (*Seed, error) {
+	if _, err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, errors.New("a seed needs a name")
+	}
+	if _, err := ks.FindSeed(name); err == nil {
+		return nil, fmt.Errorf("%w: seed %s", ErrDuplicateKey, name)
+	}
+
+	encryption, err := encrypt([]byte(strings.Join(strings.Fields(mnemonic), " ")), passphrase, ks.KDF)
+	if err != nil {
+		return nil, err
+	}
+	seed := &Seed{Name: name, Encryption: *encryption}
+	ks.Seeds = append(ks.Seeds, seed)
+	return seed, nil
+}
+
+// FindSeed returns the seed with the given name.
+func (ks *Keystore) FindSeed(name string) (*Seed, error) {
+	for _, seed := range ks.Seeds {
+		if seed.Name == name {
+			return seed, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrSeedNotFound, name)
+}
+
+// Mnemonic decrypts the seed's mnemonic with passphrase.
+func (s *Seed) Mnemonic(passphrase string) (string, error) {
+	mnemonic, err := decrypt(&s.Encryption, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return string(mnemonic), nil
+}
+
+// Derive derives the key of the algorithm at path from the named seed and adds it under name, encrypted
+// with passphrase like any other key. The seed uses an empty BIP-39 passphrase.
+func (ks *Keystore) Derive(seedName string, algorithm crypto.Algorithm, path, name, passphrase string) (*Key, error) {
+	seed, err := ks.FindSeed(seedName)
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := seed.Mnemonic(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	seedBytes, err := MnemonicSeed(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	master, err := NewMasterKey(algorithm, seedBytes)
+	if err != nil {
+		return nil, err
+	}
+	extended, err := master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := extended.Signer()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ks.Add(name, signer, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key.Seed, key.Path = seedName, path
+	return key, nil
+}
+
+// Export returns the raw private key of the key with the given name or address, decrypted with passphrase.
+func (ks *Keystore) Export(nameOrAddress, passphrase string) ([]byte, error) {
+	key, err := ks.Find(nameOrAddress)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := key.Unlock(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return signer.PrivateKey(), nil
+}
+
+// encrypt encrypts plaintext with a key derived from passphrase.
+func encrypt(plaintext []byte, passphrase string, params KDFParams) (*encryptedKey, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &encryptedKey{
+		KDF:        params,
+		Salt:       hex.EncodeToString(salt),
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(aead.Seal(nil, nonce, plaintext, nil)),
+	}, nil
+}
+
+// decrypt reverses encrypt.
+func decrypt(encrypted *encryptedKey, passphrase string) ([]byte, error) {
+	salt, err := hex.DecodeString(encrypted.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("malformed salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(encrypted.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("malformed nonce: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(encrypted.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("malformed ciphertext: %w", err)
+	}
+	aead, err := newAEAD(passphrase, salt, encrypted.KDF)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("malformed nonce")
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// newAEAD derives an AES-256 key from passphrase and salt and returns its GCM cipher.
+func newAEAD(passphrase string, salt []byte, params KDFParams) (cipher.AEAD, error) {
+	var key []byte
+	switch params.Name {
+	case KDFScrypt:
+		var err error
+		if key, err = scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, 32); err != nil {
+			return nil, err
+		}
+	case KDFArgon2id:
+		if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+			return nil, errors.New("argon2id parameters must be positive")
+		}
+		key = argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, 32)
+	default:
+		return nil, fmt.Errorf("unknown key derivation function %q", params.Name)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
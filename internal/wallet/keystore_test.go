@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"blockchain/internal/crypto"
+)
+
+// testKDFs are cheap key derivation parameters that keep the tests fast.
+var testKDFs = []KDFParams{
+	{Name: KDFScrypt, N: 1 << 10, R: 8, P: 1},
+	{Name: KDFArgon2id, Time: 1, Memory: 1024, Threads: 1},
+}
+
+// TestKeystore tests generating, saving, reopening and unlocking keys with both key derivation functions.
+func TestKeystore(t *testing.T) {
+	for _, kdf := range testKDFs {
+		path := filepath.Join(t.TempDir(), "keystore.json")
+		ks, err := Open(path)
+		if err != nil {
+			t.Fatalf("%s: failed to open a new keystore: %v", kdf.Name, err)
+		}
+		ks.KDF = kdf
+
+		var addresses []string
+		for _, algorithm := range crypto.Algorithms {
+			key, err := ks.Generate(string(algorithm), algorithm, "secret")
+			if err != nil {
+				t.Fatalf("%s: failed to generate %s key: %v", kdf.Name, algorithm, err)
+			}
+			addresses = append(addresses, key.Address)
+		}
+		if _, err := ks.Generate(string(crypto.Ed25519), crypto.Ed25519, "secret"); !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("%s: expected ErrDuplicateKey for a reused name, but got %v", kdf.Name, err)
+		}
+		if err := ks.Save(); err != nil {
+			t.Fatalf("%s: failed to save: %v", kdf.Name, err)
+		}
+
+		reopened, err := Open(path)
+		if err != nil {
+			t.Fatalf("%s: failed to reopen: %v", kdf.Name, err)
+		}
+		if len(reopened.Keys) != len(crypto.Algorithms) {
+			t.Fatalf("%s: expected %d keys, but got %d", kdf.Name, len(crypto.Algorithms), len(reopened.Keys))
+		}
+		for i, address := range addresses {
+			key, err := reopened.Find(address)
+			if err != nil {
+				t.Fatalf("%s: failed to find %s: %v", kdf.Name, address, err)
+			}
+			if _, err := key.Unlock("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("%s: expected ErrWrongPassphrase, but got %v", kdf.Name, err)
+			}
+			signer, err := key.Unlock("secret")
+			if err != nil {
+				t.Fatalf("%s: failed to unlock key %d: %v", kdf.Name, i, err)
+			}
+			if crypto.Address(signer) != address {
+				t.Errorf("%s: expected the unlocked key to have address %s", kdf.Name, address)
+			}
+		}
+	}
+}
+
+// TestImportExport tests that a raw private key survives an import and export unchanged.
+func TestImportExport(t *testing.T) {
+	ks, _ := Open(filepath.Join(t.TempDir(), "keystore.json"))
+	ks.KDF = testKDFs[0]
+
+	privateKey := bytes.Repeat([]byte{7}, 32)
+	key, err := ks.Import("", crypto.Secp256k1, privateKey, "secret")
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if key.Name != key.Address {
+		t.Errorf("Expected an unnamed key to be named by its address, but got %q", key.Name)
+	}
+	if _, err := ks.Import("again", crypto.Secp256k1, privateKey, "secret"); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("Expected ErrDuplicateKey for a reimported key, but got %v", err)
+	}
+
+	exported, err := ks.Export(key.Address, "secret")
+	if err != nil || !bytes.Equal(exported, privateKey) {
+		t.Errorf("Expected the exported key to match the imported one, but got %x (%v)", exported, err)
+	}
+	if _, err := ks.Export("missing", "secret"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, but got %v", err)
+	}
+}
+
+// TestRecoverFromMnemonic tests that a mnemonic restored into another keystore derives the same keys.
+func TestRecoverFromMnemonic(t *testing.T) {
+	mnemonic, err := NewMnemonic(128)
+	if err != nil {
+		t.Fatalf("failed to generate a mnemonic: %v", err)
+	}
+
+	derive := func(path string) []string {
+		ks, err := Open(path)
+		if err != nil {
+			t.Fatalf("failed to open the keystore: %v", err)
+		}
+		ks.KDF = testKDFs[0]
+		if _, err := ks.AddSeed("backup", mnemonic, "secret"); err != nil {
+			t.Fatalf("failed to add the seed: %v", err)
+		}
+		var addresses []string
+		for _, algorithm := range crypto.Algorithms {
+			for index := uint32(0); index < 2; index++ {
+				key, err := ks.Derive("backup", algorithm, DefaultPath(index), "", "secret")
+				if err != nil {
+					t.Fatalf("%s: failed to derive key %d: %v", algorithm, index, err)
+				}
+				addresses = append(addresses, key.Address)
+			}
+		}
+		if err := ks.Save(); err != nil {
+			t.Fatalf("failed to save the keystore: %v", err)
+		}
+		return addresses
+	}
+
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	original := derive(path)
+	recovered := derive(filepath.Join(t.TempDir(), "keystore.json"))
+	for i := range original {
+		if original[i] != recovered[i] {
+			t.Errorf("recovered key %d has address %s, want %s", i, recovered[i], original[i])
+		}
+	}
+
+	ks, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen the keystore: %v", err)
+	}
+	seed, err := ks.FindSeed("backup")
+	if err != nil {
+		t.Fatalf("seed not saved: %v", err)
+	}
+	if got, err := seed.Mnemonic("secret"); err != nil || got != mnemonic {
+		t.Errorf("decrypted mnemonic %q, %v; want %q", got, err, mnemonic)
+	}
+	if _, err := seed.Mnemonic("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("decrypting with a wrong passphrase: got %v, want ErrWrongPassphrase", err)
+	}
+	if _, err := ks.AddSeed("backup", mnemonic, "secret"); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("adding the seed twice: got %v, want ErrDuplicateKey", err)
+	}
+	if _, err := ks.AddSeed("typo", mnemonic+" abandon", "secret"); !errors.Is(err, ErrInvalidMnemonic) {
+		t.Errorf("adding an invalid mnemonic: got %v, want ErrInvalidMnemonic", err)
+	}
+	if key, _ := ks.Find(original[0]); key == nil || key.Seed != "backup" || key.Path != DefaultPath(0) {
+		t.Errorf("derived key does not record its seed and path: %+v", key)
+	}
+}
@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// englishWords is the BIP-39 English wordlist, one word per line.
+//
+//go:embed wordlist/english.txt
+var englishWords string
+
+var (
+	wordlist    = strings.Fields(englishWords)
+	wordIndexes = func() map[string]int {
+		indexes := make(map[string]int, len(wordlist))
+		for i, word := range wordlist {
+			indexes[word] = i
+		}
+		return indexes
+	}()
+)
+
+// ErrInvalidMnemonic is returned (possibly wrapped) for a mnemonic with an unknown word, a wrong number of
+// words or a bad checksum.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// NewMnemonic returns a BIP-39 mnemonic encoding bits of fresh random entropy: 128 bits give 12 words and
+// 256 bits give 24 words.
+func NewMnemonic(bits int) (string, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", fmt.Errorf("entropy must be 128 to 256 bits in steps of 32, got %d", bits)
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return MnemonicFromEntropy(entropy)
+}
+
+// MnemonicFromEntropy encodes entropy as a BIP-39 mnemonic: the entropy followed by the first bits of its
+// SHA-256 hash, one bit per 32 bits of entropy, split into 11-bit word indexes.
+func MnemonicFromEntropy(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", fmt.Errorf("entropy must be 128 to 256 bits in steps of 32, got %d", bits)
+	}
+	checksum := sha256.Sum256(entropy)
+	data := append(append([]byte(nil), entropy...), checksum[0])
+
+	words := make([]string, (bits+bits/32)/11)
+	for i := range words {
+		index := 0
+		for bit := i * 11; bit < (i+1)*11; bit++ {
+			index = index<<1 | int(data[bit/8]>>(7-bit%8)&1)
+		}
+		words[i] = wordlist[index]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// ValidateMnemonic checks the words and checksum of a BIP-39 mnemonic and returns its entropy.
+func ValidateMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("%w: %d words", ErrInvalidMnemonic, len(words))
+	}
+
+	data := make([]byte, (len(words)*11+7)/8)
+	for i, word := range words {
+		index, ok := wordIndexes[word]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, word)
+		}
+		for bit := 0; bit < 11; bit++ {
+			if index>>(10-bit)&1 == 1 {
+				position := i*11 + bit
+				data[position/8] |= 1 << (7 - position%8)
+			}
+		}
+	}
+
+	entropyBits := len(words) * 11 * 32 / 33
+	entropy := data[:entropyBits/8]
+	checksum := sha256.Sum256(entropy)
+	checksumBits := entropyBits / 32
+	if data[entropyBits/8]>>(8-checksumBits) != checksum[0]>>(8-checksumBits) {
+		return nil, fmt.Errorf("%w: bad checksum", ErrInvalidMnemonic)
+	}
+	return entropy, nil
+}
+
+// MnemonicSeed validates mnemonic and derives the 64-byte BIP-39 seed from it and an optional passphrase.
+func MnemonicSeed(mnemonic, passphrase string) ([]byte, error) {
+	if _, err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := norm.NFKD.String(strings.Join(strings.Fields(mnemonic), " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+	return pbkdf2.Key([]byte(normalized), []byte(salt), 2048, 64, sha512.New), nil
+}
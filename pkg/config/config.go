@@ -2,21 +2,67 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 // Config struct holds the configuration settings for the application.
 type Config struct {
-	NodeAddress  string
-	APIAddress   string
-	InitialPeer  string
+	NodeAddress      string
+	APIAddress       string
+	InitialPeer      string
+	Checkpoints      string        // Extra trusted checkpoints as comma-separated "height:hash" pairs.
+	ReverifyInterval time.Duration // Interval of the background full re-verification; zero disables it.
+	SnapshotDir      string        // Directory for periodic state snapshots.
+	SnapshotInterval int           // Save a snapshot every this many blocks; zero disables snapshots.
+	SnapshotKeep     int           // Number of snapshots to keep.
+	BootstrapSource  string        // Snapshot file or peer API URL to bootstrap from instead of genesis.
+	PruneDepth       int           // Keep the bodies of only this many most recent blocks; zero keeps the full history.
+	StorageBackend   string        // Storage backend: "memory", "file" or "bolt".
+	StoragePath      string        // File the storage backend keeps its data in.
+	Ledger           string        // Ledger mode of a new chain: "account" or "utxo".
+	GenesisAlloc     string        // Genesis allocations of a new chain as comma-separated "address:amount" pairs.
+	BlockReward      int           // Block reward of a new chain; zero mints nothing.
+	HalvingInterval  int           // The block reward of a new chain halves every this many blocks; zero never halves it.
+	Producer         string        // Address paid the reward and fees of the blocks this node adds; empty burns the fees.
+	MinTxFee         int           // Minimum fee of transactions admitted to the mempool.
+	AnchorLog        string        // Transparency log file the chain's tip is anchored to; empty disables it.
+	AnchorNode       string        // API URL of another node the chain's tip is anchored to; empty disables it.
+	AnchorNodeToken  string        // Notary token of the node the chain's tip is anchored to.
+	AnchorRecords    string        // File the records of anchored tips are kept in.
+	AnchorInterval   time.Duration // Interval of anchoring the chain's tip; zero anchors only on request.
+	NotaryToken      string        // Bearer token required to notarize documents; empty disables notarization.
+	NotaryRate       int           // Most notarizations taken a minute; zero for no limit.
 }
 
 // LoadConfig loads configuration settings from environment variables.
 func LoadConfig() *Config {
 	return &Config{
-		NodeAddress: getEnv("NODE_ADDRESS", "localhost:3001"),
-		APIAddress:  getEnv("API_ADDRESS", "localhost:8080"),
-		InitialPeer: getEnv("INITIAL_PEER", ""),
+		NodeAddress:      getEnv("NODE_ADDRESS", "localhost:3001"),
+		APIAddress:       getEnv("API_ADDRESS", "localhost:8080"),
+		InitialPeer:      getEnv("INITIAL_PEER", ""),
+		Checkpoints:      getEnv("CHECKPOINTS", ""),
+		ReverifyInterval: getDuration("REVERIFY_INTERVAL", time.Hour),
+		SnapshotDir:      getEnv("SNAPSHOT_DIR", "snapshots"),
+		SnapshotInterval: getInt("SNAPSHOT_INTERVAL", 0),
+		SnapshotKeep:     getInt("SNAPSHOT_KEEP", 2),
+		BootstrapSource:  getEnv("BOOTSTRAP_SOURCE", ""),
+		PruneDepth:       getInt("PRUNE_DEPTH", 0),
+		StorageBackend:   getEnv("STORAGE_BACKEND", "memory"),
+		StoragePath:      getEnv("STORAGE_PATH", "data/chain.db"),
+		Ledger:           getEnv("LEDGER", "account"),
+		GenesisAlloc:     getEnv("GENESIS_ALLOC", ""),
+		BlockReward:      getInt("BLOCK_REWARD", 0),
+		HalvingInterval:  getInt("HALVING_INTERVAL", 0),
+		Producer:         getEnv("PRODUCER_ADDRESS", ""),
+		MinTxFee:         getInt("MIN_TX_FEE", 0),
+		AnchorLog:        getEnv("ANCHOR_LOG", ""),
+		AnchorNode:       getEnv("ANCHOR_NODE", ""),
+		AnchorNodeToken:  getEnv("ANCHOR_NODE_TOKEN", ""),
+		AnchorRecords:    getEnv("ANCHOR_RECORDS", "anchors/records.jsonl"),
+		AnchorInterval:   getDuration("ANCHOR_INTERVAL", 0),
+		NotaryToken:      getEnv("NOTARY_TOKEN", ""),
+		NotaryRate:       getInt("NOTARY_RATE", 60),
 	}
 }
 
@@ -27,3 +73,21 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getDuration retrieves an environment variable as a time.Duration or returns a default value if not set or invalid.
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, defaultValue.String()))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getInt retrieves an environment variable as an int or returns a default value if not set or invalid.
+func getInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
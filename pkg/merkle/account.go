@@ -0,0 +1,27 @@
+package merkle
+
+import "fmt"
+
+// AccountKeyPrefix prefixes the state keys of accounts: "account/<address>" holds the account as JSON.
+const AccountKeyPrefix = "account/"
+
+// AccountProof proves the state of an account against the state root of a block.
+type AccountProof struct {
+	Address   string      `json:"address"`
+	Height    int         `json:"height"`          // Height of the block the proof is against.
+	BlockHash string      `json:"block_hash"`      // Hash of that block.
+	StateRoot string      `json:"state_root"`      // State root committed in that block's header.
+	Exists    bool        `json:"exists"`          // Whether the account is in the state; unused accounts are absent.
+	Value     string      `json:"value,omitempty"` // The account's balance and nonce as committed, e.g. {"balance":5,"nonce":1}.
+	Proof     SparseProof `json:"proof"`
+}
+
+// VerifyAccountProof checks an account proof against a trusted state root, taken from a block header the
+// client has verified. On success, proof.Exists and proof.Value are the account's state at that block.
+// Returns an error wrapping ErrInvalidProof if the proof does not verify.
+func VerifyAccountProof(stateRoot string, proof *AccountProof) error {
+	if proof.StateRoot != stateRoot {
+		return fmt.Errorf("%w: proof is against state root %s, not %s", ErrInvalidProof, proof.StateRoot, stateRoot)
+	}
+	return VerifySparseProof(stateRoot, AccountKeyPrefix+proof.Address, proof.Value, proof.Exists, &proof.Proof)
+}
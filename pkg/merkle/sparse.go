@@ -0,0 +1,205 @@
+// Package merkle implements the authenticated data structures that commit a block's state and transactions,
+// and lets light clients verify proofs against them without replaying the chain.
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"blockchain/internal/crypto"
+)
+
+// HashSize is the size of every hash in the tree.
+const HashSize = 32
+
+// Domain separation prefixes keep leaf and internal node hashes apart.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// ErrInvalidProof is returned (possibly wrapped) when a proof does not verify.
+var ErrInvalidProof = errors.New("invalid Merkle proof")
+
+// emptyHash is the hash of an empty subtree.
+var emptyHash = make([]byte, HashSize)
+
+// SparseTree is a sparse Merkle tree mapping string keys to string values. A key is placed at the path given
+// by the bits of its SHA-256 hash, most significant bit first. A subtree holding a single leaf is replaced by
+// that leaf and an empty subtree hashes to zero, so a tree of n entries needs about n internal nodes instead
+// of 256 per entry.
+//
+// Hashes:
+//   - leaf: SHA-256(0x00 || SHA-256(key) || SHA-256(value))
+//   - internal node: SHA-256(0x01 || left || right)
+//   - empty subtree: 32 zero bytes
+type SparseTree struct {
+	leaves []sparseLeaf // Sorted by key hash.
+}
+
+// sparseLeaf is an entry of the tree with its hashes precomputed.
+type sparseLeaf struct {
+	keyHash   []byte
+	valueHash []byte
+}
+
+// NewSparseTree builds the tree holding entries.
+func NewSparseTree(entries map[string]string) *SparseTree {
+	leaves := make([]sparseLeaf, 0, len(entries))
+	for key, value := range entries {
+		leaves = append(leaves, sparseLeaf{keyHash: KeyHash(key), valueHash: crypto.HashSHA256Go([]byte(value))})
+	}
+	sort.Slice(leaves, func(i, j int) bool { return bytes.Compare(leaves[i].keyHash, leaves[j].keyHash) < 0 })
+	return &SparseTree{leaves: leaves}
+}
+
+// KeyHash returns the hash of key, which determines its path in a SparseTree.
+func KeyHash(key string) []byte {
+	return crypto.HashSHA256Go([]byte(key))
+}
+
+// Root returns the root hash of the tree.
+func (t *SparseTree) Root() []byte {
+	return subtreeHash(t.leaves, 0)
+}
+
+// Prove returns a proof that key is in the tree with its current value, or that it is absent.
+func (t *SparseTree) Prove(key string) *SparseProof {
+	keyHash := KeyHash(key)
+	proof := &SparseProof{Siblings: []string{}}
+	leaves := t.leaves
+	for depth := 0; len(leaves) > 1; depth++ {
+		left, right := split(leaves, depth)
+		if bit(keyHash, depth) == 0 {
+			leaves = left
+			proof.Siblings = append(proof.Siblings, hex.EncodeToString(subtreeHash(right, depth+1)))
+		} else {
+			leaves = right
+			proof.Siblings = append(proof.Siblings, hex.EncodeToString(subtreeHash(left, depth+1)))
+		}
+	}
+	// The path ends at an empty subtree, at the key's own leaf, or at the only leaf of another key that shares
+	// the path so far; that leaf proves the key's absence.
+	if len(leaves) == 1 && !bytes.Equal(leaves[0].keyHash, keyHash) {
+		proof.Leaf = &SparseProofLeaf{
+			KeyHash:   hex.EncodeToString(leaves[0].keyHash),
+			ValueHash: hex.EncodeToString(leaves[0].valueHash),
+		}
+	}
+	return proof
+}
+
+// SparseProof proves that a key has a given value in a SparseTree, or that it is absent.
+type SparseProof struct {
+	Siblings []string         `json:"siblings"`       // Hex-encoded hashes of the siblings along the key's path, from the root down.
+	Leaf     *SparseProofLeaf `json:"leaf,omitempty"` // For an absent key: the other key's leaf the path ends at, if any.
+}
+
+// SparseProofLeaf is a leaf of another key, found where the path of an absent key ends.
+type SparseProofLeaf struct {
+	KeyHash   string `json:"key_hash"`   // Hex-encoded hash of the leaf's key.
+	ValueHash string `json:"value_hash"` // Hex-encoded hash of the leaf's value.
+}
+
+// VerifySparseProof checks proof against the hex-encoded root of a SparseTree. If exists is true, it checks
+// that key holds value; otherwise it checks that key is absent and value is ignored.
+// Returns an error wrapping ErrInvalidProof if the proof does not verify.
+func VerifySparseProof(root, key, value string, exists bool, proof *SparseProof) error {
+	if proof == nil {
+		return fmt.Errorf("%w: missing proof", ErrInvalidProof)
+	}
+	if len(proof.Siblings) > 8*HashSize {
+		return fmt.Errorf("%w: %d siblings exceed the tree depth", ErrInvalidProof, len(proof.Siblings))
+	}
+	keyHash := KeyHash(key)
+
+	var hash []byte
+	switch {
+	case exists:
+		if proof.Leaf != nil {
+			return fmt.Errorf("%w: an inclusion proof ends at the key's own leaf", ErrInvalidProof)
+		}
+		hash = leafHash(keyHash, crypto.HashSHA256Go([]byte(value)))
+	case proof.Leaf != nil:
+		otherKey, err := decodeHash(proof.Leaf.KeyHash)
+		if err != nil {
+			return err
+		}
+		otherValue, err := decodeHash(proof.Leaf.ValueHash)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(otherKey, keyHash) {
+			return fmt.Errorf("%w: the leaf proving absence belongs to the key", ErrInvalidProof)
+		}
+		for depth := range proof.Siblings {
+			if bit(otherKey, depth) != bit(keyHash, depth) {
+				return fmt.Errorf("%w: the leaf proving absence is not on the key's path", ErrInvalidProof)
+			}
+		}
+		hash = leafHash(otherKey, otherValue)
+	default:
+		hash = emptyHash
+	}
+
+	for depth := len(proof.Siblings) - 1; depth >= 0; depth-- {
+		sibling, err := decodeHash(proof.Siblings[depth])
+		if err != nil {
+			return err
+		}
+		if bit(keyHash, depth) == 0 {
+			hash = nodeHash(hash, sibling)
+		} else {
+			hash = nodeHash(sibling, hash)
+		}
+	}
+	if hex.EncodeToString(hash) != root {
+		return fmt.Errorf("%w: computed root %x does not match %s", ErrInvalidProof, hash, root)
+	}
+	return nil
+}
+
+// subtreeHash returns the hash of the subtree at depth holding leaves, which share their first depth bits.
+func subtreeHash(leaves []sparseLeaf, depth int) []byte {
+	switch len(leaves) {
+	case 0:
+		return emptyHash
+	case 1:
+		return leafHash(leaves[0].keyHash, leaves[0].valueHash)
+	}
+	left, right := split(leaves, depth)
+	return nodeHash(subtreeHash(left, depth+1), subtreeHash(right, depth+1))
+}
+
+// split divides sorted leaves by the bit at depth of their key hashes.
+func split(leaves []sparseLeaf, depth int) (left, right []sparseLeaf) {
+	i := sort.Search(len(leaves), func(i int) bool { return bit(leaves[i].keyHash, depth) == 1 })
+	return leaves[:i], leaves[i:]
+}
+
+// bit returns the bit of hash at depth, most significant bit first.
+func bit(hash []byte, depth int) byte {
+	return hash[depth/8] >> (7 - depth%8) & 1
+}
+
+// leafHash returns the hash of a leaf.
+func leafHash(keyHash, valueHash []byte) []byte {
+	return crypto.HashSHA256Go(append(append([]byte{leafPrefix}, keyHash...), valueHash...))
+}
+
+// nodeHash returns the hash of an internal node.
+func nodeHash(left, right []byte) []byte {
+	return crypto.HashSHA256Go(append(append([]byte{nodePrefix}, left...), right...))
+}
+
+// decodeHash decodes a hex-encoded hash of a proof.
+func decodeHash(s string) ([]byte, error) {
+	hash, err := hex.DecodeString(s)
+	if err != nil || len(hash) != HashSize {
+		return nil, fmt.Errorf("%w: malformed hash %q", ErrInvalidProof, s)
+	}
+	return hash, nil
+}
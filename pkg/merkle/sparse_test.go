@@ -0,0 +1,59 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestSparseProofs tests inclusion and exclusion proofs for every key of trees of several sizes.
+func TestSparseProofs(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 3, 50} {
+		entries := make(map[string]string)
+		for i := 0; i < size; i++ {
+			entries[fmt.Sprintf("key%d", i)] = fmt.Sprintf("value%d", i)
+		}
+		tree := NewSparseTree(entries)
+		root := fmt.Sprintf("%x", tree.Root())
+
+		for key, value := range entries {
+			proof := tree.Prove(key)
+			if err := VerifySparseProof(root, key, value, true, proof); err != nil {
+				t.Errorf("size %d: inclusion proof of %s failed: %v", size, key, err)
+			}
+			if err := VerifySparseProof(root, key, "forged", true, proof); !errors.Is(err, ErrInvalidProof) {
+				t.Errorf("size %d: expected a forged value of %s to fail, but got %v", size, key, err)
+			}
+			if err := VerifySparseProof(root, key, "", false, proof); !errors.Is(err, ErrInvalidProof) {
+				t.Errorf("size %d: expected an exclusion proof of present key %s to fail, but got %v", size, key, err)
+			}
+		}
+		for i := 0; i < 20; i++ {
+			key := fmt.Sprintf("absent%d", i)
+			proof := tree.Prove(key)
+			if err := VerifySparseProof(root, key, "", false, proof); err != nil {
+				t.Errorf("size %d: exclusion proof of %s failed: %v", size, key, err)
+			}
+			if err := VerifySparseProof(root, key, "value", true, proof); !errors.Is(err, ErrInvalidProof) {
+				t.Errorf("size %d: expected an inclusion proof of absent key %s to fail, but got %v", size, key, err)
+			}
+		}
+	}
+}
+
+// TestSparseTreeRoot tests that the root depends on every entry but not on the order they were added in.
+func TestSparseTreeRoot(t *testing.T) {
+	if root := NewSparseTree(nil).Root(); fmt.Sprintf("%x", root) != fmt.Sprintf("%x", emptyHash) {
+		t.Errorf("Expected the empty tree to have the empty hash as root, but got %x", root)
+	}
+
+	a := NewSparseTree(map[string]string{"a": "1", "b": "2"}).Root()
+	b := NewSparseTree(map[string]string{"b": "2", "a": "1"}).Root()
+	c := NewSparseTree(map[string]string{"a": "1", "b": "3"}).Root()
+	if fmt.Sprintf("%x", a) != fmt.Sprintf("%x", b) {
+		t.Error("Expected equal trees to have equal roots")
+	}
+	if fmt.Sprintf("%x", a) == fmt.Sprintf("%x", c) {
+		t.Error("Expected a changed value to change the root")
+	}
+}
@@ -0,0 +1,114 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"blockchain/internal/crypto"
+)
+
+// countPrefix separates the root of a binary Merkle tree, which commits to the number of leaves, from its leaf
+// and internal node hashes, which use the prefixes of SparseTree.
+const countPrefix = 0x02
+
+// Root computes the root of a binary Merkle tree over leaves. Leaves are hashed with a 0x00 prefix and each
+// level hashes pairs of adjacent nodes with a 0x01 prefix; an odd node at the end of a level is promoted to the
+// next level unchanged. The root hashes the number of leaves, as 8 big-endian bytes after a 0x02 prefix, with
+// the top node, so that a proof cannot claim another shape of tree. The root of no leaves is the hash of the
+// empty string.
+//
+// Hashes:
+//   - leaf: SHA-256(0x00 || leaf)
+//   - internal node: SHA-256(0x01 || left || right)
+//   - root: SHA-256(0x02 || count || top)
+func Root(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return crypto.HashSHA256Go(nil)
+	}
+	level := hashLeaves(leaves)
+	for len(level) > 1 {
+		level = nextLevel(level)
+	}
+	return countHash(len(leaves), level[0])
+}
+
+// Path returns the siblings of the leaf at index on its way to the root, from the leaf up. Levels at which the
+// node is promoted have no sibling.
+func Path(leaves [][]byte, index int) [][]byte {
+	var siblings [][]byte
+	level := hashLeaves(leaves)
+	for len(level) > 1 {
+		if sibling := index ^ 1; sibling < len(level) {
+			siblings = append(siblings, level[sibling])
+		}
+		level = nextLevel(level)
+		index /= 2
+	}
+	return siblings
+}
+
+// VerifyPath checks that leaf is at index among count leaves of the tree with the given root, using the
+// siblings returned by Path. The root commits to count, so a wrong count fails like a wrong sibling.
+// Returns an error wrapping ErrInvalidProof if it is not.
+func VerifyPath(root, leaf []byte, index, count int, siblings [][]byte) error {
+	if index < 0 || index >= count {
+		return fmt.Errorf("%w: index %d out of range for %d leaves", ErrInvalidProof, index, count)
+	}
+	hash := binaryLeafHash(leaf)
+	leaves := count
+	for ; count > 1; count = (count + 1) / 2 {
+		if sibling := index ^ 1; sibling < count {
+			if len(siblings) == 0 {
+				return fmt.Errorf("%w: too few siblings", ErrInvalidProof)
+			}
+			if index%2 == 0 {
+				hash = nodeHash(hash, siblings[0])
+			} else {
+				hash = nodeHash(siblings[0], hash)
+			}
+			siblings = siblings[1:]
+		}
+		index /= 2
+	}
+	if len(siblings) != 0 {
+		return fmt.Errorf("%w: too many siblings", ErrInvalidProof)
+	}
+	if hash = countHash(leaves, hash); !bytes.Equal(hash, root) {
+		return fmt.Errorf("%w: computed root %x does not match %x", ErrInvalidProof, hash, root)
+	}
+	return nil
+}
+
+// nextLevel hashes the pairs of a level of a binary Merkle tree.
+func nextLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, nodeHash(level[i], level[i+1]))
+	}
+	return next
+}
+
+// hashLeaves returns the leaf hashes of leaves, the lowest level of a binary Merkle tree.
+func hashLeaves(leaves [][]byte) [][]byte {
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = binaryLeafHash(leaf)
+	}
+	return level
+}
+
+// binaryLeafHash returns the hash of a leaf of a binary Merkle tree.
+func binaryLeafHash(leaf []byte) []byte {
+	return crypto.HashSHA256Go(append([]byte{leafPrefix}, leaf...))
+}
+
+// countHash returns the root of a binary Merkle tree of count leaves whose top node is top.
+func countHash(count int, top []byte) []byte {
+	input := binary.BigEndian.AppendUint64([]byte{countPrefix}, uint64(count))
+	return crypto.HashSHA256Go(append(input, top...))
+}
@@ -0,0 +1,65 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"blockchain/internal/crypto"
+)
+
+// TestPath tests that the path of every leaf verifies, for trees with and without promoted nodes.
+func TestPath(t *testing.T) {
+	for count := 1; count <= 9; count++ {
+		leaves := make([][]byte, count)
+		for i := range leaves {
+			leaves[i] = crypto.HashSHA256Go([]byte(fmt.Sprintf("leaf%d", i)))
+		}
+		root := Root(leaves)
+
+		for index, leaf := range leaves {
+			siblings := Path(leaves, index)
+			if err := VerifyPath(root, leaf, index, count, siblings); err != nil {
+				t.Errorf("%d leaves: path of leaf %d failed: %v", count, index, err)
+			}
+			if count > 1 {
+				if err := VerifyPath(root, leaf, (index+1)%count, count, siblings); !errors.Is(err, ErrInvalidProof) {
+					t.Errorf("%d leaves: expected leaf %d at the wrong index to fail, but got %v", count, index, err)
+				}
+			}
+			if err := VerifyPath(root, crypto.HashSHA256Go([]byte("other")), index, count, siblings); !errors.Is(err, ErrInvalidProof) {
+				t.Errorf("%d leaves: expected another leaf at index %d to fail, but got %v", count, index, err)
+			}
+		}
+	}
+}
+
+// TestPathForgery tests that an internal node cannot be proven as a leaf, and that a proof cannot claim
+// another number of leaves.
+func TestPathForgery(t *testing.T) {
+	leaves := make([][]byte, 5)
+	for i := range leaves {
+		leaves[i] = crypto.HashSHA256Go([]byte(fmt.Sprintf("leaf%d", i)))
+	}
+	root := Root(leaves)
+
+	// The first internal level of leaves 0 to 3, and the promoted leaf 4.
+	level := nextLevel(hashLeaves(leaves))
+	if err := VerifyPath(root, level[0], 0, 3, [][]byte{level[1], level[2]}); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("Expected an internal node presented as a leaf to fail, but got %v", err)
+	}
+	top := nextLevel(level)
+	if err := VerifyPath(root, top[0], 0, 2, [][]byte{top[1]}); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("Expected an internal node presented as a leaf to fail, but got %v", err)
+	}
+
+	siblings := Path(leaves, 4)
+	if err := VerifyPath(root, leaves[4], 4, 5, siblings); err != nil {
+		t.Fatalf("Path of leaf 4 failed: %v", err)
+	}
+	for _, count := range []int{6, 7, 8} {
+		if err := VerifyPath(root, leaves[4], 4, count, siblings); !errors.Is(err, ErrInvalidProof) {
+			t.Errorf("Expected leaf 4 among %d leaves to fail, but got %v", count, err)
+		}
+	}
+}
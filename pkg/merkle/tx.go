@@ -0,0 +1,60 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// BlockHeader is the hashed part of a block. Its JSON encoding matches the blocks returned by the API, so a
+// block or header fetched from a node can be decoded into it.
+type BlockHeader struct {
+	Timestamp    int64  // Creation time of the block, in Unix milliseconds.
+	DataHash     string // SHA-256 hash of the block's data.
+	TxRoot       string // Root of the binary Merkle tree of the block's transaction IDs; see Root.
+	PreviousHash string // Hash of the previous block.
+	StateRoot    string // Root of the sparse Merkle tree of the state; see SparseTree.
+	LogsBloom    string `json:",omitempty"` // Bloom filter of the block's logs; empty if there are none.
+	Hash         string // Hash of the header.
+}
+
+// ComputeHash returns the hash of the header's fields, which Hash must equal.
+func (h *BlockHeader) ComputeHash() string {
+	hash := sha256.Sum256([]byte(strconv.FormatInt(h.Timestamp, 10) + h.DataHash + h.TxRoot + h.PreviousHash + h.StateRoot + h.LogsBloom))
+	return hex.EncodeToString(hash[:])
+}
+
+// TxProof proves that a transaction is included in a block.
+type TxProof struct {
+	TxID     string      `json:"txid"`     // ID of the transaction: the hex-encoded SHA-256 hash of its JSON encoding.
+	Height   int         `json:"height"`   // Height of the block.
+	Index    int         `json:"index"`    // Position of the transaction in the block.
+	Count    int         `json:"count"`    // Number of transactions in the block, which the TxRoot commits to.
+	Siblings []string    `json:"siblings"` // Hex-encoded siblings of the transaction's ID in the block's transaction tree, from the leaf up.
+	Header   BlockHeader `json:"header"`
+}
+
+// VerifyTxProof checks, offline, that proof.TxID is included in the block with the given trusted hash: the
+// header must hash to blockHash, and the sibling path must lead from the transaction ID to the header's TxRoot.
+// Returns an error wrapping ErrInvalidProof if the proof does not verify.
+func VerifyTxProof(blockHash string, proof *TxProof) error {
+	if proof.Header.Hash != blockHash || proof.Header.ComputeHash() != blockHash {
+		return fmt.Errorf("%w: header does not hash to block %s", ErrInvalidProof, blockHash)
+	}
+	root, err := decodeHash(proof.Header.TxRoot)
+	if err != nil {
+		return err
+	}
+	leaf, err := decodeHash(proof.TxID)
+	if err != nil {
+		return err
+	}
+	siblings := make([][]byte, len(proof.Siblings))
+	for i, sibling := range proof.Siblings {
+		if siblings[i], err = decodeHash(sibling); err != nil {
+			return err
+		}
+	}
+	return VerifyPath(root, leaf, proof.Index, proof.Count, siblings)
+}